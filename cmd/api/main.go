@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,9 +10,14 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/recover"
 	"github.com/yourusername/go-production-level/config"
 	"github.com/yourusername/go-production-level/internal/controllers"
+	"github.com/yourusername/go-production-level/internal/mailer"
 	"github.com/yourusername/go-production-level/internal/middlewares"
 	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/oidc"
+	"github.com/yourusername/go-production-level/internal/policies"
+	"github.com/yourusername/go-production-level/internal/ratelimit"
 	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/saml"
 	"github.com/yourusername/go-production-level/internal/services"
 	"github.com/yourusername/go-production-level/internal/utils"
 )
@@ -38,7 +44,7 @@ func main() {
 	}
 
 	// Auto migrate database
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(&models.User{}, &models.RefreshToken{}, &models.Role{}, &models.Permission{}, &models.LoginEvent{}, &models.RecoveryCode{}, &models.OneTimeToken{}, &models.AuditLog{}, &models.APIKey{}, &models.LinkedIdentity{}, &models.OAuthClient{}, &models.OAuthConsent{}, &models.Session{}, &models.PasswordHistory{}, &models.SAMLConnection{})
 	if err != nil {
 		log.Fatalf("Failed to migrate database: %v", err)
 	}
@@ -49,16 +55,106 @@ func main() {
 		log.Fatalf("Failed to connect to Redis: %v", err)
 	}
 
+	// Initialize mailer
+	mail, err := mailer.New(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize mailer: %v", err)
+	}
+
+	// Initialize JWT signing keys
+	keyRing, err := utils.NewKeyRing(cfg)
+	if err != nil {
+		log.Fatalf("Failed to load JWT keys: %v", err)
+	}
+
+	// Initialize password hashing
+	passwordHasher, err := utils.NewPasswordHasher(cfg)
+	if err != nil {
+		log.Fatalf("Failed to configure password hashing: %v", err)
+	}
+	var breachCorpus *utils.BreachCorpus
+	if cfg.PasswordBreachCorpusDir != "" {
+		breachCorpus, err = utils.NewBreachCorpus(cfg.PasswordBreachCorpusDir)
+		if err != nil {
+			log.Fatalf("Failed to load breached password corpus: %v", err)
+		}
+	}
+
+	// Initialize SAML service provider credentials
+	samlCredentials, err := saml.LoadCredentials(cfg.SAMLKeyFile, cfg.SAMLCertFile)
+	if err != nil {
+		log.Fatalf("Failed to load SAML credentials: %v", err)
+	}
+
 	// Initialize repositories
-	gormRepo := repository.NewGormRepository(db)
-	userRepo := repository.NewUserRepository(gormRepo)
+	database := repository.NewDatabase(db)
+	txManager := repository.NewTxManager(db)
+	userRepo := repository.NewUserRepository(repository.NewGormRepository[models.User, uint](db))
+	refreshTokenRepo := repository.NewRefreshTokenRepository(database)
+	roleRepo := repository.NewRoleRepository(database)
+	permissionRepo := repository.NewPermissionRepository(database)
+	loginEventRepo := repository.NewLoginEventRepository(database)
+	recoveryCodeRepo := repository.NewRecoveryCodeRepository(database)
+	oneTimeTokenRepo := repository.NewOneTimeTokenRepository(database)
+	auditLogRepo := repository.NewAuditLogRepository(database)
+	apiKeyRepo := repository.NewAPIKeyRepository(database)
+	linkedIdentityRepo := repository.NewLinkedIdentityRepository(database)
+	oauthClientRepo := repository.NewOAuthClientRepository(database)
+	oauthConsentRepo := repository.NewOAuthConsentRepository(database)
+	sessionRepo := repository.NewSessionRepository(database)
+	passwordHistoryRepo := repository.NewPasswordHistoryRepository(database)
+	samlConnectionRepo := repository.NewSAMLConnectionRepository(database)
+
+	// Repositories holding records of a user, removed when the user is purged
+	userDataRepos := []repository.UserDataRepository{
+		loginEventRepo,
+		refreshTokenRepo,
+		sessionRepo,
+		apiKeyRepo,
+		recoveryCodeRepo,
+		oneTimeTokenRepo,
+		linkedIdentityRepo,
+		oauthConsentRepo,
+	}
 
 	// Initialize services
-	userService := services.NewUserService(userRepo, redis, cfg)
+	tokenService := services.NewTokenService(userRepo, refreshTokenRepo, sessionRepo, keyRing, redis, cfg)
+	rbacService := services.NewRBACService(roleRepo, permissionRepo, redis)
+	auditService := services.NewAuditService(auditLogRepo)
+	loginGuard := services.NewLoginGuard(auditService, redis, cfg)
+	mfaService := services.NewMFAService(userRepo, recoveryCodeRepo, tokenService, loginGuard, keyRing, redis, cfg)
+	passwordPolicy := services.NewPasswordPolicy(passwordHistoryRepo, txManager, passwordHasher, breachCorpus, cfg)
+	verificationService := services.NewEmailVerificationService(userRepo, oneTimeTokenRepo, mail, redis, cfg)
+	userService := services.NewUserService(userRepo, loginEventRepo, userDataRepos, tokenService, rbacService, mfaService, verificationService, loginGuard, auditService, passwordHasher, passwordPolicy, txManager, redis, cfg)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo, rbacService, cfg)
+	passwordService := services.NewPasswordService(userRepo, oneTimeTokenRepo, userService, mail, cfg)
+	magicLinkService := services.NewMagicLinkService(userRepo, oneTimeTokenRepo, userService, mail, redis, cfg)
+	oidcService := services.NewOIDCService(oidc.NewRegistry(cfg), linkedIdentityRepo, userRepo, userService, redis, cfg)
+	samlService := services.NewSAMLService(samlConnectionRepo, linkedIdentityRepo, userRepo, userService, rbacService, samlCredentials, redis, cfg)
+	oauthService := services.NewOAuthService(oauthClientRepo, oauthConsentRepo, refreshTokenRepo, userRepo, tokenService, rbacService, keyRing, redis, cfg)
+
+	// Seed built-in roles and permissions
+	if err := rbacService.SeedDefaults(context.Background()); err != nil {
+		log.Fatalf("Failed to seed roles: %v", err)
+	}
 
 	// Initialize controllers
-	userController := controllers.NewUserController(userService)
+	userPolicy := policies.NewUserPolicy(rbacService)
+	userController := controllers.NewUserController(userService, userPolicy, cfg)
+	authController := controllers.NewAuthController(tokenService)
+	rolePolicy := policies.NewRolePolicy(rbacService)
+	roleController := controllers.NewRoleController(rbacService, rolePolicy)
+	adminController := controllers.NewAdminController(userService, rbacService, auditService, userPolicy, cfg)
+	mfaController := controllers.NewMFAController(mfaService)
+	passwordController := controllers.NewPasswordController(passwordService)
+	magicLinkController := controllers.NewMagicLinkController(magicLinkService)
+	emailController := controllers.NewEmailController(verificationService)
+	apiKeyController := controllers.NewAPIKeyController(apiKeyService)
+	oidcController := controllers.NewOIDCController(oidcService)
+	oauthController := controllers.NewOAuthController(oauthService, rbacService)
+	samlController := controllers.NewSAMLController(samlService, rbacService)
 	healthController := controllers.NewHealthController()
+	jwksController := controllers.NewJWKSController(keyRing)
 
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
@@ -76,12 +172,21 @@ func main() {
 	// Middleware
 	app.Use(recover.New())
 	app.Use(logger.New())
+	app.Use(middlewares.RequestTimeout(cfg.RequestTimeout))
 	app.Use(cors.New(cors.Config{
-		AllowOrigins: "*",
-		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE, OPTIONS",
+		AllowOrigins:  "*",
+		AllowHeaders:  "Origin, Content-Type, Accept, Authorization, X-API-Key",
+		AllowMethods:  "GET, POST, PUT, DELETE, OPTIONS",
+		ExposeHeaders: "RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After",
 	}))
 
+	// Rate limiting, shared through Redis with an in-process fallback
+	rateLimiter := middlewares.NewRateLimiter(ratelimit.NewFallbackLimiter(ratelimit.NewRedisLimiter(redis), ratelimit.NewMemoryLimiter()), apiKeyService, cfg)
+	app.Use("/api/v1", rateLimiter.For("default"))
+	for _, path := range []string{"/api/v1/login", "/api/v1/token", "/api/v1/password", "/api/v1/email", "/api/v1/mfa/verify", "/api/v1/auth/oidc", "/api/v1/auth/saml", "/api/v1/oauth/token"} {
+		app.Use(path, rateLimiter.For("auth"))
+	}
+
 	// Serve Swagger documentation
 	app.Static("/api/v1/docs", "./docs")
 
@@ -90,20 +195,35 @@ func main() {
 
 	// Register routes
 	api := app.Group("/api/v1")
+	authMiddleware := middlewares.AuthMiddleware(tokenService, apiKeyService)
 
 	// Health check route (before other routes)
 	healthController.Register(app)
-
-	// Public routes
-	userController.Register(app)
+	jwksController.Register(app)
+
+	// Public and authenticated routes
+	userController.Register(app, authMiddleware)
+	authController.Register(app, authMiddleware)
+	mfaController.Register(app, authMiddleware)
+	passwordController.Register(app)
+	magicLinkController.Register(app)
+	emailController.Register(app)
+	apiKeyController.Register(app, authMiddleware)
+	oidcController.Register(app, authMiddleware)
+	oauthController.Register(app, authMiddleware)
+	samlController.Register(app)
 
 	// Protected routes
 	protected := api.Group("/protected")
-	protected.Use(middlewares.AuthMiddleware(cfg))
+	protected.Use(authMiddleware, rateLimiter.For("user"))
 
 	// Admin routes
 	admin := protected.Group("/admin")
-	admin.Use(middlewares.AdminMiddleware())
+	admin.Use(middlewares.AdminMiddleware(rbacService))
+	roleController.RegisterAdmin(admin)
+	adminController.RegisterAdmin(admin)
+	oauthController.RegisterAdmin(admin)
+	samlController.RegisterAdmin(admin)
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.ServerPort)
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// OIDCProvider configures an OpenID Connect identity provider for social login
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+}
+
+// loadOIDCProviders reads the providers named in OIDC_PROVIDERS. Each provider
+// is configured with OIDC_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET and the
+// optional _SCOPES.
+func loadOIDCProviders() ([]OIDCProvider, error) {
+	var providers []OIDCProvider
+
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		provider := OIDCProvider{
+			Name:         name,
+			Issuer:       strings.TrimSuffix(os.Getenv(prefix+"ISSUER"), "/"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		}
+		if provider.Issuer == "" || provider.ClientID == "" {
+			return nil, fmt.Errorf("OIDC provider %s needs %sISSUER and %sCLIENT_ID", name, prefix, prefix)
+		}
+
+		providers = append(providers, provider)
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limiting algorithms
+const (
+	RateLimitSlidingWindow = "sliding_window"
+	RateLimitTokenBucket   = "token_bucket"
+)
+
+// Rate limit keys
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByUser   = "user"
+	RateLimitByAPIKey = "api_key"
+)
+
+// defaultRateLimitPolicies applies when RATE_LIMIT_POLICIES is not set
+const defaultRateLimitPolicies = "default=sliding_window:100/1m:ip,auth=sliding_window:10/1m:ip,user=token_bucket:300/1m:user"
+
+// RateLimitPolicy limits requests to Limit per Window. For the token bucket
+// algorithm Limit is the bucket size and the bucket refills over Window.
+type RateLimitPolicy struct {
+	Algorithm string
+	Limit     int
+	Window    time.Duration
+	KeyBy     string
+}
+
+// String formats the policy as a RateLimit-Policy header value
+func (p RateLimitPolicy) String() string {
+	return fmt.Sprintf("%d;w=%d", p.Limit, int(p.Window.Seconds()))
+}
+
+// parseRateLimitPolicies parses a comma separated list of
+// group=algorithm:limit/window:key entries, e.g. auth=sliding_window:10/1m:ip
+func parseRateLimitPolicies(value string) (map[string]RateLimitPolicy, error) {
+	policies := make(map[string]RateLimitPolicy)
+
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		group, spec, ok := strings.Cut(entry, "=")
+		parts := strings.Split(spec, ":")
+		if !ok || group == "" || len(parts) != 3 {
+			return nil, fmt.Errorf("invalid rate limit policy %q", entry)
+		}
+
+		policy := RateLimitPolicy{Algorithm: parts[0], KeyBy: parts[2]}
+		switch policy.Algorithm {
+		case RateLimitSlidingWindow, RateLimitTokenBucket:
+		default:
+			return nil, fmt.Errorf("invalid rate limit algorithm %q", policy.Algorithm)
+		}
+		switch policy.KeyBy {
+		case RateLimitByIP, RateLimitByUser, RateLimitByAPIKey:
+		default:
+			return nil, fmt.Errorf("invalid rate limit key %q", policy.KeyBy)
+		}
+
+		limit, window, ok := strings.Cut(parts[1], "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit %q", parts[1])
+		}
+		var err error
+		if policy.Limit, err = strconv.Atoi(limit); err != nil || policy.Limit < 1 {
+			return nil, fmt.Errorf("invalid rate limit %q", parts[1])
+		}
+		if policy.Window, err = time.ParseDuration(window); err != nil || policy.Window < time.Second {
+			return nil, fmt.Errorf("invalid rate limit window %q", parts[1])
+		}
+
+		policies[group] = policy
+	}
+
+	return policies, nil
+}
@@ -0,0 +1,491 @@
+package controllers
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/policies"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// AdminController handles HTTP requests for administering user accounts
+type AdminController struct {
+	userService  services.UserService
+	rbacService  services.RBACService
+	auditService services.AuditService
+	userPolicy   *policies.UserPolicy
+	config       *config.Config
+}
+
+// NewAdminController creates a new admin controller
+func NewAdminController(userService services.UserService, rbacService services.RBACService, auditService services.AuditService, userPolicy *policies.UserPolicy, config *config.Config) *AdminController {
+	return &AdminController{
+		userService:  userService,
+		rbacService:  rbacService,
+		auditService: auditService,
+		userPolicy:   userPolicy,
+		config:       config,
+	}
+}
+
+// ChangeRoleRequest represents the role change request body
+type ChangeRoleRequest struct {
+	Role string `json:"role" validate:"required" example:"support"`
+}
+
+// SuspendUserRequest represents the suspension request body
+type SuspendUserRequest struct {
+	Reason string `json:"reason" example:"Chargeback fraud"`
+}
+
+// ImpersonateRequest represents the impersonation request body
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required" example:"Ticket #4821: cannot see invoices"`
+}
+
+// RegisterAdmin registers user administration routes on the admin group
+func (c *AdminController) RegisterAdmin(admin fiber.Router) {
+	read := middlewares.RequirePermission(c.rbacService, models.PermissionUsersRead)
+	write := middlewares.RequirePermission(c.rbacService, models.PermissionUsersWrite)
+	remove := middlewares.RequirePermission(c.rbacService, models.PermissionUsersDelete)
+	audit := middlewares.RequirePermission(c.rbacService, models.PermissionAuditRead)
+	impersonate := middlewares.RequirePermission(c.rbacService, models.PermissionImpersonate)
+
+	users := admin.Group("/users")
+	users.Get("/", read, c.SearchUsers)
+	users.Get("/:id/logins", read, c.LoginHistory)
+	users.Put("/:id/role", write, c.manageable, c.ChangeRole)
+	users.Post("/:id/suspend", write, c.manageable, c.SuspendUser)
+	users.Post("/:id/unsuspend", write, c.manageable, c.UnsuspendUser)
+	users.Post("/:id/force-password-reset", write, c.manageable, c.ForcePasswordReset)
+	users.Post("/:id/unlock", write, c.manageable, c.UnlockUser)
+	users.Post("/:id/restore", write, c.manageable, c.RestoreUser)
+	users.Delete("/:id/purge", remove, c.manageable, c.PurgeUser)
+	users.Post("/:id/impersonate", impersonate, middlewares.RequireSession(), c.ImpersonateUser)
+
+	admin.Get("/audit-logs", audit, c.ListAuditLogs)
+}
+
+// manageable rejects requests on accounts whose role holds permissions the
+// admin lacks, so that an admin cannot act on a more privileged account
+func (c *AdminController) manageable(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	target, err := c.userService.GetAccount(ctx.UserContext(), uint(id))
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+	if err := c.userPolicy.CanManage(ctx.UserContext(), currentUser(ctx), target.Role); err != nil {
+		return policyError(ctx, err)
+	}
+
+	return ctx.Next()
+}
+
+// SearchUsers handles searching users
+// @Summary Search users
+// @Description Search and filter users, including suspended and soft-deleted accounts
+// @Tags Admin
+// @Produce json
+// @Param q query string false "Matches email or name"
+// @Param role query string false "Role name"
+// @Param status query string false "Account status" Enums(active, suspended, deleted)
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users [get]
+func (c *AdminController) SearchUsers(ctx *fiber.Ctx) error {
+	page, limit, offset := parsePagination(ctx, c.config.PaginationMaxLimit)
+
+	filter := repository.UserFilter{
+		Query:  ctx.Query("q"),
+		Role:   ctx.Query("role"),
+		Status: ctx.Query("status"),
+	}
+
+	switch filter.Status {
+	case "", repository.UserStatusActive, repository.UserStatusSuspended, repository.UserStatusDeleted:
+	default:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid status",
+		})
+	}
+
+	users, total, err := c.userService.Search(ctx.UserContext(), filter, offset, limit)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"users": users,
+		"page":  page,
+		"limit": limit,
+		"total": total,
+	})
+}
+
+// ChangeRole handles changing the role of a user
+// @Summary Change user role
+// @Description Assign a role to a user. The user is signed out so the new role takes effect immediately. Only roles whose permissions the admin holds can be assigned.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param role body ChangeRoleRequest true "New role"
+// @Success 200 {object} models.AdminUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/role [put]
+func (c *AdminController) ChangeRole(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	var req ChangeRoleRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Role == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := c.userPolicy.CanAssignRole(ctx.UserContext(), currentUser(ctx), req.Role); err != nil {
+		return policyError(ctx, err)
+	}
+
+	user, err := c.userService.ChangeRole(ctx.UserContext(), uint(id), req.Role)
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(user)
+}
+
+// SuspendUser handles suspending a user
+// @Summary Suspend user
+// @Description Block a user from logging in and revoke their tokens
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param suspension body SuspendUserRequest false "Suspension reason"
+// @Success 200 {object} models.AdminUserResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/suspend [post]
+func (c *AdminController) SuspendUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	var req SuspendUserRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+
+	user, err := c.userService.Suspend(ctx.UserContext(), uint(id), req.Reason)
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(user)
+}
+
+// UnsuspendUser handles lifting a suspension
+// @Summary Unsuspend user
+// @Description Allow a suspended user to log in again
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.AdminUserResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/unsuspend [post]
+func (c *AdminController) UnsuspendUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	user, err := c.userService.Unsuspend(ctx.UserContext(), uint(id))
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(user)
+}
+
+// ForcePasswordReset handles forcing a user to choose a new password
+// @Summary Force password reset
+// @Description Revoke the user's tokens and refuse logins until the password is changed
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.AdminUserResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/force-password-reset [post]
+func (c *AdminController) ForcePasswordReset(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	user, err := c.userService.ForcePasswordReset(ctx.UserContext(), uint(id))
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(user)
+}
+
+// UnlockUser handles lifting a login lockout
+// @Summary Unlock user
+// @Description Lift a lockout caused by failed login attempts and reset the back-off
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.AdminUserResponse
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/unlock [post]
+func (c *AdminController) UnlockUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	user, err := c.userService.Unlock(ctx.UserContext(), uint(id), currentUser(ctx).UserID)
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(user)
+}
+
+// RestoreUser handles restoring a soft-deleted user
+// @Summary Restore user
+// @Description Restore a soft-deleted user
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} models.AdminUserResponse
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/restore [post]
+func (c *AdminController) RestoreUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	user, err := c.userService.Restore(ctx.UserContext(), uint(id))
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(user)
+}
+
+// PurgeUser handles permanently deleting a user
+// @Summary Purge user
+// @Description Permanently delete a user and their login history. This cannot be undone.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/purge [delete]
+func (c *AdminController) PurgeUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	if err := c.userService.Purge(ctx.UserContext(), uint(id)); err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "user purged successfully",
+	})
+}
+
+// LoginHistory handles fetching the login history of a user
+// @Summary Get login history
+// @Description Get login attempts for a user, newest first
+// @Tags Admin
+// @Produce json
+// @Param id path int true "User ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/logins [get]
+func (c *AdminController) LoginHistory(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	page, limit, offset := parsePagination(ctx, c.config.PaginationMaxLimit)
+
+	events, err := c.userService.LoginHistory(ctx.UserContext(), uint(id), offset, limit)
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"logins": events,
+		"page":   page,
+		"limit":  limit,
+	})
+}
+
+// ListAuditLogs handles fetching the audit log
+// @Summary List audit log
+// @Description Get audit log entries, newest first
+// @Tags Admin
+// @Produce json
+// @Param action query string false "Action, e.g. login.lockout"
+// @Param user_id query int false "Affected user ID"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/audit-logs [get]
+func (c *AdminController) ListAuditLogs(ctx *fiber.Ctx) error {
+	page, limit, offset := parsePagination(ctx, c.config.PaginationMaxLimit)
+
+	filter := repository.AuditLogFilter{
+		Action: ctx.Query("action"),
+	}
+	if userID := ctx.Query("user_id"); userID != "" {
+		id, err := strconv.ParseUint(userID, 10, 32)
+		if err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid user id",
+			})
+		}
+		filter.UserID = uint(id)
+	}
+
+	entries, err := c.auditService.List(ctx.UserContext(), filter, offset, limit)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"entries": entries,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// ImpersonateUser handles issuing an impersonation token
+// @Summary Impersonate user
+// @Description Issue a short-lived access token for acting as a user. The token names the administrator in its act claim, cannot be refreshed, and cannot change credentials, MFA, sessions or API keys. Every impersonation is audited.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "User ID"
+// @Param impersonation body ImpersonateRequest true "Reason for the impersonation"
+// @Success 200 {object} models.ImpersonationToken
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/users/{id}/impersonate [post]
+func (c *AdminController) ImpersonateUser(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid user id",
+		})
+	}
+
+	var req ImpersonateRequest
+	if err := ctx.BodyParser(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "a reason is required",
+		})
+	}
+
+	token, err := c.userService.Impersonate(ctx.UserContext(), uint(id), currentUser(ctx).UserID, strings.TrimSpace(req.Reason), clientInfo(ctx))
+	if err == services.ErrCannotImpersonate {
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		return c.accountError(ctx, err)
+	}
+
+	return ctx.JSON(token)
+}
+
+// accountError writes the response for errors returned by account operations
+func (c *AdminController) accountError(ctx *fiber.Ctx, err error) error {
+	switch err {
+	case services.ErrUserNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	case services.ErrRoleNotFound, services.ErrUserNotDeleted:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "internal server error",
+	})
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// APIKeyController handles HTTP requests for personal API keys
+type APIKeyController struct {
+	apiKeyService services.APIKeyService
+}
+
+// NewAPIKeyController creates a new API key controller
+func NewAPIKeyController(apiKeyService services.APIKeyService) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// Register registers all API key routes
+func (c *APIKeyController) Register(app *fiber.App, auth fiber.Handler) {
+	api := app.Group("/api/v1")
+
+	// Protected routes
+	keys := api.Group("/api-keys", auth)
+	keys.Get("/", c.ListAPIKeys)
+	keys.Post("/", middlewares.RequireSession(), middlewares.DenyImpersonation(), c.CreateAPIKey)
+	keys.Delete("/:id", middlewares.DenyImpersonation(), c.RevokeAPIKey)
+}
+
+// ListAPIKeys handles fetching the current user's API keys
+// @Summary List API keys
+// @Description Get the current user's API keys that have not been revoked. Secrets are never returned.
+// @Tags API Keys
+// @Produce json
+// @Success 200 {array} models.APIKeyResponse
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /api-keys [get]
+func (c *APIKeyController) ListAPIKeys(ctx *fiber.Ctx) error {
+	keys, err := c.apiKeyService.List(ctx.UserContext(), currentUser(ctx).UserID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(keys)
+}
+
+// CreateAPIKey handles API key creation
+// @Summary Create API key
+// @Description Create a personal API key to send in the X-API-Key header. The key is only shown in this response. Scopes are permission names that narrow what the key can do; without scopes the key has all permissions of the user's role. Requires a login session.
+// @Tags API Keys
+// @Accept json
+// @Produce json
+// @Param key body models.CreateAPIKeyRequest true "API key settings"
+// @Success 201 {object} models.APIKeyCreated
+// @Failure 400 {array} models.ValidationError
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /api-keys [post]
+func (c *APIKeyController) CreateAPIKey(ctx *fiber.Ctx) error {
+	var req models.CreateAPIKeyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate request input
+	if errors := req.Validate(); errors != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
+	}
+
+	key, err := c.apiKeyService.Create(ctx.UserContext(), currentUser(ctx).UserID, &req)
+	if err != nil {
+		if err == services.ErrInvalidScope || err == services.ErrInvalidExpiry {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrUserNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create API key",
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(key)
+}
+
+// RevokeAPIKey handles revoking an API key
+// @Summary Revoke API key
+// @Description Revoke one of the current user's API keys. It stops working immediately.
+// @Tags API Keys
+// @Produce json
+// @Param id path int true "API key ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /api-keys/{id} [delete]
+func (c *APIKeyController) RevokeAPIKey(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid API key id",
+		})
+	}
+
+	if err := c.apiKeyService.Revoke(ctx.UserContext(), currentUser(ctx).UserID, uint(id)); err != nil {
+		if err == services.ErrAPIKeyNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,209 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// AuthController handles HTTP requests for token management
+type AuthController struct {
+	tokenService services.TokenService
+}
+
+// NewAuthController creates a new auth controller
+func NewAuthController(tokenService services.TokenService) *AuthController {
+	return &AuthController{
+		tokenService: tokenService,
+	}
+}
+
+// RefreshTokenRequest represents the token refresh request body
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LogoutRequest represents the logout request body
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Register registers all auth routes
+func (c *AuthController) Register(app *fiber.App, auth fiber.Handler) {
+	api := app.Group("/api/v1")
+
+	// Public routes
+	api.Post("/token/refresh", c.RefreshToken)
+
+	// Protected routes
+	api.Post("/logout", auth, middlewares.RequireSession(), c.Logout)
+	api.Post("/logout-all", auth, middlewares.RequireSession(), middlewares.DenyImpersonation(), c.LogoutAll)
+
+	sessions := api.Group("/sessions", auth, middlewares.RequireSession())
+	sessions.Get("/", c.ListSessions)
+	sessions.Delete("/:id", middlewares.DenyImpersonation(), c.RevokeSession)
+}
+
+// RefreshToken handles refresh token rotation
+// @Summary Refresh access token
+// @Description Exchange a refresh token for a new access token and a rotated refresh token. Reusing a rotated refresh token revokes the whole token family.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param refresh body RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /token/refresh [post]
+func (c *AuthController) RefreshToken(ctx *fiber.Ctx) error {
+	var req RefreshTokenRequest
+	if err := ctx.BodyParser(&req); err != nil || req.RefreshToken == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	tokens, err := c.tokenService.Refresh(ctx.UserContext(), req.RefreshToken)
+	if err != nil {
+		if err == services.ErrInvalidRefreshToken || err == services.ErrRefreshTokenReused {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(tokens)
+}
+
+// Logout handles revoking the current session
+// @Summary Logout
+// @Description Revoke the current access token and end the session it belongs to. A refresh token can be provided to also end its session.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param logout body LogoutRequest false "Refresh token to revoke"
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /logout [post]
+func (c *AuthController) Logout(ctx *fiber.Ctx) error {
+	claims := currentUser(ctx)
+
+	var req LogoutRequest
+	if len(ctx.Body()) > 0 {
+		if err := ctx.BodyParser(&req); err != nil {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "invalid request body",
+			})
+		}
+	}
+
+	if err := c.tokenService.RevokeAccessToken(ctx.UserContext(), claims); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	if req.RefreshToken != "" {
+		if err := c.tokenService.RevokeRefreshToken(ctx.UserContext(), claims.UserID, req.RefreshToken); err != nil {
+			if err == services.ErrInvalidRefreshToken {
+				return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": err.Error(),
+				})
+			}
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "internal server error",
+			})
+		}
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "logged out successfully",
+	})
+}
+
+// LogoutAll handles revoking every session of the current user
+// @Summary Logout from all devices
+// @Description Invalidate every access and refresh token issued to the current user
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /logout-all [post]
+func (c *AuthController) LogoutAll(ctx *fiber.Ctx) error {
+	claims := currentUser(ctx)
+
+	if err := c.tokenService.RevokeAllForUser(ctx.UserContext(), claims.UserID); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "logged out from all devices",
+	})
+}
+
+// ListSessions handles fetching the current user's sessions
+// @Summary List sessions
+// @Description Get the devices the current user is signed in on, most recently seen first. The session of the current request is flagged as current.
+// @Tags Sessions
+// @Produce json
+// @Success 200 {array} models.SessionResponse
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /sessions [get]
+func (c *AuthController) ListSessions(ctx *fiber.Ctx) error {
+	claims := currentUser(ctx)
+
+	sessions, err := c.tokenService.ListSessions(ctx.UserContext(), claims.UserID, claims.SessionID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(sessions)
+}
+
+// RevokeSession handles signing out of one session
+// @Summary Revoke session
+// @Description Sign the current user out on one device. Access tokens of the session stop working immediately and its refresh token can no longer be used.
+// @Tags Sessions
+// @Produce json
+// @Param id path int true "Session ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /sessions/{id} [delete]
+func (c *AuthController) RevokeSession(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid session id",
+		})
+	}
+
+	if err := c.tokenService.RevokeSession(ctx.UserContext(), currentUser(ctx).UserID, uint(id)); err != nil {
+		if err == services.ErrSessionNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "session revoked successfully",
+	})
+}
@@ -0,0 +1,73 @@
+package controllers
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/policies"
+	"github.com/yourusername/go-production-level/internal/services"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// currentUser returns the claims set by AuthMiddleware, or nil for anonymous requests
+func currentUser(ctx *fiber.Ctx) *utils.JWTClaims {
+	claims, _ := ctx.Locals("user").(*utils.JWTClaims)
+	return claims
+}
+
+// clientInfo describes the client that sent the request
+func clientInfo(ctx *fiber.Ctx) services.ClientInfo {
+	return services.ClientInfo{
+		IP:        ctx.IP(),
+		UserAgent: ctx.Get(fiber.HeaderUserAgent),
+	}
+}
+
+// policyError writes the response for a denied policy decision
+func policyError(ctx *fiber.Ctx, err error) error {
+	switch err {
+	case policies.ErrUnauthenticated:
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case policies.ErrForbidden:
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "internal server error",
+	})
+}
+
+// retryLater writes a 429 response telling the client when to try again
+func retryLater(ctx *fiber.Ctx, err error, code string, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(seconds))
+	return ctx.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+		"error":       err.Error(),
+		"code":        code,
+		"retry_after": seconds,
+	})
+}
+
+// parsePagination reads the page and limit query parameters and returns them
+// with the matching offset. limit is capped at maxLimit.
+func parsePagination(ctx *fiber.Ctx, maxLimit int) (page, limit, offset int) {
+	page, _ = strconv.Atoi(ctx.Query("page", "1"))
+	limit, _ = strconv.Atoi(ctx.Query("limit", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = 10
+	}
+	if limit > maxLimit {
+		limit = maxLimit
+	}
+
+	return page, limit, (page - 1) * limit
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// EmailController handles HTTP requests for email verification
+type EmailController struct {
+	verificationService services.EmailVerificationService
+}
+
+// NewEmailController creates a new email controller
+func NewEmailController(verificationService services.EmailVerificationService) *EmailController {
+	return &EmailController{
+		verificationService: verificationService,
+	}
+}
+
+// VerifyEmailRequest represents the email verification request body
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// ResendVerificationRequest represents the resend verification request body
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email" example:"john@example.com"`
+}
+
+// Register registers all email verification routes
+func (c *EmailController) Register(app *fiber.App) {
+	api := app.Group("/api/v1")
+
+	// Public routes
+	api.Post("/email/verify", c.VerifyEmail)
+	api.Post("/email/verify/resend", c.ResendVerification)
+}
+
+// VerifyEmail handles confirming an email address
+// @Summary Verify email address
+// @Description Confirm an email address with the token from the verification email, or a new address with the token from the email change confirmation
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /email/verify [post]
+func (c *EmailController) VerifyEmail(ctx *fiber.Ctx) error {
+	var req VerifyEmailRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := c.verificationService.Verify(ctx.UserContext(), req.Token); err != nil {
+		if err == services.ErrInvalidVerificationToken {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrEmailExists {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "email verified successfully",
+	})
+}
+
+// ResendVerification handles sending a new verification email
+// @Summary Resend verification email
+// @Description Send a new verification link. The response is the same whether or not the account exists. Limited to one request per address per interval.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResendVerificationRequest true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 429 {object} map[string]interface{}
+// @Router /email/verify/resend [post]
+func (c *EmailController) ResendVerification(ctx *fiber.Ctx) error {
+	var req ResendVerificationRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Email == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	retryAfter, err := c.verificationService.Resend(ctx.UserContext(), req.Email)
+	if err != nil {
+		if err == services.ErrVerificationThrottled {
+			return retryLater(ctx, err, "verification_throttled", retryAfter)
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "if an unverified account exists for this email, a verification link has been sent",
+	})
+}
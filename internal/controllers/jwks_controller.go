@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// JWKSController publishes the public keys used to verify access tokens
+type JWKSController struct {
+	keys *utils.KeyRing
+}
+
+// NewJWKSController creates a new JWKS controller
+func NewJWKSController(keys *utils.KeyRing) *JWKSController {
+	return &JWKSController{
+		keys: keys,
+	}
+}
+
+// Register registers the JWKS route
+func (c *JWKSController) Register(app *fiber.App) {
+	app.Get("/.well-known/jwks.json", c.JWKS)
+}
+
+// JWKS handles the JSON Web Key Set endpoint
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens, identified by kid
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} utils.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (c *JWKSController) JWKS(ctx *fiber.Ctx) error {
+	ctx.Set(fiber.HeaderCacheControl, "public, max-age=300")
+	return ctx.JSON(c.keys.JWKS())
+}
@@ -0,0 +1,106 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// MagicLinkController handles HTTP requests for passwordless login
+type MagicLinkController struct {
+	magicLinkService services.MagicLinkService
+}
+
+// NewMagicLinkController creates a new magic link controller
+func NewMagicLinkController(magicLinkService services.MagicLinkService) *MagicLinkController {
+	return &MagicLinkController{
+		magicLinkService: magicLinkService,
+	}
+}
+
+// MagicLinkRequest represents the login link request body
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email" example:"john@example.com"`
+}
+
+// MagicLinkLoginRequest represents the login link exchange request body
+type MagicLinkLoginRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// Register registers all magic link routes
+func (c *MagicLinkController) Register(app *fiber.App) {
+	api := app.Group("/api/v1")
+
+	// Public routes
+	api.Post("/login/magic-link", c.RequestLink)
+	api.Post("/login/magic-link/verify", c.Login)
+}
+
+// RequestLink handles requesting a login link
+// @Summary Request login link
+// @Description Email a single-use login link. The response is the same whether or not the account exists, and repeated requests for the same address within the resend interval are ignored.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body MagicLinkRequest true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /login/magic-link [post]
+func (c *MagicLinkController) RequestLink(ctx *fiber.Ctx) error {
+	var req MagicLinkRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Email == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := c.magicLinkService.RequestLink(ctx.UserContext(), req.Email); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "if an account exists for this email, a login link has been sent",
+	})
+}
+
+// Login handles exchanging a login link for tokens
+// @Summary Log in with link
+// @Description Exchange the token from a login link for the same response as /login. Each link works once and also verifies the email address.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body MagicLinkLoginRequest true "Token from the login link"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /login/magic-link/verify [post]
+func (c *MagicLinkController) Login(ctx *fiber.Ctx) error {
+	var req MagicLinkLoginRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	result, err := c.magicLinkService.Login(ctx.UserContext(), req.Token, clientInfo(ctx))
+	if err != nil {
+		if err == services.ErrInvalidMagicLink {
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrAccountSuspended || err == services.ErrPasswordResetRequired {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(result)
+}
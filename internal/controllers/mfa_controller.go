@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// MFAController handles HTTP requests for two-factor authentication
+type MFAController struct {
+	mfaService services.MFAService
+}
+
+// NewMFAController creates a new MFA controller
+func NewMFAController(mfaService services.MFAService) *MFAController {
+	return &MFAController{
+		mfaService: mfaService,
+	}
+}
+
+// MFACodeRequest represents a request carrying a TOTP or recovery code
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required" example:"123456"`
+}
+
+// MFAVerifyRequest represents the second step of a two-step login
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required" example:"123456"`
+}
+
+// Register registers all MFA routes
+func (c *MFAController) Register(app *fiber.App, auth fiber.Handler) {
+	api := app.Group("/api/v1")
+
+	// Public routes
+	api.Post("/mfa/verify", c.Verify)
+
+	// Protected routes
+	totp := api.Group("/mfa/totp", auth, middlewares.RequireSession(), middlewares.DenyImpersonation())
+	totp.Post("/enroll", c.Enroll)
+	totp.Post("/confirm", c.Confirm)
+	totp.Post("/disable", c.Disable)
+}
+
+// Enroll handles starting TOTP enrollment
+// @Summary Start TOTP enrollment
+// @Description Generate a TOTP secret and the otpauth:// URI to show as a QR code. Two-factor authentication is enabled once the first code is confirmed.
+// @Tags MFA
+// @Produce json
+// @Success 200 {object} models.TOTPEnrollment
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /mfa/totp/enroll [post]
+func (c *MFAController) Enroll(ctx *fiber.Ctx) error {
+	enrollment, err := c.mfaService.BeginEnrollment(ctx.UserContext(), currentUser(ctx).UserID)
+	if err != nil {
+		return c.mfaError(ctx, err)
+	}
+
+	return ctx.JSON(enrollment)
+}
+
+// Confirm handles confirming TOTP enrollment
+// @Summary Confirm TOTP enrollment
+// @Description Enable two-factor authentication with a first TOTP code. Returns one-time recovery codes that are never shown again.
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param code body MFACodeRequest true "TOTP code"
+// @Success 200 {object} map[string][]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /mfa/totp/confirm [post]
+func (c *MFAController) Confirm(ctx *fiber.Ctx) error {
+	var req MFACodeRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Code == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	codes, err := c.mfaService.ConfirmEnrollment(ctx.UserContext(), currentUser(ctx).UserID, req.Code)
+	if err != nil {
+		return c.mfaError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"recovery_codes": codes,
+	})
+}
+
+// Disable handles turning off two-factor authentication
+// @Summary Disable TOTP
+// @Description Disable two-factor authentication with a TOTP or recovery code
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param code body MFACodeRequest true "TOTP or recovery code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /mfa/totp/disable [post]
+func (c *MFAController) Disable(ctx *fiber.Ctx) error {
+	var req MFACodeRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Code == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := c.mfaService.Disable(ctx.UserContext(), currentUser(ctx).UserID, req.Code); err != nil {
+		return c.mfaError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "two-factor authentication disabled",
+	})
+}
+
+// Verify handles the second step of a two-step login
+// @Summary Complete two-step login
+// @Description Exchange the MFA token returned by /login and a TOTP or recovery code for access and refresh tokens
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Param verify body MFAVerifyRequest true "MFA token and code"
+// @Success 200 {object} models.TokenPair
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 429 {object} map[string]interface{}
+// @Router /mfa/verify [post]
+func (c *MFAController) Verify(ctx *fiber.Ctx) error {
+	var req MFAVerifyRequest
+	if err := ctx.BodyParser(&req); err != nil || req.MFAToken == "" || req.Code == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	tokens, err := c.mfaService.VerifyChallenge(ctx.UserContext(), req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		return c.mfaError(ctx, err)
+	}
+
+	return ctx.JSON(tokens)
+}
+
+// mfaError writes the response for errors returned by the MFA service
+func (c *MFAController) mfaError(ctx *fiber.Ctx, err error) error {
+	var lockout *services.LockoutError
+	if errors.As(err, &lockout) {
+		code := "account_locked"
+		if lockout.Err == services.ErrTooManyLoginAttempts {
+			code = "too_many_attempts"
+		}
+		return retryLater(ctx, lockout, code, lockout.RetryAfter)
+	}
+
+	switch err {
+	case services.ErrInvalidMFACode, services.ErrInvalidMFAToken:
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrMFAAlreadyEnabled:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrMFANotEnabled, services.ErrMFANotEnrolled:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrUserNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "user not found",
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "internal server error",
+	})
+}
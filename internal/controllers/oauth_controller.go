@@ -0,0 +1,412 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// OAuthController handles HTTP requests for the OAuth2 authorization server
+type OAuthController struct {
+	oauthService services.OAuthService
+	rbacService  services.RBACService
+}
+
+// NewOAuthController creates a new OAuth2 controller
+func NewOAuthController(oauthService services.OAuthService, rbacService services.RBACService) *OAuthController {
+	return &OAuthController{
+		oauthService: oauthService,
+		rbacService:  rbacService,
+	}
+}
+
+// Register registers the authorization server routes
+func (c *OAuthController) Register(app *fiber.App, auth fiber.Handler) {
+	api := app.Group("/api/v1")
+
+	// Client authenticated endpoints
+	oauth := api.Group("/oauth")
+	oauth.Post("/token", c.Token)
+	oauth.Post("/introspect", c.Introspect)
+	oauth.Post("/revoke", c.Revoke)
+
+	// Consent screen API, only for the user's own login session
+	oauth.Get("/authorize", auth, middlewares.RequireSession(), c.AuthorizePrompt)
+	oauth.Post("/authorize", auth, middlewares.RequireSession(), middlewares.DenyImpersonation(), c.Authorize)
+	oauth.Get("/consents", auth, c.ListConsents)
+	oauth.Delete("/consents/:id", auth, middlewares.RequireSession(), middlewares.DenyImpersonation(), c.RevokeConsent)
+}
+
+// RegisterAdmin registers client registration routes on the admin group
+func (c *OAuthController) RegisterAdmin(admin fiber.Router) {
+	manage := middlewares.RequirePermission(c.rbacService, models.PermissionClientsManage)
+
+	clients := admin.Group("/oauth-clients", manage)
+	clients.Get("/", c.ListClients)
+	clients.Post("/", c.CreateClient)
+	clients.Delete("/:id", c.DeleteClient)
+}
+
+// AuthorizePrompt handles checking an authorization request for the consent screen
+// @Summary Get authorization request
+// @Description Validate an OAuth2 authorization request and describe it for the consent screen. The consent screen then approves or denies it with POST /oauth/authorize. PKCE with S256 is required. Requires a login session.
+// @Tags OAuth2
+// @Produce json
+// @Param response_type query string true "Must be code"
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param scope query string false "Space separated scopes, all scopes of the client when omitted"
+// @Param state query string false "Opaque value returned to the client"
+// @Param code_challenge query string true "PKCE code challenge"
+// @Param code_challenge_method query string true "Must be S256"
+// @Success 200 {object} models.OAuthAuthorizePrompt
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /oauth/authorize [get]
+func (c *OAuthController) AuthorizePrompt(ctx *fiber.Ctx) error {
+	var req models.OAuthAuthorizeRequest
+	if err := ctx.QueryParser(&req); err != nil {
+		return oauthErrorResponse(ctx, &services.OAuthError{Code: services.OAuthInvalidRequest, Description: "invalid query parameters"})
+	}
+
+	prompt, err := c.oauthService.PrepareAuthorization(ctx.UserContext(), currentUser(ctx).UserID, &req)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(prompt)
+}
+
+// Authorize handles the user's decision on the consent screen
+// @Summary Approve or deny authorization
+// @Description Approve or deny an OAuth2 authorization request. The response names the URL to send the user back to, carrying an authorization code or an access_denied error. Requires a login session.
+// @Tags OAuth2
+// @Accept json
+// @Produce json
+// @Param decision body models.OAuthConsentDecision true "Authorization request and decision"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /oauth/authorize [post]
+func (c *OAuthController) Authorize(ctx *fiber.Ctx) error {
+	var decision models.OAuthConsentDecision
+	if err := ctx.BodyParser(&decision); err != nil {
+		return oauthErrorResponse(ctx, &services.OAuthError{Code: services.OAuthInvalidRequest, Description: "invalid request body"})
+	}
+
+	redirectTo, err := c.oauthService.Authorize(ctx.UserContext(), currentUser(ctx).UserID, &decision)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"redirect_to": redirectTo,
+	})
+}
+
+// Token handles the OAuth2 token endpoint
+// @Summary Issue tokens
+// @Description OAuth2 token endpoint (RFC 6749) for the authorization_code, client_credentials and refresh_token grants. Clients authenticate with HTTP Basic or client_id and client_secret form fields; public clients send only client_id.
+// @Tags OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "Grant type" Enums(authorization_code, client_credentials, refresh_token)
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI of the authorization request"
+// @Param code_verifier formData string false "PKCE code verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Space separated scopes"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} models.OAuthTokenResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/token [post]
+func (c *OAuthController) Token(ctx *fiber.Ctx) error {
+	var req models.OAuthTokenRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return oauthErrorResponse(ctx, &services.OAuthError{Code: services.OAuthInvalidRequest, Description: "invalid request body"})
+	}
+	if err := clientCredentials(ctx, &req.ClientID, &req.ClientSecret); err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	tokens, err := c.oauthService.Token(ctx.UserContext(), &req)
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderPragma, "no-cache")
+	return ctx.JSON(tokens)
+}
+
+// Introspect handles token introspection
+// @Summary Introspect token
+// @Description OAuth2 token introspection (RFC 7662) for access and refresh tokens. Only confidential clients may call it.
+// @Tags OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to introspect"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200 {object} models.OAuthIntrospection
+// @Failure 401 {object} map[string]string
+// @Router /oauth/introspect [post]
+func (c *OAuthController) Introspect(ctx *fiber.Ctx) error {
+	clientID, clientSecret := ctx.FormValue("client_id"), ctx.FormValue("client_secret")
+	if err := clientCredentials(ctx, &clientID, &clientSecret); err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	result, err := c.oauthService.Introspect(ctx.UserContext(), clientID, clientSecret, ctx.FormValue("token"), ctx.FormValue("token_type_hint"))
+	if err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	return ctx.JSON(result)
+}
+
+// Revoke handles token revocation
+// @Summary Revoke token
+// @Description OAuth2 token revocation (RFC 7009). Revoking a refresh token revokes every token rotated from the same grant. Unknown tokens are ignored.
+// @Tags OAuth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param token formData string true "Token to revoke"
+// @Param token_type_hint formData string false "access_token or refresh_token"
+// @Param client_id formData string false "Client ID, unless sent with HTTP Basic"
+// @Param client_secret formData string false "Client secret, unless sent with HTTP Basic"
+// @Success 200
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Router /oauth/revoke [post]
+func (c *OAuthController) Revoke(ctx *fiber.Ctx) error {
+	clientID, clientSecret := ctx.FormValue("client_id"), ctx.FormValue("client_secret")
+	if err := clientCredentials(ctx, &clientID, &clientSecret); err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	token := ctx.FormValue("token")
+	if token == "" {
+		return oauthErrorResponse(ctx, &services.OAuthError{Code: services.OAuthInvalidRequest, Description: "token is required"})
+	}
+
+	if err := c.oauthService.Revoke(ctx.UserContext(), clientID, clientSecret, token); err != nil {
+		return oauthErrorResponse(ctx, err)
+	}
+
+	return ctx.SendStatus(fiber.StatusOK)
+}
+
+// ListConsents handles fetching the clients the current user has authorized
+// @Summary List authorized applications
+// @Description Get the OAuth2 clients the current user has granted access to, with the granted scopes
+// @Tags OAuth2
+// @Produce json
+// @Success 200 {array} models.OAuthConsentResponse
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /oauth/consents [get]
+func (c *OAuthController) ListConsents(ctx *fiber.Ctx) error {
+	consents, err := c.oauthService.ListConsents(ctx.UserContext(), currentUser(ctx).UserID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(consents)
+}
+
+// RevokeConsent handles withdrawing access from a client
+// @Summary Revoke authorized application
+// @Description Withdraw the consent given to an OAuth2 client and revoke its refresh tokens for the current user. Requires a login session.
+// @Tags OAuth2
+// @Produce json
+// @Param id path int true "Consent ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /oauth/consents/{id} [delete]
+func (c *OAuthController) RevokeConsent(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid consent id",
+		})
+	}
+
+	if err := c.oauthService.RevokeConsent(ctx.UserContext(), currentUser(ctx).UserID, uint(id)); err != nil {
+		if err == services.ErrConsentNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "consent revoked successfully",
+	})
+}
+
+// ListClients handles fetching the registered OAuth2 clients
+// @Summary List OAuth2 clients
+// @Description Get all registered OAuth2 clients. Secrets are never returned.
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.OAuthClientResponse
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/oauth-clients [get]
+func (c *OAuthController) ListClients(ctx *fiber.Ctx) error {
+	clients, err := c.oauthService.ListClients(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(clients)
+}
+
+// CreateClient handles OAuth2 client registration
+// @Summary Register OAuth2 client
+// @Description Register an OAuth2 client. Confidential clients get a secret that is only shown in this response; public clients have none and must use PKCE. Scopes are the permission names the client may request.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param client body models.CreateOAuthClientRequest true "Client settings"
+// @Success 201 {object} models.OAuthClientCreated
+// @Failure 400 {array} models.ValidationError
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/oauth-clients [post]
+func (c *OAuthController) CreateClient(ctx *fiber.Ctx) error {
+	var req models.CreateOAuthClientRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate request input
+	if errors := req.Validate(); errors != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
+	}
+
+	client, err := c.oauthService.CreateClient(ctx.UserContext(), &req)
+	if err != nil {
+		switch err {
+		case services.ErrInvalidScope, services.ErrRedirectURIRequired, services.ErrInvalidRedirectURI, services.ErrInvalidGrantTypes:
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to register client",
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(client)
+}
+
+// DeleteClient handles removing an OAuth2 client
+// @Summary Delete OAuth2 client
+// @Description Remove an OAuth2 client with its consents and refresh tokens. Access tokens already issued expire on their own.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Client ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/oauth-clients/{id} [delete]
+func (c *OAuthController) DeleteClient(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid client id",
+		})
+	}
+
+	if err := c.oauthService.DeleteClient(ctx.UserContext(), uint(id)); err != nil {
+		if err == services.ErrOAuthClientNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "client deleted successfully",
+	})
+}
+
+// clientCredentials reads client credentials from an HTTP Basic Authorization
+// header (RFC 6749 section 2.3.1). Without the header the form values are kept.
+func clientCredentials(ctx *fiber.Ctx, clientID, clientSecret *string) error {
+	header := ctx.Get(fiber.HeaderAuthorization)
+	if header == "" {
+		return nil
+	}
+
+	invalid := &services.OAuthError{Code: services.OAuthInvalidClient, Description: "invalid Basic authorization header"}
+	encoded, ok := strings.CutPrefix(header, "Basic ")
+	if !ok {
+		return invalid
+	}
+	decoded, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return invalid
+	}
+	id, secret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return invalid
+	}
+	if *clientID, err = url.QueryUnescape(id); err != nil {
+		return invalid
+	}
+	if *clientSecret, err = url.QueryUnescape(secret); err != nil {
+		return invalid
+	}
+	return nil
+}
+
+// oauthErrorResponse writes an OAuth2 error response (RFC 6749 section 5.2)
+func oauthErrorResponse(ctx *fiber.Ctx, err error) error {
+	var oauthErr *services.OAuthError
+	if !errors.As(err, &oauthErr) {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "server_error",
+		})
+	}
+
+	status := fiber.StatusBadRequest
+	if oauthErr.Code == services.OAuthInvalidClient {
+		status = fiber.StatusUnauthorized
+		ctx.Set(fiber.HeaderWWWAuthenticate, `Basic realm="oauth"`)
+	}
+	return ctx.Status(status).JSON(fiber.Map{
+		"error":             oauthErr.Code,
+		"error_description": oauthErr.Description,
+	})
+}
@@ -0,0 +1,191 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// OIDCController handles HTTP requests for OpenID Connect social login
+type OIDCController struct {
+	oidcService services.OIDCService
+}
+
+// NewOIDCController creates a new OpenID Connect controller
+func NewOIDCController(oidcService services.OIDCService) *OIDCController {
+	return &OIDCController{
+		oidcService: oidcService,
+	}
+}
+
+// Register registers all OpenID Connect routes
+func (c *OIDCController) Register(app *fiber.App, auth fiber.Handler) {
+	api := app.Group("/api/v1")
+
+	// Public routes
+	oidc := api.Group("/auth/oidc")
+	oidc.Get("/providers", c.ListProviders)
+	oidc.Get("/:provider/login", c.BeginLogin)
+	oidc.Get("/:provider/callback", c.Callback)
+
+	// Protected routes
+	identities := api.Group("/auth/identities", auth)
+	identities.Get("/", c.ListIdentities)
+	identities.Delete("/:id", middlewares.RequireSession(), middlewares.DenyImpersonation(), c.UnlinkIdentity)
+}
+
+// ListProviders handles fetching the configured identity providers
+// @Summary List identity providers
+// @Description Get the names of the OpenID Connect providers users can sign in with
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} map[string][]string
+// @Router /auth/oidc/providers [get]
+func (c *OIDCController) ListProviders(ctx *fiber.Ctx) error {
+	return ctx.JSON(fiber.Map{
+		"providers": c.oidcService.Providers(),
+	})
+}
+
+// BeginLogin handles starting a social login
+// @Summary Start social login
+// @Description Redirect to the identity provider to sign in. The authorization code flow uses PKCE, and the state and nonce are checked on the callback.
+// @Tags Authentication
+// @Param provider path string true "Provider name"
+// @Success 302
+// @Failure 404 {object} map[string]string
+// @Router /auth/oidc/{provider}/login [get]
+func (c *OIDCController) BeginLogin(ctx *fiber.Ctx) error {
+	authURL, err := c.oidcService.BeginLogin(ctx.UserContext(), ctx.Params("provider"))
+	if err != nil {
+		if err == services.ErrUnknownProvider {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": "identity provider unavailable",
+		})
+	}
+
+	return ctx.Redirect(authURL, fiber.StatusFound)
+}
+
+// Callback handles the redirect back from the identity provider
+// @Summary Complete social login
+// @Description Complete a social login and return tokens like /login. On first use the identity is linked to the account with the same email, which must be verified both at the provider and locally, or a new account is created.
+// @Tags Authentication
+// @Produce json
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code"
+// @Param state query string true "State from the login redirect"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/oidc/{provider}/callback [get]
+func (c *OIDCController) Callback(ctx *fiber.Ctx) error {
+	// The provider reports errors such as a denied consent through the query string
+	if providerErr := ctx.Query("error"); providerErr != "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "identity provider returned " + providerErr,
+		})
+	}
+
+	state, code := ctx.Query("state"), ctx.Query("code")
+	if state == "" || code == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "code and state are required",
+		})
+	}
+
+	result, err := c.oidcService.CompleteLogin(ctx.UserContext(), ctx.Params("provider"), state, code, clientInfo(ctx))
+	if err != nil {
+		switch err {
+		case services.ErrUnknownProvider:
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrInvalidOIDCState:
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrOIDCLoginFailed:
+			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrOIDCAccountExists:
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case services.ErrOIDCEmailNotVerified, services.ErrUserNotFound, services.ErrAccountSuspended, services.ErrPasswordResetRequired, services.ErrEmailNotVerified:
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(result)
+}
+
+// ListIdentities handles fetching the current user's linked identities
+// @Summary List linked identities
+// @Description Get the external identities linked to the current user
+// @Tags Authentication
+// @Produce json
+// @Success 200 {array} models.LinkedIdentity
+// @Failure 401 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/identities [get]
+func (c *OIDCController) ListIdentities(ctx *fiber.Ctx) error {
+	identities, err := c.oidcService.ListIdentities(ctx.UserContext(), currentUser(ctx).UserID)
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(identities)
+}
+
+// UnlinkIdentity handles removing a linked identity
+// @Summary Unlink identity
+// @Description Remove an external identity from the current user. Signing in with it afterwards links it again if the email matches. Requires a login session.
+// @Tags Authentication
+// @Produce json
+// @Param id path int true "Linked identity ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /auth/identities/{id} [delete]
+func (c *OIDCController) UnlinkIdentity(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid identity id",
+		})
+	}
+
+	if err := c.oidcService.Unlink(ctx.UserContext(), currentUser(ctx).UserID, uint(id)); err != nil {
+		if err == services.ErrIdentityNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "identity unlinked successfully",
+	})
+}
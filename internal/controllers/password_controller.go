@@ -0,0 +1,104 @@
+package controllers
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// PasswordController handles HTTP requests for password recovery
+type PasswordController struct {
+	passwordService services.PasswordService
+}
+
+// NewPasswordController creates a new password controller
+func NewPasswordController(passwordService services.PasswordService) *PasswordController {
+	return &PasswordController{
+		passwordService: passwordService,
+	}
+}
+
+// ForgotPasswordRequest represents the forgot password request body
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email" example:"john@example.com"`
+}
+
+// ResetPasswordRequest represents the password reset request body
+type ResetPasswordRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required" example:"newpassword123"`
+}
+
+// Register registers all password recovery routes
+func (c *PasswordController) Register(app *fiber.App) {
+	api := app.Group("/api/v1")
+
+	// Public routes
+	api.Post("/password/forgot", c.ForgotPassword)
+	api.Post("/password/reset", c.ResetPassword)
+}
+
+// ForgotPassword handles requesting a password reset email
+// @Summary Request password reset
+// @Description Email a single-use password reset link. The response is the same whether or not the account exists.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Router /password/forgot [post]
+func (c *PasswordController) ForgotPassword(ctx *fiber.Ctx) error {
+	var req ForgotPasswordRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Email == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := c.passwordService.RequestReset(ctx.UserContext(), req.Email); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "if an account exists for this email, a reset link has been sent",
+	})
+}
+
+// ResetPassword handles completing a password reset
+// @Summary Reset password
+// @Description Set a new password with a reset token. All existing sessions of the user are signed out. A password rejected by the password policy leaves the token usable for another attempt.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {array} models.ValidationError
+// @Router /password/reset [post]
+func (c *PasswordController) ResetPassword(ctx *fiber.Ctx) error {
+	var req ResetPasswordRequest
+	if err := ctx.BodyParser(&req); err != nil || req.Token == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	if err := c.passwordService.ResetPassword(ctx.UserContext(), req.Token, req.Password); err != nil {
+		if err == services.ErrInvalidResetToken {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if policyErr, ok := err.(*services.PasswordPolicyError); ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(policyErr.Violations)
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "password reset successfully",
+	})
+}
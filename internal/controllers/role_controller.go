@@ -0,0 +1,381 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/policies"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// RoleController handles HTTP requests for roles and permissions
+type RoleController struct {
+	rbacService services.RBACService
+	rolePolicy  *policies.RolePolicy
+}
+
+// NewRoleController creates a new role controller
+func NewRoleController(rbacService services.RBACService, rolePolicy *policies.RolePolicy) *RoleController {
+	return &RoleController{
+		rbacService: rbacService,
+		rolePolicy:  rolePolicy,
+	}
+}
+
+// RolePermissionsRequest represents the body for replacing a role's permissions
+type RolePermissionsRequest struct {
+	Permissions []string `json:"permissions" example:"users:read,users:delete"`
+}
+
+// RegisterAdmin registers role management routes on the admin group
+func (c *RoleController) RegisterAdmin(admin fiber.Router) {
+	manage := middlewares.RequirePermission(c.rbacService, models.PermissionRolesManage)
+
+	roles := admin.Group("/roles", manage)
+	roles.Get("/", c.ListRoles)
+	roles.Post("/", c.CreateRole)
+	roles.Get("/:id", c.GetRole)
+	roles.Put("/:id", c.UpdateRole)
+	roles.Delete("/:id", c.DeleteRole)
+	roles.Put("/:id/permissions", c.SetRolePermissions)
+
+	permissions := admin.Group("/permissions", manage)
+	permissions.Get("/", c.ListPermissions)
+	permissions.Post("/", c.CreatePermission)
+	permissions.Delete("/:id", c.DeletePermission)
+}
+
+// ListRoles handles fetching all roles
+// @Summary List roles
+// @Description Get all roles with their permissions
+// @Tags Roles
+// @Produce json
+// @Success 200 {array} models.Role
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/roles [get]
+func (c *RoleController) ListRoles(ctx *fiber.Ctx) error {
+	roles, err := c.rbacService.ListRoles(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(roles)
+}
+
+// CreateRole handles role creation
+// @Summary Create role
+// @Description Create a new role without permissions
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param role body models.Role true "Role object"
+// @Success 201 {object} models.Role
+// @Failure 400 {array} models.ValidationError
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/roles [post]
+func (c *RoleController) CreateRole(ctx *fiber.Ctx) error {
+	var role models.Role
+	if err := ctx.BodyParser(&role); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate role input
+	if errors := role.Validate(); errors != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
+	}
+
+	if err := c.rbacService.CreateRole(ctx.UserContext(), &role); err != nil {
+		if err == services.ErrRoleExists {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create role",
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(role)
+}
+
+// GetRole handles fetching a single role
+// @Summary Get role by ID
+// @Description Get a role and its permissions
+// @Tags Roles
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} models.Role
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/roles/{id} [get]
+func (c *RoleController) GetRole(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid role id",
+		})
+	}
+
+	role, err := c.rbacService.GetRole(ctx.UserContext(), uint(id))
+	if err != nil {
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "role not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(role)
+}
+
+// UpdateRole handles role updates
+// @Summary Update role
+// @Description Update a role's description. Roles cannot be renamed.
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param role body models.Role true "Role object"
+// @Success 200 {object} models.Role
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/roles/{id} [put]
+func (c *RoleController) UpdateRole(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid role id",
+		})
+	}
+
+	var role models.Role
+	if err := ctx.BodyParser(&role); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	role.ID = uint(id)
+	if err := c.rbacService.UpdateRole(ctx.UserContext(), &role); err != nil {
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "role not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(role)
+}
+
+// DeleteRole handles role deletion
+// @Summary Delete role
+// @Description Delete a custom role. The built-in admin and user roles cannot be deleted.
+// @Tags Roles
+// @Produce json
+// @Param id path int true "Role ID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/roles/{id} [delete]
+func (c *RoleController) DeleteRole(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid role id",
+		})
+	}
+
+	if err := c.rbacService.DeleteRole(ctx.UserContext(), uint(id)); err != nil {
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "role not found",
+			})
+		}
+		if err == services.ErrProtectedRole {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "role deleted successfully",
+	})
+}
+
+// SetRolePermissions handles replacing the permissions of a role
+// @Summary Set role permissions
+// @Description Replace the permissions granted to a role. Only permissions the caller holds can be granted or revoked, and the admin role cannot lose permissions.
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param id path int true "Role ID"
+// @Param permissions body RolePermissionsRequest true "Permission names"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/roles/{id}/permissions [put]
+func (c *RoleController) SetRolePermissions(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid role id",
+		})
+	}
+
+	var req RolePermissionsRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	role, err := c.rbacService.GetRole(ctx.UserContext(), uint(id))
+	if err != nil {
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "role not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+	current := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		current[i] = permission.Name
+	}
+	if err := c.rolePolicy.CanSetPermissions(ctx.UserContext(), currentUser(ctx), current, req.Permissions); err != nil {
+		return policyError(ctx, err)
+	}
+
+	role, err = c.rbacService.SetRolePermissions(ctx.UserContext(), uint(id), req.Permissions)
+	if err != nil {
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "role not found",
+			})
+		}
+		if err == services.ErrAdminPermissions {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrPermissionNotFound {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "unknown permission",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(role)
+}
+
+// ListPermissions handles fetching all permissions
+// @Summary List permissions
+// @Description Get all permissions that can be granted to roles
+// @Tags Roles
+// @Produce json
+// @Success 200 {array} models.Permission
+// @Security BearerAuth
+// @Router /protected/admin/permissions [get]
+func (c *RoleController) ListPermissions(ctx *fiber.Ctx) error {
+	permissions, err := c.rbacService.ListPermissions(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(permissions)
+}
+
+// CreatePermission handles permission creation
+// @Summary Create permission
+// @Description Create a new permission, e.g. reports:export
+// @Tags Roles
+// @Accept json
+// @Produce json
+// @Param permission body models.Permission true "Permission object"
+// @Success 201 {object} models.Permission
+// @Failure 400 {array} models.ValidationError
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/permissions [post]
+func (c *RoleController) CreatePermission(ctx *fiber.Ctx) error {
+	var permission models.Permission
+	if err := ctx.BodyParser(&permission); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate permission input
+	if errors := permission.Validate(); errors != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
+	}
+
+	if err := c.rbacService.CreatePermission(ctx.UserContext(), &permission); err != nil {
+		if err == services.ErrPermissionExists {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "failed to create permission",
+		})
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(permission)
+}
+
+// DeletePermission handles permission deletion
+// @Summary Delete permission
+// @Description Delete a permission and remove it from every role
+// @Tags Roles
+// @Produce json
+// @Param id path int true "Permission ID"
+// @Success 200 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/permissions/{id} [delete]
+func (c *RoleController) DeletePermission(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid permission id",
+		})
+	}
+
+	if err := c.rbacService.DeletePermission(ctx.UserContext(), uint(id)); err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "permission deleted successfully",
+	})
+}
@@ -0,0 +1,267 @@
+package controllers
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/internal/middlewares"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/services"
+)
+
+// SAMLController handles HTTP requests for SAML single sign-on
+type SAMLController struct {
+	samlService services.SAMLService
+	rbacService services.RBACService
+}
+
+// NewSAMLController creates a new SAML controller
+func NewSAMLController(samlService services.SAMLService, rbacService services.RBACService) *SAMLController {
+	return &SAMLController{
+		samlService: samlService,
+		rbacService: rbacService,
+	}
+}
+
+// Register registers the public SAML service provider routes
+func (c *SAMLController) Register(app *fiber.App) {
+	api := app.Group("/api/v1")
+
+	saml := api.Group("/auth/saml/:organization")
+	saml.Get("/metadata", c.Metadata)
+	saml.Get("/login", c.BeginLogin)
+	saml.Post("/acs", c.AssertionConsumerService)
+}
+
+// RegisterAdmin registers SAML connection management routes on the admin group
+func (c *SAMLController) RegisterAdmin(admin fiber.Router) {
+	manage := middlewares.RequirePermission(c.rbacService, models.PermissionSSOManage)
+
+	connections := admin.Group("/saml-connections", manage)
+	connections.Get("/", c.ListConnections)
+	connections.Post("/", c.CreateConnection)
+	connections.Put("/:id", c.UpdateConnection)
+	connections.Delete("/:id", c.DeleteConnection)
+}
+
+// Metadata handles fetching the service provider metadata
+// @Summary Get SAML service provider metadata
+// @Description Get the SAML 2.0 service provider metadata to register at an organization's identity provider. The metadata URL is also the service provider's entity ID.
+// @Tags Authentication
+// @Produce xml
+// @Param organization path string true "Organization identifier"
+// @Success 200 {string} string "SAML metadata"
+// @Failure 404 {object} map[string]string
+// @Router /auth/saml/{organization}/metadata [get]
+func (c *SAMLController) Metadata(ctx *fiber.Ctx) error {
+	metadata, err := c.samlService.Metadata(ctx.UserContext(), ctx.Params("organization"))
+	if err != nil {
+		return c.samlError(ctx, err)
+	}
+
+	ctx.Set(fiber.HeaderContentType, "application/samlmetadata+xml")
+	return ctx.Send(metadata)
+}
+
+// BeginLogin handles starting an SSO login
+// @Summary Start SAML login
+// @Description Redirect to the organization's identity provider with a SAML AuthnRequest
+// @Tags Authentication
+// @Param organization path string true "Organization identifier"
+// @Success 302
+// @Failure 404 {object} map[string]string
+// @Router /auth/saml/{organization}/login [get]
+func (c *SAMLController) BeginLogin(ctx *fiber.Ctx) error {
+	authURL, err := c.samlService.BeginLogin(ctx.UserContext(), ctx.Params("organization"))
+	if err != nil {
+		return c.samlError(ctx, err)
+	}
+
+	return ctx.Redirect(authURL, fiber.StatusFound)
+}
+
+// AssertionConsumerService handles the response posted by the identity provider
+// @Summary Complete SAML login
+// @Description Verify the signed SAML response and return tokens like /login. On first login the user is linked to the account with the same email, which must be in one of the organization's domains, or provisioned when the organization allows it. The role is mapped from the configured attribute.
+// @Tags Authentication
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param organization path string true "Organization identifier"
+// @Param SAMLResponse formData string true "Base64 encoded SAML response"
+// @Param RelayState formData string true "Relay state from the login redirect"
+// @Success 200 {object} models.LoginResponse
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /auth/saml/{organization}/acs [post]
+func (c *SAMLController) AssertionConsumerService(ctx *fiber.Ctx) error {
+	samlResponse, relayState := ctx.FormValue("SAMLResponse"), ctx.FormValue("RelayState")
+	if samlResponse == "" || relayState == "" {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "SAMLResponse and RelayState are required",
+		})
+	}
+
+	result, err := c.samlService.CompleteLogin(ctx.UserContext(), ctx.Params("organization"), samlResponse, relayState, clientInfo(ctx))
+	if err != nil {
+		return c.samlError(ctx, err)
+	}
+
+	return ctx.JSON(result)
+}
+
+// ListConnections handles fetching the SAML connections
+// @Summary List SAML connections
+// @Description Get the SAML single sign-on connections of all organizations
+// @Tags Admin
+// @Produce json
+// @Success 200 {array} models.SAMLConnectionResponse
+// @Failure 403 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/saml-connections [get]
+func (c *SAMLController) ListConnections(ctx *fiber.Ctx) error {
+	connections, err := c.samlService.ListConnections(ctx.UserContext())
+	if err != nil {
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
+	return ctx.JSON(connections)
+}
+
+// CreateConnection handles adding a SAML connection
+// @Summary Create SAML connection
+// @Description Connect an organization's SAML identity provider. The metadata is given inline or fetched from its URL. Register the entity ID and ACS URL from the response at the identity provider.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param connection body models.SAMLConnectionRequest true "Connection settings"
+// @Success 201 {object} models.SAMLConnectionResponse
+// @Failure 400 {array} models.ValidationError
+// @Failure 403 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/saml-connections [post]
+func (c *SAMLController) CreateConnection(ctx *fiber.Ctx) error {
+	var req models.SAMLConnectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate request input
+	if errors := req.Validate(); errors != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
+	}
+
+	connection, err := c.samlService.CreateConnection(ctx.UserContext(), &req)
+	if err != nil {
+		return c.samlError(ctx, err)
+	}
+
+	return ctx.Status(fiber.StatusCreated).JSON(connection)
+}
+
+// UpdateConnection handles changing a SAML connection
+// @Summary Update SAML connection
+// @Description Replace the settings of a SAML connection. Renaming the organization changes its SSO URLs and unlinks the identities linked through it.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param id path int true "Connection ID"
+// @Param connection body models.SAMLConnectionRequest true "Connection settings"
+// @Success 200 {object} models.SAMLConnectionResponse
+// @Failure 400 {array} models.ValidationError
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/saml-connections/{id} [put]
+func (c *SAMLController) UpdateConnection(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid connection id",
+		})
+	}
+
+	var req models.SAMLConnectionRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
+
+	// Validate request input
+	if errors := req.Validate(); errors != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
+	}
+
+	connection, err := c.samlService.UpdateConnection(ctx.UserContext(), uint(id), &req)
+	if err != nil {
+		return c.samlError(ctx, err)
+	}
+
+	return ctx.JSON(connection)
+}
+
+// DeleteConnection handles removing a SAML connection
+// @Summary Delete SAML connection
+// @Description Remove a SAML connection and unlink the identities linked through it. Accounts are kept and can still sign in by other means.
+// @Tags Admin
+// @Produce json
+// @Param id path int true "Connection ID"
+// @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /protected/admin/saml-connections/{id} [delete]
+func (c *SAMLController) DeleteConnection(ctx *fiber.Ctx) error {
+	id, err := strconv.ParseUint(ctx.Params("id"), 10, 32)
+	if err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid connection id",
+		})
+	}
+
+	if err := c.samlService.DeleteConnection(ctx.UserContext(), uint(id)); err != nil {
+		return c.samlError(ctx, err)
+	}
+
+	return ctx.JSON(fiber.Map{
+		"message": "connection deleted successfully",
+	})
+}
+
+// samlError writes the response for errors returned by the SAML service
+func (c *SAMLController) samlError(ctx *fiber.Ctx, err error) error {
+	switch err {
+	case services.ErrSAMLConnectionNotFound:
+		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrInvalidSAMLState, services.ErrInvalidIDPMetadata, services.ErrRoleNotFound:
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrSAMLLoginFailed:
+		return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrSAMLConnectionExists, services.ErrSAMLAccountExists:
+		return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case services.ErrSAMLEmailMissing, services.ErrSAMLDomainNotAllowed, services.ErrSAMLNotProvisioned, services.ErrUserNotFound, services.ErrAccountSuspended, services.ErrPasswordResetRequired, services.ErrEmailNotVerified:
+		return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+		"error": "internal server error",
+	})
+}
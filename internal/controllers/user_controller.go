@@ -1,22 +1,31 @@
 package controllers
 
 import (
+	"errors"
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/middlewares"
 	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/policies"
+	"github.com/yourusername/go-production-level/internal/repository"
 	"github.com/yourusername/go-production-level/internal/services"
 )
 
 // UserController handles HTTP requests for users
 type UserController struct {
 	userService services.UserService
+	userPolicy  *policies.UserPolicy
+	config      *config.Config
 }
 
 // NewUserController creates a new user controller
-func NewUserController(userService services.UserService) *UserController {
+func NewUserController(userService services.UserService, userPolicy *policies.UserPolicy, config *config.Config) *UserController {
 	return &UserController{
 		userService: userService,
+		userPolicy:  userPolicy,
+		config:      config,
 	}
 }
 
@@ -26,32 +35,40 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// UpdateUserRequest carries the current password that confirms a change to
+// the caller's own email or password, next to the fields of the user
+type UpdateUserRequest struct {
+	CurrentPassword string `json:"current_password"`
+}
+
 // Register registers all user routes
-func (c *UserController) Register(app *fiber.App) {
+func (c *UserController) Register(app *fiber.App, auth fiber.Handler) {
 	api := app.Group("/api/v1")
-	
+
 	// Public routes
 	api.Post("/login", c.Login)
 	api.Post("/users", c.CreateUser)
 
 	// Protected routes
 	users := api.Group("/users")
-	users.Get("/", c.ListUsers)
-	users.Get("/:id", c.GetUser)
-	users.Put("/:id", c.UpdateUser)
-	users.Delete("/:id", c.DeleteUser)
+	users.Get("/", auth, c.ListUsers)
+	users.Get("/:id", auth, c.GetUser)
+	users.Put("/:id", auth, middlewares.RequireSession(), middlewares.DenyImpersonation(), c.UpdateUser)
+	users.Delete("/:id", auth, middlewares.RequireSession(), middlewares.DenyImpersonation(), c.DeleteUser)
 }
 
 // Login handles user authentication
 // @Summary User login
-// @Description Authenticate user and return JWT token
+// @Description Authenticate user and return a short-lived JWT access token and a refresh token. Users with two-factor authentication get an MFA token to exchange at /mfa/verify instead. Repeated failures temporarily lock the account (code account_locked) or the client IP (code too_many_attempts).
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param login body LoginRequest true "Login credentials"
-// @Success 200 {object} map[string]string
+// @Success 200 {object} models.LoginResponse
 // @Failure 400 {object} map[string]string
 // @Failure 401 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Failure 429 {object} map[string]interface{}
 // @Router /login [post]
 func (c *UserController) Login(ctx *fiber.Ctx) error {
 	var req LoginRequest
@@ -61,32 +78,44 @@ func (c *UserController) Login(ctx *fiber.Ctx) error {
 		})
 	}
 
-	token, err := c.userService.Login(ctx.Context(), req.Email, req.Password)
+	result, err := c.userService.Login(ctx.UserContext(), req.Email, req.Password, clientInfo(ctx))
 	if err != nil {
+		var lockout *services.LockoutError
+		if errors.As(err, &lockout) {
+			code := "account_locked"
+			if lockout.Err == services.ErrTooManyLoginAttempts {
+				code = "too_many_attempts"
+			}
+			return retryLater(ctx, lockout, code, lockout.RetryAfter)
+		}
 		if err == services.ErrInvalidCredentials {
 			return ctx.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "invalid credentials",
 			})
 		}
+		if err == services.ErrAccountSuspended || err == services.ErrPasswordResetRequired || err == services.ErrEmailNotVerified {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "internal server error",
 		})
 	}
 
-	return ctx.JSON(fiber.Map{
-		"token": token,
-	})
+	return ctx.JSON(result)
 }
 
 // CreateUser handles user creation
 // @Summary Create new user
-// @Description Create a new user account
+// @Description Create a new user account. Sign-ups get the default user role and a verification email.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param user body models.User true "User object"
 // @Success 201 {object} map[string]string
 // @Failure 400 {array} models.ValidationError
+// @Failure 401 {object} map[string]string
 // @Router /users [post]
 func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
 	var user models.User
@@ -96,17 +125,32 @@ func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
 		})
 	}
 
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	if err := c.userPolicy.CanAssignRole(ctx.UserContext(), currentUser(ctx), user.Role); err != nil {
+		return policyError(ctx, err)
+	}
+
 	// Validate user input
 	if errors := user.Validate(); errors != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
 	}
 
-	if err := c.userService.Create(ctx.Context(), &user); err != nil {
+	if err := c.userService.Create(ctx.UserContext(), &user); err != nil {
 		if err == services.ErrEmailExists {
 			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
 				"error": err.Error(),
 			})
 		}
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if policyErr, ok := err.(*services.PasswordPolicyError); ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(policyErr.Violations)
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "failed to create user",
 		})
@@ -125,6 +169,7 @@ func (c *UserController) CreateUser(ctx *fiber.Ctx) error {
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 200 {object} models.UserResponse
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Security BearerAuth
 // @Router /users/{id} [get]
@@ -136,7 +181,11 @@ func (c *UserController) GetUser(ctx *fiber.Ctx) error {
 		})
 	}
 
-	user, err := c.userService.GetByID(ctx.Context(), uint(id))
+	if err := c.userPolicy.CanView(ctx.UserContext(), currentUser(ctx), uint(id)); err != nil {
+		return policyError(ctx, err)
+	}
+
+	user, err := c.userService.GetByID(ctx.UserContext(), uint(id))
 	if err != nil {
 		if err == services.ErrUserNotFound {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
@@ -153,7 +202,7 @@ func (c *UserController) GetUser(ctx *fiber.Ctx) error {
 
 // UpdateUser handles user updates
 // @Summary Update user
-// @Description Update user details
+// @Description Update user details. Users changing their own email or password confirm it with current_password. A new email takes effect once confirmed through the link sent to it; the current address is notified. Not available to API keys and OAuth2 clients.
 // @Tags Users
 // @Accept json
 // @Produce json
@@ -161,7 +210,9 @@ func (c *UserController) GetUser(ctx *fiber.Ctx) error {
 // @Param user body models.User true "User object"
 // @Success 200 {object} map[string]string
 // @Failure 400 {array} models.ValidationError
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
 // @Security BearerAuth
 // @Router /users/{id} [put]
 func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
@@ -172,25 +223,82 @@ func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
 		})
 	}
 
+	actor := currentUser(ctx)
+	if err := c.userPolicy.CanUpdate(ctx.UserContext(), actor, uint(id)); err != nil {
+		return policyError(ctx, err)
+	}
+
+	existing, err := c.userService.GetByID(ctx.UserContext(), uint(id))
+	if err != nil {
+		if err == services.ErrUserNotFound {
+			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "user not found",
+			})
+		}
+		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "internal server error",
+		})
+	}
+
 	var user models.User
+	var req UpdateUserRequest
 	if err := ctx.BodyParser(&user); err != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "invalid request body",
 		})
 	}
+	if err := ctx.BodyParser(&req); err != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid request body",
+		})
+	}
 
 	// Validate user input
 	if errors := user.Validate(); errors != nil {
 		return ctx.Status(fiber.StatusBadRequest).JSON(errors)
 	}
 
+	if actor.UserID != uint(id) {
+		if err := c.userPolicy.CanManage(ctx.UserContext(), actor, existing.Role); err != nil {
+			return policyError(ctx, err)
+		}
+	}
+	if user.Role != existing.Role {
+		if err := c.userPolicy.CanAssignRole(ctx.UserContext(), actor, user.Role); err != nil {
+			return policyError(ctx, err)
+		}
+	}
+
 	user.ID = uint(id)
-	if err := c.userService.Update(ctx.Context(), &user); err != nil {
+	if actor.UserID == user.ID {
+		err = c.userService.UpdateOwn(ctx.UserContext(), &user, req.CurrentPassword)
+	} else {
+		err = c.userService.Update(ctx.UserContext(), &user)
+	}
+	if err != nil {
+		if err == services.ErrCurrentPassword {
+			return ctx.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == services.ErrEmailExists {
+			return ctx.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
 		if err == services.ErrUserNotFound {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "user not found",
 			})
 		}
+		if err == services.ErrRoleNotFound {
+			return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if policyErr, ok := err.(*services.PasswordPolicyError); ok {
+			return ctx.Status(fiber.StatusBadRequest).JSON(policyErr.Violations)
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "internal server error",
 		})
@@ -203,12 +311,13 @@ func (c *UserController) UpdateUser(ctx *fiber.Ctx) error {
 
 // DeleteUser handles user deletion
 // @Summary Delete user
-// @Description Delete user by ID
+// @Description Delete user by ID. Not available to API keys and OAuth2 clients.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param id path int true "User ID"
 // @Success 200 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Security BearerAuth
 // @Router /users/{id} [delete]
@@ -220,7 +329,28 @@ func (c *UserController) DeleteUser(ctx *fiber.Ctx) error {
 		})
 	}
 
-	if err := c.userService.Delete(ctx.Context(), uint(id)); err != nil {
+	actor := currentUser(ctx)
+	if err := c.userPolicy.CanDelete(ctx.UserContext(), actor, uint(id)); err != nil {
+		return policyError(ctx, err)
+	}
+	if actor.UserID != uint(id) {
+		target, err := c.userService.GetByID(ctx.UserContext(), uint(id))
+		if err != nil {
+			if err == services.ErrUserNotFound {
+				return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
+					"error": "user not found",
+				})
+			}
+			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "internal server error",
+			})
+		}
+		if err := c.userPolicy.CanManage(ctx.UserContext(), actor, target.Role); err != nil {
+			return policyError(ctx, err)
+		}
+	}
+
+	if err := c.userService.Delete(ctx.UserContext(), uint(id)); err != nil {
 		if err == services.ErrUserNotFound {
 			return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
 				"error": "user not found",
@@ -238,38 +368,57 @@ func (c *UserController) DeleteUser(ctx *fiber.Ctx) error {
 
 // ListUsers handles fetching a list of users
 // @Summary List users
-// @Description Get paginated list of users
+// @Description Get paginated list of users in order of creation. Passing the cursor parameter, empty for the first page, switches from page numbers to cursor pagination, which stays consistent while users are added; follow next_cursor and prev_cursor to move through the list. The limit is capped at the configured maximum.
+// @Description Filter with field=value or field[operator]=value on id (eq, ne, gt, gte, lt, lte, in), email (eq, ne, like, in), name (eq, ne, like), role (eq, ne, in) and created_at (gt, gte, lt, lte). in takes a comma separated list and like matches substrings ignoring case. Sort with a comma separated list of id, email, name, role and created_at, each prefixed with - for descending order; sort is not available with cursor pagination. q searches email and name.
 // @Tags Users
 // @Accept json
 // @Produce json
 // @Param page query int false "Page number"
 // @Param limit query int false "Items per page"
-// @Success 200 {object} map[string]interface{}
+// @Param cursor query string false "Cursor from a previous page"
+// @Param total query bool false "Include the total number of matching users"
+// @Param sort query string false "Sort fields, for example -created_at,name"
+// @Param q query string false "Search text"
+// @Param role query string false "Filter by role"
+// @Param created_at[gte] query string false "Created at or after this date or RFC 3339 time"
+// @Param email[like] query string false "Email contains this text"
+// @Success 200 {object} models.UserPage
+// @Failure 400 {array} models.ValidationError
+// @Failure 403 {object} map[string]string
 // @Security BearerAuth
 // @Router /users [get]
 func (c *UserController) ListUsers(ctx *fiber.Ctx) error {
-	page, _ := strconv.Atoi(ctx.Query("page", "1"))
-	limit, _ := strconv.Atoi(ctx.Query("limit", "10"))
-
-	if page < 1 {
-		page = 1
-	}
-	if limit < 1 {
-		limit = 10
+	if err := c.userPolicy.CanList(ctx.UserContext(), currentUser(ctx)); err != nil {
+		return policyError(ctx, err)
 	}
 
-	offset := (page - 1) * limit
+	page, limit, _ := parsePagination(ctx, c.config.PaginationMaxLimit)
+	withTotal := ctx.QueryBool("total")
+
+	// Validate filters, sort and search
+	q, violations := repository.UserQuerySchema.Parse(ctx.Queries())
+	if violations != nil {
+		return ctx.Status(fiber.StatusBadRequest).JSON(violations)
+	}
 
-	users, err := c.userService.List(ctx.Context(), offset, limit)
+	var users *models.UserPage
+	var err error
+	if ctx.Context().QueryArgs().Has("cursor") {
+		users, err = c.userService.ListByCursor(ctx.UserContext(), q, ctx.Query("cursor"), limit, withTotal)
+	} else {
+		users, err = c.userService.List(ctx.UserContext(), q, page, limit, withTotal)
+	}
 	if err != nil {
+		if err == services.ErrInvalidCursor || err == services.ErrCursorSort {
+			return ctx.Status(fiber.StatusBadRequest).JSON([]models.ValidationError{{
+				Field: "cursor",
+				Error: err.Error(),
+			}})
+		}
 		return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "internal server error",
 		})
 	}
 
-	return ctx.JSON(fiber.Map{
-		"users": users,
-		"page":  page,
-		"limit": limit,
-	})
+	return ctx.JSON(users)
 }
@@ -0,0 +1,57 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// LogMailer writes emails to the application log. Intended for local development.
+type LogMailer struct {
+	from string
+}
+
+// NewLogMailer creates a mailer that logs every message
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	log.Printf("Email from %s to %s\nSubject: %s\n\n%s", m.from, msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileMailer writes each email as an .eml file. Intended for local development and tests.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// NewFileMailer creates a mailer that writes messages to dir
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	name := fmt.Sprintf("%d-%s.eml", time.Now().UnixNano(), sanitizeFileName(msg.To))
+	if err := os.WriteFile(filepath.Join(m.dir, name), buildMessage(m.from, msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+func sanitizeFileName(name string) string {
+	out := []rune(name)
+	for i, r := range out {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '@' || r == '-') {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-production-level/config"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends emails
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the mailer selected by MAIL_DRIVER: smtp, file or log
+func New(cfg *config.Config) (Mailer, error) {
+	switch cfg.MailDriver {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		return NewFileMailer(cfg.MailFileDir, cfg.MailFrom)
+	case "log", "":
+		return NewLogMailer(cfg.MailFrom), nil
+	}
+	return nil, fmt.Errorf("unknown mail driver: %s", cfg.MailDriver)
+}
+
+// buildMessage renders msg as an RFC 5322 message
+func buildMessage(from string, msg Message) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + sanitizeHeader(from) + "\r\n")
+	b.WriteString("To: " + sanitizeHeader(msg.To) + "\r\n")
+	b.WriteString("Subject: " + sanitizeHeader(msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// sanitizeHeader prevents header injection through user supplied values
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(value)
+}
@@ -0,0 +1,42 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+
+	"github.com/yourusername/go-production-level/config"
+)
+
+// SMTPMailer sends emails through an SMTP server, using STARTTLS when the server offers it
+type SMTPMailer struct {
+	addr     string
+	host     string
+	username string
+	password string
+	from     string
+}
+
+// NewSMTPMailer creates a mailer for the SMTP server in cfg
+func NewSMTPMailer(cfg *config.Config) *SMTPMailer {
+	return &SMTPMailer{
+		addr:     net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		host:     cfg.SMTPHost,
+		username: cfg.SMTPUsername,
+		password: cfg.SMTPPassword,
+		from:     cfg.MailFrom,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if m.username != "" {
+		auth = smtp.PlainAuth("", m.username, m.password, m.host)
+	}
+
+	if err := smtp.SendMail(m.addr, auth, m.from, []string{msg.To}, buildMessage(m.from, msg)); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
@@ -4,13 +4,33 @@ import (
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/services"
 	"github.com/yourusername/go-production-level/internal/utils"
 )
 
-func AuthMiddleware(cfg *config.Config) fiber.Handler {
+// AuthMiddleware authenticates requests with a Bearer access token or, when no
+// Authorization header is sent, an API key in the X-API-Key header
+func AuthMiddleware(tokenService services.TokenService, apiKeyService services.APIKeyService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		authHeader := c.Get("Authorization")
+		if apiKey := c.Get(APIKeyHeader); authHeader == "" && apiKey != "" {
+			claims, err := apiKeyService.Authenticate(c.UserContext(), apiKey)
+			if err != nil {
+				if err == services.ErrInvalidAPIKey {
+					return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+						"error": err.Error(),
+					})
+				}
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "internal server error",
+				})
+			}
+
+			c.Locals("user", claims)
+			return c.Next()
+		}
+
 		if authHeader == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "missing authorization header",
@@ -24,20 +44,36 @@ func AuthMiddleware(cfg *config.Config) fiber.Handler {
 			})
 		}
 
-		claims, err := utils.ValidateToken(tokenParts[1], cfg)
+		claims, err := tokenService.ValidateAccessToken(c.UserContext(), tokenParts[1])
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "invalid token",
+			if err == services.ErrTokenRevoked {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "token has been revoked",
+				})
+			}
+			if err == services.ErrInvalidToken {
+				return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+					"error": "invalid token",
+				})
+			}
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "internal server error",
 			})
 		}
 
-		// Add claims to context for use in handlers
+		// Add claims to context for use in handlers. When an admin is acting as
+		// the user, "user" is the impersonated user and "actor" the admin.
 		c.Locals("user", claims)
+		if claims.Act != nil {
+			c.Locals("actor", claims.Act)
+		}
 		return c.Next()
 	}
 }
 
-func AdminMiddleware() fiber.Handler {
+// RequireSession rejects requests authenticated with an API key or an OAuth2
+// client token. It guards account security operations that need an interactive login.
+func RequireSession() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		claims, ok := c.Locals("user").(*utils.JWTClaims)
 		if !ok {
@@ -45,10 +81,67 @@ func AdminMiddleware() fiber.Handler {
 				"error": "missing user claims",
 			})
 		}
+		if claims.APIKeyID != 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "not available when authenticated with an API key",
+			})
+		}
+		if claims.ClientID != "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "not available to OAuth2 clients",
+			})
+		}
 
-		if claims.Role != "admin" {
+		return c.Next()
+	}
+}
+
+// DenyImpersonation rejects requests made with an impersonation token. It guards
+// operations that only the account owner may perform, such as changing the
+// password or deleting the account.
+func DenyImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*utils.JWTClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing user claims",
+			})
+		}
+		if claims.Impersonated() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "not available while impersonating",
+			})
+		}
+
+		return c.Next()
+	}
+}
+
+// AdminMiddleware allows access to roles granted the admin:access permission
+func AdminMiddleware(rbacService services.RBACService) fiber.Handler {
+	return RequirePermission(rbacService, models.PermissionAdminAccess)
+}
+
+// RequirePermission allows access only when the role in the JWT claims grants
+// the permission and, for scoped API keys, the key has the permission as a scope
+func RequirePermission(rbacService services.RBACService, permission string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		claims, ok := c.Locals("user").(*utils.JWTClaims)
+		if !ok {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing user claims",
+			})
+		}
+
+		allowed, err := rbacService.HasPermission(c.UserContext(), claims.Role, permission)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "internal server error",
+			})
+		}
+		if !allowed || !claims.AllowsScope(permission) {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "admin access required",
+				"error": "missing permission: " + permission,
 			})
 		}
 
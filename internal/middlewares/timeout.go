@@ -0,0 +1,34 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequestTimeout gives every request a context with a deadline, which handlers
+// pass on as ctx.UserContext(). Database queries and Redis commands still
+// running when it passes are cancelled, and a failed request is answered with
+// 504 Gateway Timeout. fasthttp does not report client disconnects, so a
+// request whose client has gone away runs until the deadline.
+func RequestTimeout(timeout time.Duration) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if timeout <= 0 {
+			return c.Next()
+		}
+
+		ctx, cancel := context.WithTimeout(c.UserContext(), timeout)
+		defer cancel()
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && (err != nil || c.Response().StatusCode() >= fiber.StatusInternalServerError) {
+			return c.Status(fiber.StatusGatewayTimeout).JSON(fiber.Map{
+				"error": "request timed out",
+			})
+		}
+		return err
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// APIKey represents a personal API key. Only the SHA-256 hash of the key is
+// stored; the prefix is kept in plain text to find the key and to tell keys apart.
+type APIKey struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	Name       string     `gorm:"not null" json:"name"`
+	Prefix     string     `gorm:"uniqueIndex;not null" json:"prefix"`
+	KeyHash    string     `gorm:"not null" json:"-"`
+	Scopes     string     `json:"scopes"` // space separated permission names, empty for all of the owner's permissions
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// ScopeList returns the scopes as a slice, nil when the key is not restricted
+func (k *APIKey) ScopeList() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// Active reports whether the key can be used at the given time
+func (k *APIKey) Active(at time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || at.Before(*k.ExpiresAt))
+}
+
+// APIKeyResponse represents an API key without its secret
+// @Description API key information
+type APIKeyResponse struct {
+	ID         uint       `json:"id" example:"1"`
+	CreatedAt  time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	Name       string     `json:"name" example:"CI deploy"`
+	Prefix     string     `json:"prefix" example:"gpl_3f9a1c0b7d2e"`
+	Scopes     []string   `json:"scopes,omitempty" example:"users:read"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty" example:"2025-01-01T00:00:00Z"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" example:"2024-01-01T00:00:00Z"`
+}
+
+// APIKeyCreated is returned once when a key is created. The key cannot be retrieved again.
+// @Description Newly created API key including the secret
+type APIKeyCreated struct {
+	APIKeyResponse
+	Key string `json:"key" example:"gpl_3f9a1c0b7d2e_q2uR8p0c2xJ0m7a9..."`
+}
+
+// CreateAPIKeyRequest represents the API key creation request body
+// @Description API key creation request
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100" example:"CI deploy"`
+	Scopes    []string   `json:"scopes" example:"users:read"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2025-01-01T00:00:00Z"`
+}
+
+// Validate validates the request and returns an array of validation errors
+func (r *CreateAPIKeyRequest) Validate() []ValidationError {
+	return validateStruct(r)
+}
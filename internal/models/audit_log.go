@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Audit log actions
+const (
+	AuditLoginLockout    = "login.lockout"
+	AuditLoginIPLockout  = "login.ip_lockout"
+	AuditMFALockout      = "login.mfa_lockout"
+	AuditUserUnlock      = "user.unlock"
+	AuditUserImpersonate = "user.impersonate"
+)
+
+// AuditLog records a security relevant event
+// @Description Audit log entry
+type AuditLog struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `gorm:"index" json:"created_at" example:"2024-01-01T00:00:00Z"`
+	Action    string    `gorm:"index;not null" json:"action" example:"login.lockout"`
+	ActorID   *uint     `gorm:"index" json:"actor_id,omitempty" example:"1"`
+	UserID    *uint     `gorm:"index" json:"user_id,omitempty" example:"2"`
+	IP        string    `json:"ip,omitempty" example:"203.0.113.7"`
+	Details   string    `json:"details,omitempty" example:"locked for 2m0s after 5 failed attempts"`
+}
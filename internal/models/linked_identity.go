@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// LinkedIdentity links a user to an account at an external OpenID Connect
+// provider or, with a provider of saml:<organization>, a SAML identity provider
+// @Description External identity linked to a user
+type LinkedIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	UserID    uint      `gorm:"index;not null" json:"user_id" example:"1"`
+	Provider  string    `gorm:"uniqueIndex:idx_linked_identities_provider_subject;not null" json:"provider" example:"google"`
+	Subject   string    `gorm:"uniqueIndex:idx_linked_identities_provider_subject;not null" json:"subject" example:"110169484474386276334"`
+	Email     string    `json:"email" example:"user@example.com"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// LoginEvent records a login attempt for an existing account
+// @Description Login attempt
+type LoginEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt time.Time `gorm:"index" json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UserID    uint      `gorm:"index;not null" json:"user_id" example:"1"`
+	IP        string    `json:"ip" example:"203.0.113.7"`
+	UserAgent string    `json:"user_agent" example:"Mozilla/5.0"`
+	Success   bool      `gorm:"not null" json:"success" example:"true"`
+	Reason    string    `json:"reason,omitempty" example:"invalid_password"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RecoveryCode is a one-time code that can replace a TOTP code.
+// Only the SHA-256 hash of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// TOTPEnrollment represents a pending TOTP enrollment
+// @Description TOTP secret and the otpauth:// URI to render as a QR code
+type TOTPEnrollment struct {
+	Secret          string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	ProvisioningURI string `json:"provisioning_uri" example:"otpauth://totp/Go%20Production%20Level:user@example.com?secret=JBSWY3DPEHPK3PXP"`
+}
+
+// LoginResponse represents the result of a password login. When two-factor
+// authentication is enabled it carries an MFA token instead of the tokens.
+// @Description Tokens, or an MFA challenge to complete with /mfa/verify
+type LoginResponse struct {
+	*TokenPair
+	MFARequired bool   `json:"mfa_required" example:"false"`
+	MFAToken    string `json:"mfa_token,omitempty" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
@@ -0,0 +1,200 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// OAuth2 grant types supported by the authorization server
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeClientCredentials = "client_credentials"
+	GrantTypeRefreshToken      = "refresh_token"
+)
+
+// OAuthClient represents an application registered with the authorization server.
+// Confidential clients authenticate with a secret, of which only the SHA-256 hash
+// is stored. Public clients have no secret and must use PKCE.
+type OAuthClient struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+	ClientID     string    `gorm:"uniqueIndex;not null" json:"client_id"`
+	SecretHash   string    `json:"-"`
+	Name         string    `gorm:"not null" json:"name"`
+	RedirectURIs string    `json:"redirect_uris"` // space separated
+	GrantTypes   string    `json:"grant_types"`   // space separated
+	Scopes       string    `json:"scopes"`        // space separated permission names the client may request
+}
+
+// TableName overrides the GORM default, which would be o_auth_clients
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// Confidential reports whether the client authenticates with a secret
+func (c *OAuthClient) Confidential() bool {
+	return c.SecretHash != ""
+}
+
+// ScopeList returns the scopes the client may request
+func (c *OAuthClient) ScopeList() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// AllowsGrant reports whether the client may use a grant type
+func (c *OAuthClient) AllowsGrant(grantType string) bool {
+	return containsField(c.GrantTypes, grantType)
+}
+
+// AllowsRedirectURI reports whether uri exactly matches a registered redirect URI
+func (c *OAuthClient) AllowsRedirectURI(uri string) bool {
+	return containsField(c.RedirectURIs, uri)
+}
+
+// OAuthClientResponse represents a client without its secret
+// @Description OAuth2 client information
+type OAuthClientResponse struct {
+	ID           uint      `json:"id" example:"1"`
+	CreatedAt    time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	ClientID     string    `json:"client_id" example:"Q0wWbd1N2mXk5y1jVtZ8vA"`
+	Name         string    `json:"name" example:"Billing dashboard"`
+	Confidential bool      `json:"confidential" example:"true"`
+	RedirectURIs []string  `json:"redirect_uris" example:"https://billing.example.com/callback"`
+	GrantTypes   []string  `json:"grant_types" example:"authorization_code"`
+	Scopes       []string  `json:"scopes" example:"users:read"`
+}
+
+// OAuthClientCreated is returned once when a client is registered. The secret cannot be retrieved again.
+// @Description Newly registered OAuth2 client including the secret
+type OAuthClientCreated struct {
+	OAuthClientResponse
+	ClientSecret string `json:"client_secret,omitempty" example:"q2uR8p0c2xJ0m7a9..."`
+}
+
+// CreateOAuthClientRequest represents the client registration request body
+// @Description OAuth2 client registration request
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name" validate:"required,max=100" example:"Billing dashboard"`
+	Confidential bool     `json:"confidential" example:"true"`
+	RedirectURIs []string `json:"redirect_uris" validate:"dive,url" example:"https://billing.example.com/callback"`
+	GrantTypes   []string `json:"grant_types" validate:"required,min=1,dive,oneof=authorization_code client_credentials refresh_token" example:"authorization_code"`
+	Scopes       []string `json:"scopes" validate:"required,min=1" example:"users:read"`
+}
+
+// Validate validates the request and returns an array of validation errors
+func (r *CreateOAuthClientRequest) Validate() []ValidationError {
+	return validateStruct(r)
+}
+
+// OAuthConsent records the scopes a user has granted to a client
+type OAuthConsent struct {
+	ID            uint      `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	UserID        uint      `gorm:"uniqueIndex:idx_oauth_consents_user_client;not null" json:"user_id"`
+	OAuthClientID uint      `gorm:"column:oauth_client_id;uniqueIndex:idx_oauth_consents_user_client;index;not null" json:"oauth_client_id"`
+	Scopes        string    `json:"scopes"` // space separated
+}
+
+// TableName overrides the GORM default, which would be o_auth_consents
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// Covers reports whether every scope has been granted
+func (c *OAuthConsent) Covers(scopes []string) bool {
+	for _, scope := range scopes {
+		if !containsField(c.Scopes, scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// OAuthConsentResponse represents a consent with the client it was granted to
+// @Description Scopes granted to an OAuth2 client
+type OAuthConsentResponse struct {
+	ID         uint      `json:"id" example:"1"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt  time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	ClientID   string    `json:"client_id" example:"Q0wWbd1N2mXk5y1jVtZ8vA"`
+	ClientName string    `json:"client_name" example:"Billing dashboard"`
+	Scopes     []string  `json:"scopes" example:"users:read"`
+}
+
+// OAuthAuthorizeRequest holds the parameters of an authorization request (RFC 6749 section 4.1.1, RFC 7636)
+// @Description OAuth2 authorization request
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" query:"response_type" example:"code"`
+	ClientID            string `json:"client_id" query:"client_id" example:"Q0wWbd1N2mXk5y1jVtZ8vA"`
+	RedirectURI         string `json:"redirect_uri" query:"redirect_uri" example:"https://billing.example.com/callback"`
+	Scope               string `json:"scope" query:"scope" example:"users:read"`
+	State               string `json:"state" query:"state" example:"af0ifjsldkj"`
+	CodeChallenge       string `json:"code_challenge" query:"code_challenge" example:"E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"`
+	CodeChallengeMethod string `json:"code_challenge_method" query:"code_challenge_method" example:"S256"`
+}
+
+// OAuthConsentDecision is the user's answer to an authorization request
+// @Description Approval or denial of an OAuth2 authorization request
+type OAuthConsentDecision struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve" example:"true"`
+}
+
+// OAuthAuthorizePrompt describes an authorization request to show on the consent screen
+// @Description OAuth2 authorization request awaiting the user's consent
+type OAuthAuthorizePrompt struct {
+	ClientID        string   `json:"client_id" example:"Q0wWbd1N2mXk5y1jVtZ8vA"`
+	ClientName      string   `json:"client_name" example:"Billing dashboard"`
+	RedirectURI     string   `json:"redirect_uri" example:"https://billing.example.com/callback"`
+	Scopes          []string `json:"scopes" example:"users:read"`
+	ConsentRequired bool     `json:"consent_required" example:"true"`
+}
+
+// OAuthTokenRequest holds the form parameters of a token request (RFC 6749 section 4)
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenResponse is the successful response of the token endpoint (RFC 6749 section 5.1)
+// @Description OAuth2 access token response
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJSUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+	RefreshToken string `json:"refresh_token,omitempty" example:"q2uR8p0c2xJ0m7a9..."`
+	Scope        string `json:"scope" example:"users:read"`
+}
+
+// OAuthIntrospection is the response of the introspection endpoint (RFC 7662 section 2.2)
+// @Description OAuth2 token introspection response
+type OAuthIntrospection struct {
+	Active    bool   `json:"active" example:"true"`
+	Scope     string `json:"scope,omitempty" example:"users:read"`
+	ClientID  string `json:"client_id,omitempty" example:"Q0wWbd1N2mXk5y1jVtZ8vA"`
+	Username  string `json:"username,omitempty" example:"user@example.com"`
+	TokenType string `json:"token_type,omitempty" example:"Bearer"`
+	Exp       int64  `json:"exp,omitempty" example:"1704067200"`
+	Iat       int64  `json:"iat,omitempty" example:"1704066300"`
+	Sub       string `json:"sub,omitempty" example:"1"`
+	Iss       string `json:"iss,omitempty" example:"go-production-level"`
+	Jti       string `json:"jti,omitempty" example:"m7a9q2uR8p0c2xJ0"`
+}
+
+// containsField reports whether the space separated list contains value
+func containsField(list, value string) bool {
+	for _, field := range strings.Fields(list) {
+		if field == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// One-time token purposes
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposeEmailChange       = "email_change"
+	TokenPurposeMagicLink         = "magic_link"
+)
+
+// OneTimeToken represents a single-use token sent to a user out of band, e.g. by email.
+// Only the SHA-256 hash of the token is stored.
+type OneTimeToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	Purpose   string     `gorm:"not null" json:"purpose"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
+// Expired reports whether the token can no longer be used
+func (t *OneTimeToken) Expired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
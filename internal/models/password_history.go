@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// PasswordHistory stores the hash of a password a user has set, so that
+// recently used passwords can be refused when the password changes
+type PasswordHistory struct {
+	ID           uint      `gorm:"primarykey" json:"id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UserID       uint      `gorm:"index;not null" json:"user_id"`
+	PasswordHash string    `gorm:"not null" json:"-"`
+}
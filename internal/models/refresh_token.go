@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// RefreshToken represents an issued refresh token in the database.
+// Only the SHA-256 hash of the opaque token is stored; tokens rotated from
+// the same login share a FamilyID so the whole chain can be revoked at once.
+// Tokens issued to an OAuth2 client carry the client and the granted scopes.
+type RefreshToken struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UserID    uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID  string     `gorm:"index;not null" json:"family_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	OAuthClientID *uint  `gorm:"column:oauth_client_id;index" json:"oauth_client_id,omitempty"`
+	Scopes        string `json:"scopes,omitempty"` // space separated
+}
+
+// TokenPair represents the tokens returned after a successful authentication
+// @Description Access and refresh tokens
+type TokenPair struct {
+	AccessToken  string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"q2uR8p0c2xJ0m7a9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int64  `json:"expires_in" example:"900"`
+}
+
+// ImpersonationToken is an access token that lets an admin act as another user.
+// It cannot be refreshed.
+// @Description Short-lived access token for acting as a user
+type ImpersonationToken struct {
+	AccessToken string `json:"token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType   string `json:"token_type" example:"Bearer"`
+	ExpiresIn   int64  `json:"expires_in" example:"900"`
+	UserID      uint   `json:"user_id" example:"2"`
+	ActorID     uint   `json:"actor_id" example:"1"`
+}
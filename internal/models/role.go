@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Built-in roles created on startup
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// Built-in permissions created on startup
+const (
+	PermissionAdminAccess   = "admin:access"
+	PermissionRolesManage   = "roles:manage"
+	PermissionUsersRead     = "users:read"
+	PermissionUsersWrite    = "users:write"
+	PermissionUsersDelete   = "users:delete"
+	PermissionAuditRead     = "audit:read"
+	PermissionClientsManage = "clients:manage"
+	PermissionImpersonate   = "users:impersonate"
+	PermissionSSOManage     = "sso:manage"
+)
+
+// Role represents a named set of permissions
+// @Description Role with its granted permissions
+type Role struct {
+	ID          uint         `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt   time.Time    `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time    `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Name        string       `gorm:"uniqueIndex;not null" json:"name" validate:"required,max=50" example:"support"`
+	Description string       `json:"description" validate:"max=255" example:"Customer support staff"`
+	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions"`
+}
+
+// Permission represents a single action that can be granted to roles
+// @Description Permission in resource:action form
+type Permission struct {
+	ID          uint      `gorm:"primarykey" json:"id" example:"1"`
+	CreatedAt   time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt   time.Time `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Name        string    `gorm:"uniqueIndex;not null" json:"name" validate:"required,max=100" example:"users:delete"`
+	Description string    `json:"description" validate:"max=255" example:"Delete user accounts"`
+}
+
+// Validate validates the role model and returns an array of validation errors
+func (r *Role) Validate() []ValidationError {
+	return validateStruct(r)
+}
+
+// Validate validates the permission model and returns an array of validation errors
+func (p *Permission) Validate() []ValidationError {
+	return validateStruct(p)
+}
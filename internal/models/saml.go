@@ -0,0 +1,85 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// organizationPattern matches organization identifiers, which are used in URLs
+var organizationPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// SAMLConnection configures single sign-on with the SAML 2.0 identity provider
+// of one organization. The organization identifier appears in the SSO URLs.
+type SAMLConnection struct {
+	ID              uint              `gorm:"primarykey" json:"id"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	Organization    string            `gorm:"uniqueIndex;not null" json:"organization"`
+	Name            string            `gorm:"not null" json:"name"`
+	IDPEntityID     string            `gorm:"column:idp_entity_id;not null" json:"idp_entity_id"`
+	IDPMetadata     string            `gorm:"column:idp_metadata;type:text;not null" json:"-"`
+	Domains         string            `gorm:"not null" json:"domains"` // space separated email domains the identity provider may assert
+	EmailAttribute  string            `json:"email_attribute"`
+	NameAttribute   string            `json:"name_attribute"`
+	RoleAttribute   string            `json:"role_attribute"`
+	RoleMapping     map[string]string `gorm:"serializer:json;type:text" json:"role_mapping"` // attribute value to role name
+	DefaultRole     string            `gorm:"not null" json:"default_role"`
+	JITProvisioning bool              `gorm:"column:jit_provisioning" json:"jit_provisioning"`
+}
+
+// AllowsEmail reports whether the email address is in one of the connection's domains
+func (c *SAMLConnection) AllowsEmail(email string) bool {
+	_, domain, ok := strings.Cut(email, "@")
+	return ok && containsField(strings.ToLower(c.Domains), strings.ToLower(domain))
+}
+
+// SAMLConnectionResponse represents a SAML connection with the URLs to register at the identity provider
+// @Description SAML single sign-on connection
+type SAMLConnectionResponse struct {
+	ID              uint              `json:"id" example:"1"`
+	CreatedAt       time.Time         `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt       time.Time         `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	Organization    string            `json:"organization" example:"acme"`
+	Name            string            `json:"name" example:"Acme Corp"`
+	IDPEntityID     string            `json:"idp_entity_id" example:"https://idp.acme.com/saml"`
+	Domains         []string          `json:"domains" example:"acme.com"`
+	EmailAttribute  string            `json:"email_attribute" example:"email"`
+	NameAttribute   string            `json:"name_attribute" example:"displayName"`
+	RoleAttribute   string            `json:"role_attribute" example:"groups"`
+	RoleMapping     map[string]string `json:"role_mapping"`
+	DefaultRole     string            `json:"default_role" example:"user"`
+	JITProvisioning bool              `json:"jit_provisioning" example:"true"`
+	EntityID        string            `json:"entity_id" example:"https://api.example.com/api/v1/auth/saml/acme/metadata"`
+	ACSURL          string            `json:"acs_url" example:"https://api.example.com/api/v1/auth/saml/acme/acs"`
+	LoginURL        string            `json:"login_url" example:"https://api.example.com/api/v1/auth/saml/acme/login"`
+}
+
+// SAMLConnectionRequest represents the SAML connection request body. The
+// identity provider metadata is given either inline or as a URL to fetch it from.
+// @Description SAML single sign-on connection settings
+type SAMLConnectionRequest struct {
+	Organization    string            `json:"organization" validate:"required,max=50" example:"acme"`
+	Name            string            `json:"name" validate:"required,max=100" example:"Acme Corp"`
+	IDPMetadata     string            `json:"idp_metadata" validate:"required_without=IDPMetadataURL" example:"<EntityDescriptor ...>"`
+	IDPMetadataURL  string            `json:"idp_metadata_url" validate:"omitempty,url" example:"https://idp.acme.com/saml/metadata"`
+	Domains         []string          `json:"domains" validate:"required,min=1,dive,fqdn" example:"acme.com"`
+	EmailAttribute  string            `json:"email_attribute" example:"email"`
+	NameAttribute   string            `json:"name_attribute" example:"displayName"`
+	RoleAttribute   string            `json:"role_attribute" example:"groups"`
+	RoleMapping     map[string]string `json:"role_mapping"`
+	DefaultRole     string            `json:"default_role" example:"user"`
+	JITProvisioning bool              `json:"jit_provisioning" example:"true"`
+}
+
+// Validate validates the request and returns an array of validation errors
+func (r *SAMLConnectionRequest) Validate() []ValidationError {
+	errors := validateStruct(r)
+	if r.Organization != "" && !organizationPattern.MatchString(r.Organization) {
+		errors = append(errors, ValidationError{
+			Field: "Organization",
+			Error: "Should contain only lowercase letters, digits and hyphens",
+		})
+	}
+	return errors
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// Session represents a login on one device. It lives as long as the refresh
+// token family started by the login, and the family ID is carried in the sid
+// claim of every access token issued for the session.
+type Session struct {
+	ID         uint       `gorm:"primarykey" json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UserID     uint       `gorm:"index;not null" json:"user_id"`
+	FamilyID   string     `gorm:"uniqueIndex;not null" json:"-"`
+	IP         string     `json:"ip"`
+	UserAgent  string     `json:"user_agent"`
+	Location   string     `json:"location"` // reserved for a geo-IP lookup, not filled in yet
+	LastSeenAt time.Time  `gorm:"not null" json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// SessionResponse represents a session of the current user
+// @Description Signed-in device
+type SessionResponse struct {
+	ID         uint      `json:"id" example:"1"`
+	CreatedAt  time.Time `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	LastSeenAt time.Time `json:"last_seen_at" example:"2024-01-01T00:00:00Z"`
+	ExpiresAt  time.Time `json:"expires_at" example:"2024-01-08T00:00:00Z"`
+	IP         string    `json:"ip" example:"203.0.113.7"`
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0"`
+	Location   string    `json:"location,omitempty" example:""`
+	Current    bool      `json:"current" example:"true"`
+}
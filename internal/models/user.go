@@ -3,21 +3,44 @@ package models
 import (
 	"time"
 
-	"github.com/go-playground/validator/v10"
 	"gorm.io/gorm"
 )
 
 // User represents the user model in the database
 // @Description User account information
 type User struct {
-	ID        uint           `gorm:"primarykey" json:"id" example:"1"`
-	CreatedAt time.Time      `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	ID        uint           `gorm:"primarykey;index:idx_users_created_at_id,priority:2" json:"id" example:"1"`
+	CreatedAt time.Time      `gorm:"index:idx_users_created_at_id,priority:1" json:"created_at" example:"2024-01-01T00:00:00Z"`
 	UpdatedAt time.Time      `json:"updated_at" example:"2024-01-01T00:00:00Z"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 	Email     string         `gorm:"uniqueIndex;not null" json:"email" validate:"required,email" example:"user@example.com"`
-	Password  string         `json:"password,omitempty" validate:"required,min=6" example:"password123"`
+	Password  string         `json:"password,omitempty" validate:"required" example:"password123"`
 	Name      string         `json:"name" validate:"required" example:"John Doe"`
-	Role      string         `json:"role" validate:"required,oneof=admin user" example:"user"`
+	Role      string         `json:"role" validate:"required" example:"user"`
+
+	// Set once the user follows the link in the verification email
+	EmailVerifiedAt *time.Time `json:"-"`
+	// New address waiting to be confirmed. Email stays in use until then.
+	PendingEmail string `json:"-"`
+
+	// Account state managed through the admin API, never bound from request bodies
+	SuspendedAt           *time.Time `json:"-"`
+	SuspensionReason      string     `json:"-"`
+	PasswordResetRequired bool       `gorm:"not null;default:false" json:"-"`
+
+	// TOTP secret, encrypted at rest. It is only active once TOTPEnabledAt is set.
+	TOTPSecret    string     `json:"-"`
+	TOTPEnabledAt *time.Time `json:"-"`
+}
+
+// EmailVerified reports whether the user has confirmed their email address
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// MFAEnabled reports whether the user has confirmed TOTP enrollment
+func (u *User) MFAEnabled() bool {
+	return u.TOTPEnabledAt != nil
 }
 
 // UserResponse represents the user response without sensitive information
@@ -30,6 +53,34 @@ type UserResponse struct {
 	Role      string    `json:"role" example:"user"`
 }
 
+// UserPage represents a page of users. Page is set for offset pagination, the
+// cursors for cursor pagination, where they are empty at either end of the list.
+// @Description Page of users
+type UserPage struct {
+	Users      []UserResponse `json:"users"`
+	Page       int            `json:"page,omitempty" example:"1"`
+	Limit      int            `json:"limit" example:"10"`
+	NextCursor string         `json:"next_cursor,omitempty"`
+	PrevCursor string         `json:"prev_cursor,omitempty"`
+	Total      *int64         `json:"total,omitempty" example:"42"`
+}
+
+// AdminUserResponse represents a user with account state for the admin API
+// @Description User information including account state
+type AdminUserResponse struct {
+	ID                    uint       `json:"id" example:"1"`
+	CreatedAt             time.Time  `json:"created_at" example:"2024-01-01T00:00:00Z"`
+	UpdatedAt             time.Time  `json:"updated_at" example:"2024-01-01T00:00:00Z"`
+	DeletedAt             *time.Time `json:"deleted_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	Email                 string     `json:"email" example:"user@example.com"`
+	Name                  string     `json:"name" example:"John Doe"`
+	Role                  string     `json:"role" example:"user"`
+	EmailVerifiedAt       *time.Time `json:"email_verified_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	SuspendedAt           *time.Time `json:"suspended_at,omitempty" example:"2024-01-01T00:00:00Z"`
+	SuspensionReason      string     `json:"suspension_reason,omitempty" example:"Chargeback fraud"`
+	PasswordResetRequired bool       `json:"password_reset_required" example:"false"`
+}
+
 // ValidationError represents a validation error
 type ValidationError struct {
 	Field string `json:"field"`
@@ -38,33 +89,5 @@ type ValidationError struct {
 
 // Validate validates the user model and returns an array of validation errors
 func (u *User) Validate() []ValidationError {
-	validate := validator.New()
-	err := validate.Struct(u)
-	if err == nil {
-		return nil
-	}
-
-	var errors []ValidationError
-	for _, err := range err.(validator.ValidationErrors) {
-		var element ValidationError
-		element.Field = err.Field()
-		element.Error = getErrorMsg(err)
-		errors = append(errors, element)
-	}
-	return errors
-}
-
-// getErrorMsg returns a human-readable error message for validation errors
-func getErrorMsg(err validator.FieldError) string {
-	switch err.Tag() {
-	case "required":
-		return "This field is required"
-	case "email":
-		return "Invalid email format"
-	case "min":
-		return "Should be at least " + err.Param() + " characters long"
-	case "oneof":
-		return "Should be one of: " + err.Param()
-	}
-	return "Unknown validation error"
+	return validateStruct(u)
 }
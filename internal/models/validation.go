@@ -0,0 +1,44 @@
+package models
+
+import "github.com/go-playground/validator/v10"
+
+// validateStruct validates a model using its validate tags and returns an array of validation errors
+func validateStruct(s interface{}) []ValidationError {
+	validate := validator.New()
+	err := validate.Struct(s)
+	if err == nil {
+		return nil
+	}
+
+	var errors []ValidationError
+	for _, err := range err.(validator.ValidationErrors) {
+		var element ValidationError
+		element.Field = err.Field()
+		element.Error = getErrorMsg(err)
+		errors = append(errors, element)
+	}
+	return errors
+}
+
+// getErrorMsg returns a human-readable error message for validation errors
+func getErrorMsg(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "This field is required"
+	case "email":
+		return "Invalid email format"
+	case "min":
+		return "Should be at least " + err.Param() + " characters long"
+	case "max":
+		return "Should be at most " + err.Param() + " characters long"
+	case "oneof":
+		return "Should be one of: " + err.Param()
+	case "url":
+		return "Invalid URL"
+	case "fqdn":
+		return "Invalid domain name"
+	case "required_without":
+		return "This field is required when " + err.Param() + " is not set"
+	}
+	return "Unknown validation error"
+}
@@ -0,0 +1,301 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var ErrInvalidIDToken = errors.New("invalid ID token")
+
+const (
+	// discoveryTTL is how long provider metadata and keys are cached
+	discoveryTTL = time.Hour
+	// keyRefreshInterval limits refetching the JWKS when a token names an unknown key
+	keyRefreshInterval = time.Minute
+	// maxResponseSize bounds responses read from the provider
+	maxResponseSize = 1 << 20
+)
+
+// Discovery is the part of the OpenID Provider metadata used by the login flow
+type Discovery struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	IDTokenSigningAlgs    []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// IDToken holds the claims of a verified ID token
+type IDToken struct {
+	Email           string  `json:"email"`
+	EmailVerified   boolish `json:"email_verified"`
+	Name            string  `json:"name"`
+	Nonce           string  `json:"nonce"`
+	AuthorizedParty string  `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// boolish accepts both true and "true"; some providers send email_verified as a string
+type boolish bool
+
+func (b *boolish) UnmarshalJSON(data []byte) error {
+	switch strings.Trim(string(data), `"`) {
+	case "true":
+		*b = true
+	default:
+		*b = false
+	}
+	return nil
+}
+
+// Provider runs the authorization code flow against one OpenID Connect provider
+type Provider struct {
+	config config.OIDCProvider
+	client *http.Client
+
+	mu            sync.Mutex
+	discovery     *Discovery
+	discoveredAt  time.Time
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewProvider creates a provider. Metadata is discovered on first use.
+func NewProvider(cfg config.OIDCProvider, client *http.Client) *Provider {
+	return &Provider{
+		config: cfg,
+		client: client,
+	}
+}
+
+// Name returns the configured provider name
+func (p *Provider) Name() string {
+	return p.config.Name
+}
+
+// AuthCodeURL returns the URL to send the user to. The code challenge is the
+// S256 PKCE challenge of the verifier passed to Exchange later.
+func (p *Provider) AuthCodeURL(ctx context.Context, redirectURI, state, nonce, codeChallenge string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.config.ClientID},
+		"redirect_uri":          {redirectURI},
+		"scope":                 {strings.Join(p.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {codeChallenge},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + params.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the raw ID token
+func (p *Provider) Exchange(ctx context.Context, code, codeVerifier, redirectURI string) (string, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURI},
+		"code_verifier": {codeVerifier},
+		"client_id":     {p.config.ClientID},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := p.doJSON(req, &body)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("token exchange with %s failed: %s %s", p.config.Name, body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", fmt.Errorf("token response from %s has no id_token", p.config.Name)
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string) (*IDToken, error) {
+	discovery, err := p.Discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	algs := discovery.IDTokenSigningAlgs
+	if len(algs) == 0 {
+		algs = []string{"RS256"}
+	}
+
+	claims := &IDToken{}
+	_, err = jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, kid)
+	},
+		jwt.WithValidMethods(algs),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.config.ClientID {
+		return nil, fmt.Errorf("%w: unexpected authorized party", ErrInvalidIDToken)
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	return claims, nil
+}
+
+// Discover returns the provider metadata, fetching it when the cache is stale
+func (p *Provider) Discover(ctx context.Context) (*Discovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil && time.Since(p.discoveredAt) < discoveryTTL {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery Discovery
+	status, err := p.doJSON(req, &discovery)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery for %s failed with status %d", p.config.Name, status)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.config.Issuer {
+		return nil, fmt.Errorf("discovery for %s returned issuer %q", p.config.Name, discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("discovery for %s is missing endpoints", p.config.Name)
+	}
+
+	p.discovery = &discovery
+	p.discoveredAt = time.Now()
+	return p.discovery, nil
+}
+
+// publicKey returns the provider key with the given kid, refetching the JWKS
+// when the key is unknown, e.g. after the provider rotated its keys
+func (p *Provider) publicKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stale := time.Since(p.keysFetchedAt) > discoveryTTL
+	if key, ok := p.lookupKey(kid); ok && !stale {
+		return key, nil
+	}
+	if !stale && time.Since(p.keysFetchedAt) < keyRefreshInterval {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.discovery.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set utils.JWKSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("fetching keys for %s failed with status %d", p.config.Name, status)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookupKey finds a key by kid. Tokens without a kid are accepted only when the provider has a single key.
+func (p *Provider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+func (p *Provider) doJSON(req *http.Request, v interface{}) (int, error) {
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("request to %s failed: %w", p.config.Name, err)
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return resp.StatusCode, fmt.Errorf("invalid response from %s: %w", p.config.Name, err)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,48 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/yourusername/go-production-level/config"
+)
+
+// httpTimeout bounds every request to an identity provider
+const httpTimeout = 10 * time.Second
+
+// Registry holds the configured providers by name
+type Registry struct {
+	providers map[string]*Provider
+	names     []string
+}
+
+// NewRegistry creates a provider for every provider in the config
+func NewRegistry(cfg *config.Config) *Registry {
+	client := &http.Client{Timeout: httpTimeout}
+	registry := &Registry{providers: make(map[string]*Provider)}
+
+	for _, provider := range cfg.OIDCProviders {
+		registry.providers[provider.Name] = NewProvider(provider, client)
+		registry.names = append(registry.names, provider.Name)
+	}
+	return registry
+}
+
+// Get returns the provider with the given name
+func (r *Registry) Get(name string) (*Provider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Names returns the names of all configured providers
+func (r *Registry) Names() []string {
+	return r.names
+}
+
+// CodeChallenge returns the S256 PKCE challenge for a code verifier
+func CodeChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
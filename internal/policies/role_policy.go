@@ -0,0 +1,52 @@
+package policies
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// RolePolicy decides who may change what roles grant. Managing roles needs
+// roles:manage, which the routes check; on top of that an actor may only grant
+// or revoke permissions they hold, so editing a role never escalates their own.
+type RolePolicy struct {
+	permissions PermissionChecker
+}
+
+// NewRolePolicy creates a new role policy
+func NewRolePolicy(permissions PermissionChecker) *RolePolicy {
+	return &RolePolicy{
+		permissions: permissions,
+	}
+}
+
+// CanSetPermissions allows replacing the permissions of a role holding current
+// with requested. The actor must hold every permission that is added or removed.
+func (p *RolePolicy) CanSetPermissions(ctx context.Context, actor *utils.JWTClaims, current, requested []string) error {
+	return requireAll(ctx, p.permissions, actor, changedPermissions(current, requested))
+}
+
+// changedPermissions lists the permissions in only one of a and b
+func changedPermissions(a, b []string) []string {
+	inA := make(map[string]bool, len(a))
+	for _, permission := range a {
+		inA[permission] = true
+	}
+	inB := make(map[string]bool, len(b))
+	for _, permission := range b {
+		inB[permission] = true
+	}
+
+	var changed []string
+	for _, permission := range a {
+		if !inB[permission] {
+			changed = append(changed, permission)
+		}
+	}
+	for permission := range inB {
+		if !inA[permission] {
+			changed = append(changed, permission)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,44 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+func TestRolePolicyCanSetPermissions(t *testing.T) {
+	admin := &utils.JWTClaims{UserID: 2, Role: models.RoleAdmin}
+	auditor := &utils.JWTClaims{UserID: 4, Role: "auditor"}
+	scopedAdmin := &utils.JWTClaims{UserID: 2, Role: models.RoleAdmin, APIKeyID: 8, Scopes: []string{models.PermissionRolesManage}}
+
+	policy := NewRolePolicy(testRoles)
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		actor     *utils.JWTClaims
+		current   []string
+		requested []string
+		want      error
+	}{
+		{"anonymous cannot change roles", nil, nil, []string{models.PermissionUsersRead}, ErrUnauthenticated},
+		{"admin grants permissions it holds", admin, nil, []string{models.PermissionUsersRead, models.PermissionUsersWrite}, nil},
+		{"admin revokes permissions it holds", admin, []string{models.PermissionUsersRead}, nil, nil},
+		{"admin cannot grant permissions it lacks", admin, nil, []string{models.PermissionAuditRead}, ErrForbidden},
+		{"admin cannot revoke permissions it lacks", admin, []string{models.PermissionAuditRead}, nil, ErrForbidden},
+		{"unchanged permissions are not checked", admin, []string{models.PermissionAuditRead}, []string{models.PermissionAuditRead, models.PermissionUsersRead}, nil},
+		{"auditor cannot grant itself admin access", auditor, []string{models.PermissionAdminAccess, models.PermissionAuditRead}, []string{models.PermissionAdminAccess, models.PermissionAuditRead, models.PermissionUsersDelete}, ErrForbidden},
+		{"scopes limit what a key grants", scopedAdmin, nil, []string{models.PermissionUsersRead}, ErrForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := policy.CanSetPermissions(ctx, tt.actor, tt.current, tt.requested); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
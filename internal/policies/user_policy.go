@@ -0,0 +1,140 @@
+package policies
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrUnauthenticated = errors.New("authentication required")
+	ErrForbidden       = errors.New("not allowed to perform this action")
+)
+
+// PermissionChecker reports which permissions a role grants.
+// services.RBACService satisfies it.
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+}
+
+// UserPolicy decides who may act on user accounts. Users may always read and
+// modify their own record; acting on other accounts needs an elevated permission.
+// The actor is nil for anonymous requests.
+type UserPolicy struct {
+	permissions PermissionChecker
+}
+
+// NewUserPolicy creates a new user policy
+func NewUserPolicy(permissions PermissionChecker) *UserPolicy {
+	return &UserPolicy{
+		permissions: permissions,
+	}
+}
+
+// CanList allows listing all users
+func (p *UserPolicy) CanList(ctx context.Context, actor *utils.JWTClaims) error {
+	return p.require(ctx, actor, models.PermissionUsersRead)
+}
+
+// CanView allows reading a single user
+func (p *UserPolicy) CanView(ctx context.Context, actor *utils.JWTClaims, userID uint) error {
+	return p.ownerOr(ctx, actor, userID, models.PermissionUsersRead)
+}
+
+// CanUpdate allows modifying a single user
+func (p *UserPolicy) CanUpdate(ctx context.Context, actor *utils.JWTClaims, userID uint) error {
+	return p.ownerOr(ctx, actor, userID, models.PermissionUsersWrite)
+}
+
+// CanDelete allows deleting a single user
+func (p *UserPolicy) CanDelete(ctx context.Context, actor *utils.JWTClaims, userID uint) error {
+	return p.ownerOr(ctx, actor, userID, models.PermissionUsersDelete)
+}
+
+// CanAssignRole allows giving a user the role. Anyone, including anonymous
+// sign-ups, may get the default user role. Other roles need users:write and
+// admin:access, and the actor must hold every permission of the role, so
+// assigning a role never grants more than the actor has.
+func (p *UserPolicy) CanAssignRole(ctx context.Context, actor *utils.JWTClaims, role string) error {
+	if role == models.RoleUser {
+		return nil
+	}
+	if err := p.require(ctx, actor, models.PermissionUsersWrite); err != nil {
+		return err
+	}
+	if err := p.require(ctx, actor, models.PermissionAdminAccess); err != nil {
+		return err
+	}
+
+	// Unknown roles are rejected by the user service
+	return p.holdsRole(ctx, actor, role)
+}
+
+// CanManage allows administering another account with the role, for example
+// suspending, demoting or purging it. The actor must hold every permission of
+// the account's role, so accounts with more privileges are out of reach.
+func (p *UserPolicy) CanManage(ctx context.Context, actor *utils.JWTClaims, role string) error {
+	if actor == nil {
+		return ErrUnauthenticated
+	}
+	return p.holdsRole(ctx, actor, role)
+}
+
+// holdsRole allows actors holding every permission of the role. Roles that
+// no longer exist grant nothing.
+func (p *UserPolicy) holdsRole(ctx context.Context, actor *utils.JWTClaims, role string) error {
+	exists, err := p.permissions.RoleExists(ctx, role)
+	if err != nil || !exists {
+		return err
+	}
+	permissions, err := p.permissions.GetRolePermissions(ctx, role)
+	if err != nil {
+		return err
+	}
+	return requireAll(ctx, p.permissions, actor, permissions)
+}
+
+func (p *UserPolicy) ownerOr(ctx context.Context, actor *utils.JWTClaims, userID uint, permission string) error {
+	if actor == nil {
+		return ErrUnauthenticated
+	}
+	// Scoped API keys act on their owner only within their scopes
+	if actor.UserID == userID && actor.AllowsScope(permission) {
+		return nil
+	}
+	return p.require(ctx, actor, permission)
+}
+
+func (p *UserPolicy) require(ctx context.Context, actor *utils.JWTClaims, permission string) error {
+	return require(ctx, p.permissions, actor, permission)
+}
+
+// require allows actors whose role and scopes grant the permission
+func require(ctx context.Context, checker PermissionChecker, actor *utils.JWTClaims, permission string) error {
+	if actor == nil {
+		return ErrUnauthenticated
+	}
+
+	allowed, err := checker.HasPermission(ctx, actor.Role, permission)
+	if err != nil {
+		return err
+	}
+	if !allowed || !actor.AllowsScope(permission) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// requireAll allows actors holding every one of the permissions
+func requireAll(ctx context.Context, checker PermissionChecker, actor *utils.JWTClaims, permissions []string) error {
+	for _, permission := range permissions {
+		if err := require(ctx, checker, actor, permission); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+package policies
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// rolePermissions is a PermissionChecker backed by a fixed role table
+type rolePermissions map[string][]string
+
+func (r rolePermissions) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	for _, p := range r[role] {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r rolePermissions) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	return r[role], nil
+}
+
+func (r rolePermissions) RoleExists(ctx context.Context, role string) (bool, error) {
+	_, ok := r[role]
+	return ok, nil
+}
+
+var testRoles = rolePermissions{
+	models.RoleUser: {},
+	models.RoleAdmin: {
+		models.PermissionAdminAccess,
+		models.PermissionUsersRead,
+		models.PermissionUsersWrite,
+		models.PermissionUsersDelete,
+		models.PermissionRolesManage,
+	},
+	"support": {
+		models.PermissionUsersRead,
+		models.PermissionUsersWrite,
+	},
+	"auditor": {
+		models.PermissionAdminAccess,
+		models.PermissionAuditRead,
+	},
+}
+
+func TestUserPolicy(t *testing.T) {
+	owner := &utils.JWTClaims{UserID: 1, Role: models.RoleUser}
+	admin := &utils.JWTClaims{UserID: 2, Role: models.RoleAdmin}
+	support := &utils.JWTClaims{UserID: 3, Role: "support"}
+	readOnlyKey := &utils.JWTClaims{UserID: 1, Role: models.RoleUser, APIKeyID: 7, Scopes: []string{models.PermissionUsersRead}}
+	adminReadKey := &utils.JWTClaims{UserID: 2, Role: models.RoleAdmin, APIKeyID: 8, Scopes: []string{models.PermissionUsersRead}}
+	impersonated := &utils.JWTClaims{UserID: 1, Role: models.RoleUser, Act: &utils.ActorClaims{UserID: 2}}
+
+	policy := NewUserPolicy(testRoles)
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		check func() error
+		want  error
+	}{
+		{"anonymous cannot list", func() error { return policy.CanList(ctx, nil) }, ErrUnauthenticated},
+		{"anonymous cannot view", func() error { return policy.CanView(ctx, nil, 1) }, ErrUnauthenticated},
+		{"user cannot list", func() error { return policy.CanList(ctx, owner) }, ErrForbidden},
+
+		{"owner views self", func() error { return policy.CanView(ctx, owner, 1) }, nil},
+		{"owner updates self", func() error { return policy.CanUpdate(ctx, owner, 1) }, nil},
+		{"owner deletes self", func() error { return policy.CanDelete(ctx, owner, 1) }, nil},
+		{"owner cannot view others", func() error { return policy.CanView(ctx, owner, 2) }, ErrForbidden},
+		{"owner cannot update others", func() error { return policy.CanUpdate(ctx, owner, 2) }, ErrForbidden},
+		{"owner cannot delete others", func() error { return policy.CanDelete(ctx, owner, 2) }, ErrForbidden},
+
+		{"admin lists", func() error { return policy.CanList(ctx, admin) }, nil},
+		{"admin views others", func() error { return policy.CanView(ctx, admin, 1) }, nil},
+		{"admin updates others", func() error { return policy.CanUpdate(ctx, admin, 1) }, nil},
+		{"admin deletes others", func() error { return policy.CanDelete(ctx, admin, 1) }, nil},
+		{"support cannot delete others", func() error { return policy.CanDelete(ctx, support, 1) }, ErrForbidden},
+
+		{"scoped key views owner", func() error { return policy.CanView(ctx, readOnlyKey, 1) }, nil},
+		{"scoped key cannot update owner", func() error { return policy.CanUpdate(ctx, readOnlyKey, 1) }, ErrForbidden},
+		{"scoped key cannot delete owner", func() error { return policy.CanDelete(ctx, readOnlyKey, 1) }, ErrForbidden},
+		{"scoped admin key lists", func() error { return policy.CanList(ctx, adminReadKey) }, nil},
+		{"scoped admin key cannot update others", func() error { return policy.CanUpdate(ctx, adminReadKey, 1) }, ErrForbidden},
+		{"scoped admin key cannot assign roles", func() error { return policy.CanAssignRole(ctx, adminReadKey, "support") }, ErrForbidden},
+
+		{"impersonation views user", func() error { return policy.CanView(ctx, impersonated, 1) }, nil},
+		{"impersonation cannot view others", func() error { return policy.CanView(ctx, impersonated, 2) }, ErrForbidden},
+		{"impersonation cannot assign roles", func() error { return policy.CanAssignRole(ctx, impersonated, models.RoleAdmin) }, ErrForbidden},
+
+		{"anyone gets the user role", func() error { return policy.CanAssignRole(ctx, nil, models.RoleUser) }, nil},
+		{"anonymous cannot assign roles", func() error { return policy.CanAssignRole(ctx, nil, "support") }, ErrUnauthenticated},
+		{"user cannot assign roles", func() error { return policy.CanAssignRole(ctx, owner, "support") }, ErrForbidden},
+		{"support cannot assign admin", func() error { return policy.CanAssignRole(ctx, support, models.RoleAdmin) }, ErrForbidden},
+		{"support cannot assign its own role", func() error { return policy.CanAssignRole(ctx, support, "support") }, ErrForbidden},
+		{"admin assigns support", func() error { return policy.CanAssignRole(ctx, admin, "support") }, nil},
+		{"admin assigns admin", func() error { return policy.CanAssignRole(ctx, admin, models.RoleAdmin) }, nil},
+		{"admin cannot assign permissions it lacks", func() error { return policy.CanAssignRole(ctx, admin, "auditor") }, ErrForbidden},
+		{"unknown roles are left to the service", func() error { return policy.CanAssignRole(ctx, admin, "missing") }, nil},
+
+		{"anonymous cannot manage accounts", func() error { return policy.CanManage(ctx, nil, models.RoleUser) }, ErrUnauthenticated},
+		{"support manages users", func() error { return policy.CanManage(ctx, support, models.RoleUser) }, nil},
+		{"support manages its own role", func() error { return policy.CanManage(ctx, support, "support") }, nil},
+		{"support cannot manage admins", func() error { return policy.CanManage(ctx, support, models.RoleAdmin) }, ErrForbidden},
+		{"admin manages support", func() error { return policy.CanManage(ctx, admin, "support") }, nil},
+		{"admin cannot manage roles with permissions it lacks", func() error { return policy.CanManage(ctx, admin, "auditor") }, ErrForbidden},
+		{"scoped admin key cannot manage admins", func() error { return policy.CanManage(ctx, adminReadKey, models.RoleAdmin) }, ErrForbidden},
+		{"deleted roles grant nothing", func() error { return policy.CanManage(ctx, support, "missing") }, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUserPolicyCheckerError(t *testing.T) {
+	failure := errors.New("redis unavailable")
+	policy := NewUserPolicy(failingChecker{failure})
+
+	err := policy.CanList(context.Background(), &utils.JWTClaims{UserID: 2, Role: models.RoleAdmin})
+	if !errors.Is(err, failure) {
+		t.Errorf("got %v, want %v", err, failure)
+	}
+}
+
+type failingChecker struct {
+	err error
+}
+
+func (f failingChecker) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	return false, f.err
+}
+
+func (f failingChecker) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	return nil, f.err
+}
+
+func (f failingChecker) RoleExists(ctx context.Context, role string) (bool, error) {
+	return false, f.err
+}
@@ -0,0 +1,265 @@
+// Package query parses the filter, sort and search parameters of list
+// endpoints against a whitelist of the fields of a resource.
+//
+// Filters are given as field=value or field[operator]=value, for example
+// role=admin or created_at[gte]=2024-01-01. The in operator takes a comma
+// separated list. sort takes a comma separated list of fields, each prefixed
+// with - to sort descending. q searches the resource's text fields.
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+// Operator compares a field with a filter value
+type Operator string
+
+const (
+	Eq   Operator = "eq"
+	Ne   Operator = "ne"
+	Gt   Operator = "gt"
+	Gte  Operator = "gte"
+	Lt   Operator = "lt"
+	Lte  Operator = "lte"
+	Like Operator = "like" // case-insensitive substring match
+	In   Operator = "in"
+)
+
+// Type is the type filter values of a field are parsed as
+type Type int
+
+const (
+	String Type = iota
+	Int
+	Time
+	Bool
+)
+
+const (
+	maxInValues     = 50
+	maxSearchLength = 100
+)
+
+// Messages reported for filter values that cannot be parsed
+const (
+	msgValueRequired = "A value is required"
+	msgTooManyValues = "Should list at most %d values"
+	msgNotInteger    = "Should be an integer"
+	msgNotTime       = "Should be a date or an RFC 3339 time"
+	msgNotBool       = "Should be true or false"
+)
+
+// Field describes a field clients may filter or sort by. A field without
+// operators cannot be filtered.
+type Field struct {
+	Column    string
+	Type      Type
+	Operators []Operator
+	Sortable  bool
+}
+
+// Schema whitelists the fields of a resource
+type Schema struct {
+	Fields map[string]Field
+	// SearchColumns are matched by the q parameter. Without them q is rejected.
+	SearchColumns []string
+}
+
+// Filter is a condition on a column. Value holds a slice for In.
+type Filter struct {
+	Column   string
+	Operator Operator
+	Value    interface{}
+}
+
+// Sort orders by a column
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Query is a parsed query. Its columns come from the schema, never from the request.
+type Query struct {
+	Filters       []Filter
+	Sorts         []Sort
+	Search        string
+	SearchColumns []string
+}
+
+// reserved are the parameters that are not filters
+var reserved = map[string]bool{
+	"page":   true,
+	"limit":  true,
+	"cursor": true,
+	"total":  true,
+	"sort":   true,
+	"q":      true,
+}
+
+var filterKeyPattern = regexp.MustCompile(`^([a-z_]+)(?:\[([a-z]+)\])?$`)
+
+// Parse reads a query from the request parameters. Parameters other than
+// filters, sort and q are left to the caller, except that unknown filters are
+// reported like invalid ones.
+func (s *Schema) Parse(params map[string]string) (*Query, []models.ValidationError) {
+	q := &Query{}
+	var violations []models.ValidationError
+	invalid := func(field, format string, args ...interface{}) {
+		violations = append(violations, models.ValidationError{
+			Field: field,
+			Error: fmt.Sprintf(format, args...),
+		})
+	}
+
+	// Sorted so that filters and errors come out in a stable order
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if !reserved[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		match := filterKeyPattern.FindStringSubmatch(key)
+		if match == nil {
+			invalid(key, "Unknown filter")
+			continue
+		}
+		field, ok := s.Fields[match[1]]
+		if !ok || len(field.Operators) == 0 {
+			invalid(key, "Unknown filter")
+			continue
+		}
+		op := Eq
+		if match[2] != "" {
+			op = Operator(match[2])
+		}
+		if !field.allows(op) {
+			invalid(key, "Should use one of the operators: %s", field.operatorList())
+			continue
+		}
+
+		value, problem := field.parseValue(op, params[key])
+		if problem != "" {
+			invalid(key, "%s", problem)
+			continue
+		}
+		q.Filters = append(q.Filters, Filter{Column: field.Column, Operator: op, Value: value})
+	}
+
+	if value := params["sort"]; value != "" {
+		seen := make(map[string]bool)
+		for _, name := range strings.Split(value, ",") {
+			desc := strings.HasPrefix(name, "-")
+			name = strings.TrimPrefix(name, "-")
+			field, ok := s.Fields[name]
+			if !ok || !field.Sortable {
+				invalid("sort", "Cannot sort by %q", name)
+				continue
+			}
+			if seen[name] {
+				invalid("sort", "Sorts by %q more than once", name)
+				continue
+			}
+			seen[name] = true
+			q.Sorts = append(q.Sorts, Sort{Column: field.Column, Desc: desc})
+		}
+	}
+
+	if search := strings.TrimSpace(params["q"]); search != "" {
+		switch {
+		case len(s.SearchColumns) == 0:
+			invalid("q", "Search is not supported")
+		case len(search) > maxSearchLength:
+			invalid("q", "Should be at most %d characters long", maxSearchLength)
+		default:
+			q.Search = search
+			q.SearchColumns = s.SearchColumns
+		}
+	}
+
+	if violations != nil {
+		return nil, violations
+	}
+	return q, nil
+}
+
+func (f Field) allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Field) operatorList() string {
+	names := make([]string, len(f.Operators))
+	for i, op := range f.Operators {
+		names[i] = string(op)
+	}
+	return strings.Join(names, ", ")
+}
+
+// parseValue converts a filter value to the field's type. It returns the
+// message to report instead when the value is invalid.
+func (f Field) parseValue(op Operator, raw string) (interface{}, string) {
+	if raw == "" {
+		return nil, msgValueRequired
+	}
+	if op == Like {
+		// Matched as text whatever the column's type
+		return raw, ""
+	}
+	if op != In {
+		return f.parseScalar(raw)
+	}
+
+	parts := strings.Split(raw, ",")
+	if len(parts) > maxInValues {
+		return nil, fmt.Sprintf(msgTooManyValues, maxInValues)
+	}
+	values := make([]interface{}, len(parts))
+	for i, part := range parts {
+		value, problem := f.parseScalar(part)
+		if problem != "" {
+			return nil, problem
+		}
+		values[i] = value
+	}
+	return values, ""
+}
+
+func (f Field) parseScalar(raw string) (interface{}, string) {
+	switch f.Type {
+	case Int:
+		value, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return nil, msgNotInteger
+		}
+		return value, ""
+	case Time:
+		if value, err := time.Parse(time.RFC3339, raw); err == nil {
+			return value, ""
+		}
+		if value, err := time.Parse(time.DateOnly, raw); err == nil {
+			return value, ""
+		}
+		return nil, msgNotTime
+	case Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, msgNotBool
+		}
+		return value, ""
+	}
+	return raw, ""
+}
@@ -0,0 +1,166 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+var testSchema = &Schema{
+	Fields: map[string]Field{
+		"id":         {Column: "id", Type: Int, Operators: []Operator{Eq, Ne, Gt, Gte, Lt, Lte, In}, Sortable: true},
+		"email":      {Column: "email", Type: String, Operators: []Operator{Eq, Like, In}, Sortable: true},
+		"active":     {Column: "is_active", Type: Bool, Operators: []Operator{Eq}},
+		"created_at": {Column: "created_at", Type: Time, Operators: []Operator{Gte, Lt}, Sortable: true},
+		"password":   {Column: "password"},
+	},
+	SearchColumns: []string{"email"},
+}
+
+func TestParseFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   []Filter
+	}{
+		{"bare field compares for equality", map[string]string{"email": "a@example.com"}, []Filter{{"email", Eq, "a@example.com"}}},
+		{"explicit eq", map[string]string{"id[eq]": "7"}, []Filter{{"id", Eq, int64(7)}}},
+		{"ne", map[string]string{"id[ne]": "7"}, []Filter{{"id", Ne, int64(7)}}},
+		{"gt", map[string]string{"id[gt]": "7"}, []Filter{{"id", Gt, int64(7)}}},
+		{"gte", map[string]string{"id[gte]": "7"}, []Filter{{"id", Gte, int64(7)}}},
+		{"lt", map[string]string{"id[lt]": "7"}, []Filter{{"id", Lt, int64(7)}}},
+		{"lte", map[string]string{"id[lte]": "7"}, []Filter{{"id", Lte, int64(7)}}},
+		{"like keeps the text", map[string]string{"email[like]": "%_example"}, []Filter{{"email", Like, "%_example"}}},
+		{"in splits the list", map[string]string{"id[in]": "1,2,3"}, []Filter{{"id", In, []interface{}{int64(1), int64(2), int64(3)}}}},
+		{"bool", map[string]string{"active": "true"}, []Filter{{"is_active", Eq, true}}},
+		{"date", map[string]string{"created_at[gte]": "2024-01-02"}, []Filter{{"created_at", Gte, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}}},
+		{"rfc 3339 time", map[string]string{"created_at[lt]": "2024-01-02T03:04:05Z"}, []Filter{{"created_at", Lt, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}}},
+		{"filters come out in key order", map[string]string{"id": "1", "email": "a@example.com"}, []Filter{{"email", Eq, "a@example.com"}, {"id", Eq, int64(1)}}},
+		{"reserved parameters are not filters", map[string]string{"page": "2", "limit": "10", "cursor": "x", "total": "true"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, violations := testSchema.Parse(tt.params)
+			if violations != nil {
+				t.Fatalf("unexpected violations: %v", violations)
+			}
+			if !reflect.DeepEqual(q.Filters, tt.want) {
+				t.Errorf("got filters %v, want %v", q.Filters, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsInvalidParameters(t *testing.T) {
+	tooMany := strings.TrimSuffix(strings.Repeat("1,", maxInValues+1), ",")
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   models.ValidationError
+	}{
+		{"unknown field", map[string]string{"nickname": "bob"}, models.ValidationError{Field: "nickname", Error: "Unknown filter"}},
+		{"field without operators", map[string]string{"password": "secret"}, models.ValidationError{Field: "password", Error: "Unknown filter"}},
+		{"malformed key", map[string]string{"email[eq": "a@example.com"}, models.ValidationError{Field: "email[eq", Error: "Unknown filter"}},
+		{"column expression as key", map[string]string{"lower(email)": "a@example.com"}, models.ValidationError{Field: "lower(email)", Error: "Unknown filter"}},
+		{"unknown operator", map[string]string{"id[between]": "1"}, models.ValidationError{Field: "id[between]", Error: "Should use one of the operators: eq, ne, gt, gte, lt, lte, in"}},
+		{"operator not allowed on field", map[string]string{"email[gt]": "a"}, models.ValidationError{Field: "email[gt]", Error: "Should use one of the operators: eq, like, in"}},
+		{"implicit eq not allowed on field", map[string]string{"created_at": "2024-01-02"}, models.ValidationError{Field: "created_at", Error: "Should use one of the operators: gte, lt"}},
+		{"empty value", map[string]string{"email": ""}, models.ValidationError{Field: "email", Error: msgValueRequired}},
+		{"bad integer", map[string]string{"id": "1 OR 1=1"}, models.ValidationError{Field: "id", Error: msgNotInteger}},
+		{"bad integer in list", map[string]string{"id[in]": "1,two"}, models.ValidationError{Field: "id[in]", Error: msgNotInteger}},
+		{"too many values", map[string]string{"id[in]": tooMany}, models.ValidationError{Field: "id[in]", Error: "Should list at most 50 values"}},
+		{"bad time", map[string]string{"created_at[gte]": "yesterday"}, models.ValidationError{Field: "created_at[gte]", Error: msgNotTime}},
+		{"bad bool", map[string]string{"active": "maybe"}, models.ValidationError{Field: "active", Error: msgNotBool}},
+		{"search too long", map[string]string{"q": strings.Repeat("a", maxSearchLength+1)}, models.ValidationError{Field: "q", Error: "Should be at most 100 characters long"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, violations := testSchema.Parse(tt.params)
+			if q != nil {
+				t.Errorf("got query %+v, want none", q)
+			}
+			if len(violations) != 1 || violations[0] != tt.want {
+				t.Errorf("got violations %v, want %v", violations, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	q, violations := testSchema.Parse(map[string]string{"sort": "-created_at,id"})
+	if violations != nil {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+	want := []Sort{{Column: "created_at", Desc: true}, {Column: "id"}}
+	if !reflect.DeepEqual(q.Sorts, want) {
+		t.Errorf("got sorts %v, want %v", q.Sorts, want)
+	}
+}
+
+func TestParseRejectsSortInjection(t *testing.T) {
+	tests := []struct {
+		name string
+		sort string
+		want string
+	}{
+		{"unknown field", "nickname", `Cannot sort by "nickname"`},
+		{"field that is not sortable", "active", `Cannot sort by "active"`},
+		{"statement appended", "email;DROP TABLE users", `Cannot sort by "email;DROP TABLE users"`},
+		{"direction appended", "email DESC", `Cannot sort by "email DESC"`},
+		{"expression", "(SELECT password FROM users LIMIT 1)", `Cannot sort by "(SELECT password FROM users LIMIT 1)"`},
+		{"column name instead of field", "is_active", `Cannot sort by "is_active"`},
+		{"repeated field", "email,-email", `Sorts by "email" more than once`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, violations := testSchema.Parse(map[string]string{"sort": tt.sort})
+			if q != nil {
+				t.Fatalf("got query %+v, want none", q)
+			}
+			want := models.ValidationError{Field: "sort", Error: tt.want}
+			if len(violations) != 1 || violations[0] != want {
+				t.Errorf("got violations %v, want %v", violations, want)
+			}
+		})
+	}
+}
+
+func TestParseSearch(t *testing.T) {
+	q, violations := testSchema.Parse(map[string]string{"q": "  alice  "})
+	if violations != nil {
+		t.Fatalf("unexpected violations: %v", violations)
+	}
+	if q.Search != "alice" || !reflect.DeepEqual(q.SearchColumns, []string{"email"}) {
+		t.Errorf("got search %q on %v", q.Search, q.SearchColumns)
+	}
+
+	unsearchable := &Schema{Fields: testSchema.Fields}
+	_, violations = unsearchable.Parse(map[string]string{"q": "alice"})
+	want := models.ValidationError{Field: "q", Error: "Search is not supported"}
+	if len(violations) != 1 || violations[0] != want {
+		t.Errorf("got violations %v, want %v", violations, want)
+	}
+}
+
+func TestParseReportsEveryViolation(t *testing.T) {
+	_, violations := testSchema.Parse(map[string]string{
+		"id":       "x",
+		"nickname": "bob",
+		"sort":     "nickname",
+	})
+	want := []models.ValidationError{
+		{Field: "id", Error: msgNotInteger},
+		{Field: "nickname", Error: "Unknown filter"},
+		{Field: "sort", Error: `Cannot sort by "nickname"`},
+	}
+	if !reflect.DeepEqual(violations, want) {
+		t.Errorf("got violations %v, want %v", violations, want)
+	}
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourusername/go-production-level/config"
+)
+
+// Result describes the outcome of a rate limit check
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter counts requests for a key against a policy
+type Limiter interface {
+	Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (Result, error)
+}
+
+// FallbackLimiter uses the primary limiter and switches to the fallback while
+// the primary returns errors, e.g. when Redis is unavailable
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	degraded atomic.Bool
+}
+
+// NewFallbackLimiter creates a limiter that falls back to another limiter on errors
+func NewFallbackLimiter(primary, fallback Limiter) *FallbackLimiter {
+	return &FallbackLimiter{
+		primary:  primary,
+		fallback: fallback,
+	}
+}
+
+func (l *FallbackLimiter) Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (Result, error) {
+	result, err := l.primary.Allow(ctx, key, policy)
+	if err == nil {
+		if l.degraded.CompareAndSwap(true, false) {
+			log.Printf("Rate limiter recovered, using the shared limiter again")
+		}
+		return result, nil
+	}
+
+	if l.degraded.CompareAndSwap(false, true) {
+		log.Printf("Rate limiter unavailable, falling back to in-process limits: %v", err)
+	}
+	return l.fallback.Allow(ctx, key, policy)
+}
@@ -0,0 +1,116 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/yourusername/go-production-level/config"
+)
+
+// sweepInterval is how often idle keys are removed from memory
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	// Sliding window: request timestamps, oldest first
+	hits []time.Time
+	// Token bucket state
+	tokens  float64
+	updated time.Time
+
+	expires time.Time
+}
+
+// MemoryLimiter keeps limits in process memory. Limits are per instance, so it
+// is meant as a fallback and for local development.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemoryLimiter creates an in-process limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		entries:   make(map[string]*memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now)
+
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &memoryEntry{tokens: float64(policy.Limit), updated: now}
+		l.entries[key] = entry
+	}
+	entry.expires = now.Add(policy.Window)
+
+	switch policy.Algorithm {
+	case config.RateLimitTokenBucket:
+		return l.tokenBucket(entry, policy, now), nil
+	case config.RateLimitSlidingWindow:
+		return l.slidingWindow(entry, policy, now), nil
+	}
+	return Result{}, fmt.Errorf("unknown rate limit algorithm: %s", policy.Algorithm)
+}
+
+func (l *MemoryLimiter) slidingWindow(entry *memoryEntry, policy config.RateLimitPolicy, now time.Time) Result {
+	cutoff := now.Add(-policy.Window)
+	i := 0
+	for i < len(entry.hits) && !entry.hits[i].After(cutoff) {
+		i++
+	}
+	entry.hits = entry.hits[i:]
+
+	result := Result{Limit: policy.Limit}
+	if len(entry.hits) < policy.Limit {
+		entry.hits = append(entry.hits, now)
+		result.Allowed = true
+	}
+	result.Remaining = policy.Limit - len(entry.hits)
+	result.Reset = entry.hits[0].Add(policy.Window).Sub(now)
+	if !result.Allowed {
+		result.RetryAfter = result.Reset
+	}
+	return result
+}
+
+func (l *MemoryLimiter) tokenBucket(entry *memoryEntry, policy config.RateLimitPolicy, now time.Time) Result {
+	capacity := float64(policy.Limit)
+	rate := capacity / float64(policy.Window)
+
+	entry.tokens = math.Min(capacity, entry.tokens+float64(now.Sub(entry.updated))*rate)
+	entry.updated = now
+
+	result := Result{Limit: policy.Limit}
+	if entry.tokens >= 1 {
+		entry.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration(math.Ceil((1 - entry.tokens) / rate))
+	}
+	result.Remaining = int(entry.tokens)
+	result.Reset = time.Duration(math.Ceil((capacity - entry.tokens) / rate))
+	return result
+}
+
+// sweep removes idle entries. The caller must hold the lock.
+func (l *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	for key, entry := range l.entries {
+		if now.After(entry.expires) {
+			delete(l.entries, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// slidingWindowScript keeps one sorted set member per request in the window.
+// Returns {allowed, remaining, reset ms}.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local member = ARGV[3]
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+  redis.call('ZADD', key, now, member)
+  redis.call('PEXPIRE', key, window)
+  count = count + 1
+  allowed = 1
+end
+
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+local reset = window
+if oldest[2] then
+  reset = tonumber(oldest[2]) + window - now
+end
+return {allowed, limit - count, reset}
+`)
+
+// tokenBucketScript refills the bucket continuously over the window.
+// Returns {allowed, remaining, reset ms, retry after ms}.
+var tokenBucketScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local rate = capacity / window
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call('HMGET', key, 'tokens', 'ts')
+local tokens = tonumber(state[1]) or capacity
+local ts = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - ts) * rate)
+
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+else
+  retry = math.ceil((1 - tokens) / rate)
+end
+
+redis.call('HSET', key, 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', key, window)
+return {allowed, math.floor(tokens), math.ceil((capacity - tokens) / rate), retry}
+`)
+
+// RedisLimiter shares limits between all instances of the API
+type RedisLimiter struct {
+	redis *redis.Client
+}
+
+// NewRedisLimiter creates a limiter backed by Redis
+func NewRedisLimiter(redis *redis.Client) *RedisLimiter {
+	return &RedisLimiter{redis: redis}
+}
+
+func (l *RedisLimiter) Allow(ctx context.Context, key string, policy config.RateLimitPolicy) (Result, error) {
+	window := policy.Window.Milliseconds()
+
+	switch policy.Algorithm {
+	case config.RateLimitTokenBucket:
+		values, err := tokenBucketScript.Run(ctx, l.redis, []string{key}, window, policy.Limit).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return Result{
+			Allowed:    values[0] == 1,
+			Limit:      policy.Limit,
+			Remaining:  int(values[1]),
+			Reset:      time.Duration(values[2]) * time.Millisecond,
+			RetryAfter: time.Duration(values[3]) * time.Millisecond,
+		}, nil
+
+	case config.RateLimitSlidingWindow:
+		member, err := utils.GenerateRandomToken(8)
+		if err != nil {
+			return Result{}, err
+		}
+		values, err := slidingWindowScript.Run(ctx, l.redis, []string{key}, window, policy.Limit, member).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		result := Result{
+			Allowed:   values[0] == 1,
+			Limit:     policy.Limit,
+			Remaining: int(values[1]),
+			Reset:     time.Duration(values[2]) * time.Millisecond,
+		}
+		if !result.Allowed {
+			result.RetryAfter = result.Reset
+		}
+		return result, nil
+	}
+
+	return Result{}, fmt.Errorf("unknown rate limit algorithm: %s", policy.Algorithm)
+}
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error)
+	Revoke(ctx context.Context, id, userID uint) (bool, error)
+	TouchLastUsed(ctx context.Context, id uint, at time.Time) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type APIKeyRepositoryImpl struct {
+	db Database
+}
+
+func NewAPIKeyRepository(db Database) APIKeyRepository {
+	return &APIKeyRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *APIKeyRepositoryImpl) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *APIKeyRepositoryImpl) GetByPrefix(ctx context.Context, prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.WithContext(ctx).Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByUser returns the user's keys that have not been revoked, newest first
+func (r *APIKeyRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).Order("created_at DESC").Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Revoke revokes a key owned by the user. It reports false when there was no such active key.
+func (r *APIKeyRepositoryImpl) Revoke(ctx context.Context, id, userID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Model(&models.APIKey{}).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// TouchLastUsed records when a key was last used without changing updated_at
+func (r *APIKeyRepositoryImpl) TouchLastUsed(ctx context.Context, id uint, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at).Error
+}
+
+func (r *APIKeyRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.APIKey{}).Error
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+// AuditLogFilter narrows down audit log queries. Zero values match everything.
+type AuditLogFilter struct {
+	Action string
+	UserID uint
+}
+
+type AuditLogRepository interface {
+	Create(ctx context.Context, entry *models.AuditLog) error
+	List(ctx context.Context, filter AuditLogFilter, offset, limit int) ([]models.AuditLog, error)
+}
+
+type AuditLogRepositoryImpl struct {
+	db Database
+}
+
+func NewAuditLogRepository(db Database) AuditLogRepository {
+	return &AuditLogRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *AuditLogRepositoryImpl) Create(ctx context.Context, entry *models.AuditLog) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+func (r *AuditLogRepositoryImpl) List(ctx context.Context, filter AuditLogFilter, offset, limit int) ([]models.AuditLog, error) {
+	query := r.db.WithContext(ctx).Model(&models.AuditLog{})
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+
+	var entries []models.AuditLog
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
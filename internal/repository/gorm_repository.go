@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotFound is returned when no record matches the ID or specs
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write conflicts with a unique index
+	ErrDuplicate = errors.New("record already exists")
+)
+
+// Spec narrows down, orders or pages a query. Specs are built with the
+// constructors of this package and applied in order, so several of them
+// compose into one query. The zero Spec leaves a query unchanged.
+type Spec struct {
+	apply func(db *gorm.DB) *gorm.DB
+}
+
+// Where matches the records satisfying the condition
+func Where(query interface{}, args ...interface{}) Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	}}
+}
+
+// OrderBy sorts the records, for example by "created_at DESC"
+func OrderBy(order string) Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		return db.Order(order)
+	}}
+}
+
+// Page skips offset records and returns at most limit. Count and Exists ignore it.
+func Page(offset, limit int) Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		return db.Offset(offset).Limit(limit)
+	}}
+}
+
+// IncludeDeleted includes soft-deleted records. With Delete it removes the
+// record permanently.
+func IncludeDeleted() Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}}
+}
+
+// ForUpdate locks the records read until the transaction ends, so that
+// concurrent writers wait instead of being overwritten. Outside a transaction
+// the lock is released as soon as the query returns.
+func ForUpdate() Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		return db.Clauses(clause.Locking{Strength: "UPDATE"})
+	}}
+}
+
+// All combines specs into one
+func All(specs ...Spec) Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		for _, spec := range specs {
+			db = spec.applyTo(db)
+		}
+		return db
+	}}
+}
+
+// applyTo narrows db down by the spec
+func (s Spec) applyTo(db *gorm.DB) *gorm.DB {
+	if s.apply == nil {
+		return db
+	}
+	return s.apply(db)
+}
+
+// Repository provides typed access to the records of model T with primary key
+// type ID. Lookups return ErrNotFound when nothing matches.
+type Repository[T any, ID comparable] interface {
+	Create(ctx context.Context, entity *T) error
+	Get(ctx context.Context, id ID, specs ...Spec) (*T, error)
+	First(ctx context.Context, specs ...Spec) (*T, error)
+	Update(ctx context.Context, entity *T) error
+	UpdateFields(ctx context.Context, id ID, fields map[string]interface{}, specs ...Spec) error
+	UpdateColumns(ctx context.Context, id ID, columns map[string]interface{}, specs ...Spec) error
+	Delete(ctx context.Context, id ID, specs ...Spec) error
+	List(ctx context.Context, specs ...Spec) ([]T, error)
+	Exists(ctx context.Context, specs ...Spec) (bool, error)
+	Count(ctx context.Context, specs ...Spec) (int64, error)
+}
+
+type GormRepository[T any, ID comparable] struct {
+	db *gorm.DB
+}
+
+func NewGormRepository[T any, ID comparable](db *gorm.DB) Repository[T, ID] {
+	return &GormRepository[T, ID]{db: db}
+}
+
+func (r *GormRepository[T, ID]) Create(ctx context.Context, entity *T) error {
+	return written(conn(ctx, r.db).Create(entity).Error)
+}
+
+func (r *GormRepository[T, ID]) Get(ctx context.Context, id ID, specs ...Spec) (*T, error) {
+	return first[T](r.query(ctx, specs).Where(primaryKey(id)))
+}
+
+// First returns the first record matching the specs, by primary key unless they order the records
+func (r *GormRepository[T, ID]) First(ctx context.Context, specs ...Spec) (*T, error) {
+	return first[T](r.query(ctx, specs))
+}
+
+// Update saves all fields of the entity
+func (r *GormRepository[T, ID]) Update(ctx context.Context, entity *T) error {
+	return written(conn(ctx, r.db).Save(entity).Error)
+}
+
+// UpdateFields sets the given fields of a record and its updated_at
+func (r *GormRepository[T, ID]) UpdateFields(ctx context.Context, id ID, fields map[string]interface{}, specs ...Spec) error {
+	result := r.query(ctx, specs).Where(primaryKey(id)).Updates(fields)
+	return affected(result)
+}
+
+// UpdateColumns sets the given columns of a record without touching updated_at
+func (r *GormRepository[T, ID]) UpdateColumns(ctx context.Context, id ID, columns map[string]interface{}, specs ...Spec) error {
+	result := r.query(ctx, specs).Where(primaryKey(id)).UpdateColumns(columns)
+	return affected(result)
+}
+
+// Delete soft-deletes a record, or removes it permanently with IncludeDeleted
+func (r *GormRepository[T, ID]) Delete(ctx context.Context, id ID, specs ...Spec) error {
+	result := r.query(ctx, specs).Where(primaryKey(id)).Delete(new(T))
+	return affected(result)
+}
+
+func (r *GormRepository[T, ID]) List(ctx context.Context, specs ...Spec) ([]T, error) {
+	var entities []T
+	err := r.query(ctx, specs).Find(&entities).Error
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
+
+func (r *GormRepository[T, ID]) Exists(ctx context.Context, specs ...Spec) (bool, error) {
+	var exists bool
+	subquery := r.query(ctx, specs).Select("1").Offset(-1).Limit(1)
+	err := conn(ctx, r.db).Raw("SELECT EXISTS (?)", subquery).Scan(&exists).Error
+	return exists, err
+}
+
+func (r *GormRepository[T, ID]) Count(ctx context.Context, specs ...Spec) (int64, error) {
+	var count int64
+	err := r.query(ctx, specs).Offset(-1).Limit(-1).Count(&count).Error
+	return count, err
+}
+
+// query starts a query on the model's table with the specs applied
+func (r *GormRepository[T, ID]) query(ctx context.Context, specs []Spec) *gorm.DB {
+	return All(specs...).applyTo(conn(ctx, r.db).Model(new(T)))
+}
+
+// primaryKey matches the record with the primary key id, whatever the key's column is named
+func primaryKey[ID comparable](id ID) clause.Eq {
+	return clause.Eq{Column: clause.PrimaryColumn, Value: id}
+}
+
+// first loads the first record of the query
+func first[T any](query *gorm.DB) (*T, error) {
+	var entity T
+	err := query.First(&entity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// affected reports ErrNotFound when a write matched no record
+func affected(result *gorm.DB) error {
+	if result.Error != nil {
+		return written(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// written reports ErrDuplicate when a write failed on a unique index
+func written(err error) error {
+	var pgErr *pgconn.PgError
+	// unique_violation
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrDuplicate
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"gorm.io/gorm"
+)
+
+// LinkedIdentityRepository stores identities at external providers. Lookups
+// return ErrNotFound when there is no such identity.
+type LinkedIdentityRepository interface {
+	Create(ctx context.Context, identity *models.LinkedIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*models.LinkedIdentity, error)
+	ListByUser(ctx context.Context, userID uint) ([]models.LinkedIdentity, error)
+	Delete(ctx context.Context, id, userID uint) (bool, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+	DeleteByProvider(ctx context.Context, provider string) error
+}
+
+type LinkedIdentityRepositoryImpl struct {
+	db Database
+}
+
+func NewLinkedIdentityRepository(db Database) LinkedIdentityRepository {
+	return &LinkedIdentityRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *LinkedIdentityRepositoryImpl) Create(ctx context.Context, identity *models.LinkedIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *LinkedIdentityRepositoryImpl) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.LinkedIdentity, error) {
+	var identity models.LinkedIdentity
+	err := r.db.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *LinkedIdentityRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]models.LinkedIdentity, error) {
+	var identities []models.LinkedIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&identities).Error
+	if err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// Delete removes an identity owned by the user. It reports false when there was no such identity.
+func (r *LinkedIdentityRepositoryImpl) Delete(ctx context.Context, id, userID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.LinkedIdentity{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *LinkedIdentityRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.LinkedIdentity{}).Error
+}
+
+func (r *LinkedIdentityRepositoryImpl) DeleteByProvider(ctx context.Context, provider string) error {
+	return r.db.WithContext(ctx).Where("provider = ?", provider).Delete(&models.LinkedIdentity{}).Error
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *models.LoginEvent) error
+	ListByUser(ctx context.Context, userID uint, offset, limit int) ([]models.LoginEvent, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type LoginEventRepositoryImpl struct {
+	db Database
+}
+
+func NewLoginEventRepository(db Database) LoginEventRepository {
+	return &LoginEventRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *LoginEventRepositoryImpl) Create(ctx context.Context, event *models.LoginEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+func (r *LoginEventRepositoryImpl) ListByUser(ctx context.Context, userID uint, offset, limit int) ([]models.LoginEvent, error) {
+	var events []models.LoginEvent
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC").Offset(offset).Limit(limit).Find(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *LoginEventRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.LoginEvent{}).Error
+}
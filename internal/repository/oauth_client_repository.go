@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type OAuthClientRepository interface {
+	Create(ctx context.Context, client *models.OAuthClient) error
+	GetByID(ctx context.Context, id uint) (*models.OAuthClient, error)
+	GetByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	List(ctx context.Context) ([]models.OAuthClient, error)
+	Delete(ctx context.Context, id uint) (bool, error)
+}
+
+type OAuthClientRepositoryImpl struct {
+	db Database
+}
+
+func NewOAuthClientRepository(db Database) OAuthClientRepository {
+	return &OAuthClientRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *OAuthClientRepositoryImpl) Create(ctx context.Context, client *models.OAuthClient) error {
+	return r.db.WithContext(ctx).Create(client).Error
+}
+
+func (r *OAuthClientRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.WithContext(ctx).First(&client, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *OAuthClientRepositoryImpl) GetByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *OAuthClientRepositoryImpl) List(ctx context.Context) ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.WithContext(ctx).Model(&models.OAuthClient{}).Order("created_at").Find(&clients).Error
+	if err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Delete removes a client. It reports false when there was no such client.
+func (r *OAuthClientRepositoryImpl) Delete(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(&models.OAuthClient{}, id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type OAuthConsentRepository interface {
+	Get(ctx context.Context, userID, clientID uint) (*models.OAuthConsent, error)
+	GetByID(ctx context.Context, id, userID uint) (*models.OAuthConsent, error)
+	Save(ctx context.Context, consent *models.OAuthConsent) error
+	ListByUser(ctx context.Context, userID uint) ([]models.OAuthConsent, error)
+	Delete(ctx context.Context, id uint) error
+	DeleteByClient(ctx context.Context, clientID uint) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type OAuthConsentRepositoryImpl struct {
+	db Database
+}
+
+func NewOAuthConsentRepository(db Database) OAuthConsentRepository {
+	return &OAuthConsentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *OAuthConsentRepositoryImpl) Get(ctx context.Context, userID, clientID uint) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	err := r.db.WithContext(ctx).Where("user_id = ? AND oauth_client_id = ?", userID, clientID).First(&consent).Error
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// GetByID returns a consent granted by the user
+func (r *OAuthConsentRepositoryImpl) GetByID(ctx context.Context, id, userID uint) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&consent).Error
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+func (r *OAuthConsentRepositoryImpl) Save(ctx context.Context, consent *models.OAuthConsent) error {
+	return r.db.WithContext(ctx).Save(consent).Error
+}
+
+func (r *OAuthConsentRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]models.OAuthConsent, error) {
+	var consents []models.OAuthConsent
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&consents).Error
+	if err != nil {
+		return nil, err
+	}
+	return consents, nil
+}
+
+func (r *OAuthConsentRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&models.OAuthConsent{}, id).Error
+}
+
+func (r *OAuthConsentRepositoryImpl) DeleteByClient(ctx context.Context, clientID uint) error {
+	return r.db.WithContext(ctx).Where("oauth_client_id = ?", clientID).Delete(&models.OAuthConsent{}).Error
+}
+
+func (r *OAuthConsentRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.OAuthConsent{}).Error
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type OneTimeTokenRepository interface {
+	Create(ctx context.Context, token *models.OneTimeToken) error
+	GetUnusedByHash(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	InvalidateForUser(ctx context.Context, userID uint, purpose string) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type OneTimeTokenRepositoryImpl struct {
+	db Database
+}
+
+func NewOneTimeTokenRepository(db Database) OneTimeTokenRepository {
+	return &OneTimeTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *OneTimeTokenRepositoryImpl) Create(ctx context.Context, token *models.OneTimeToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *OneTimeTokenRepositoryImpl) GetUnusedByHash(ctx context.Context, purpose, hash string) (*models.OneTimeToken, error) {
+	var token models.OneTimeToken
+	err := r.db.WithContext(ctx).Where("purpose = ? AND token_hash = ? AND used_at IS NULL", purpose, hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes a token. It reports false when the token was already used.
+func (r *OneTimeTokenRepositoryImpl) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND used_at IS NULL", id).
+		Model(&models.OneTimeToken{}).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// InvalidateForUser consumes every outstanding token of the given purpose
+func (r *OneTimeTokenRepositoryImpl) InvalidateForUser(ctx context.Context, userID uint, purpose string) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Model(&models.OneTimeToken{}).
+		Update("used_at", time.Now()).Error
+}
+
+func (r *OneTimeTokenRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.OneTimeToken{}).Error
+}
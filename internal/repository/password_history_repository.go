@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type PasswordHistoryRepository interface {
+	Create(ctx context.Context, entry *models.PasswordHistory) error
+	ListRecent(ctx context.Context, userID uint, limit int) ([]models.PasswordHistory, error)
+	Prune(ctx context.Context, userID uint, keep int) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type PasswordHistoryRepositoryImpl struct {
+	db Database
+}
+
+func NewPasswordHistoryRepository(db Database) PasswordHistoryRepository {
+	return &PasswordHistoryRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *PasswordHistoryRepositoryImpl) Create(ctx context.Context, entry *models.PasswordHistory) error {
+	return r.db.WithContext(ctx).Create(entry).Error
+}
+
+// ListRecent returns the user's most recent password hashes, newest first
+func (r *PasswordHistoryRepositoryImpl) ListRecent(ctx context.Context, userID uint, limit int) ([]models.PasswordHistory, error) {
+	var entries []models.PasswordHistory
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id DESC").Limit(limit).Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Prune deletes all but the user's keep most recent password hashes
+func (r *PasswordHistoryRepositoryImpl) Prune(ctx context.Context, userID uint, keep int) error {
+	return r.db.WithContext(ctx).Exec(`DELETE FROM password_histories WHERE user_id = ? AND id NOT IN (
+		SELECT id FROM password_histories WHERE user_id = ? ORDER BY id DESC LIMIT ?)`, userID, userID, keep).Error
+}
+
+func (r *PasswordHistoryRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.PasswordHistory{}).Error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type PermissionRepository interface {
+	Create(ctx context.Context, permission *models.Permission) error
+	GetByName(ctx context.Context, name string) (*models.Permission, error)
+	GetByNames(ctx context.Context, names []string) ([]models.Permission, error)
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context) ([]models.Permission, error)
+}
+
+type PermissionRepositoryImpl struct {
+	db Database
+}
+
+func NewPermissionRepository(db Database) PermissionRepository {
+	return &PermissionRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *PermissionRepositoryImpl) Create(ctx context.Context, permission *models.Permission) error {
+	return r.db.WithContext(ctx).Create(permission).Error
+}
+
+func (r *PermissionRepositoryImpl) GetByName(ctx context.Context, name string) (*models.Permission, error) {
+	var permission models.Permission
+	err := r.db.WithContext(ctx).Where("name = ?", name).First(&permission).Error
+	if err != nil {
+		return nil, err
+	}
+	return &permission, nil
+}
+
+func (r *PermissionRepositoryImpl) GetByNames(ctx context.Context, names []string) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.WithContext(ctx).Where("name IN ?", names).Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *PermissionRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Exec("DELETE FROM role_permissions WHERE permission_id = ?", id).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Delete(&models.Permission{}, id).Error
+}
+
+func (r *PermissionRepositoryImpl) List(ctx context.Context) ([]models.Permission, error) {
+	var permissions []models.Permission
+	err := r.db.WithContext(ctx).Find(&permissions).Error
+	if err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"strings"
+
+	"github.com/yourusername/go-production-level/internal/query"
+	"gorm.io/gorm"
+)
+
+// comparisons maps filter operators to SQL, except for like and in
+var comparisons = map[query.Operator]string{
+	query.Eq:  "=",
+	query.Ne:  "<>",
+	query.Gt:  ">",
+	query.Gte: ">=",
+	query.Lt:  "<",
+	query.Lte: "<=",
+}
+
+// Matching narrows a query down to the records matching the filters and search
+// of q. Values are passed as parameters; the columns come from the schema the
+// query was parsed with.
+func Matching(q *query.Query) Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		if q == nil {
+			return db
+		}
+
+		for _, filter := range q.Filters {
+			switch filter.Operator {
+			case query.Like:
+				db = db.Where("LOWER("+filter.Column+") LIKE ?", likePattern(filter.Value.(string)))
+			case query.In:
+				db = db.Where(filter.Column+" IN ?", filter.Value)
+			default:
+				db = db.Where(filter.Column+" "+comparisons[filter.Operator]+" ?", filter.Value)
+			}
+		}
+
+		if q.Search != "" {
+			like := likePattern(q.Search)
+			conditions := make([]string, len(q.SearchColumns))
+			args := make([]interface{}, len(q.SearchColumns))
+			for i, column := range q.SearchColumns {
+				conditions[i] = "LOWER(" + column + ") LIKE ?"
+				args[i] = like
+			}
+			db = db.Where(strings.Join(conditions, " OR "), args...)
+		}
+		return db
+	}}
+}
+
+// SortedBy orders a query by the sorts of q, followed by the fallback order
+// when q has none. The fallback also breaks ties between equal sort values.
+func SortedBy(q *query.Query, fallback string) Spec {
+	return Spec{func(db *gorm.DB) *gorm.DB {
+		if q != nil {
+			for _, sort := range q.Sorts {
+				if sort.Desc {
+					db = db.Order(sort.Column + " DESC")
+				} else {
+					db = db.Order(sort.Column)
+				}
+			}
+		}
+		return db.Order(fallback)
+	}}
+}
+
+// likePattern matches values containing s, ignoring case
+func likePattern(s string) string {
+	return "%" + escapeLike(strings.ToLower(s)) + "%"
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type RecoveryCodeRepository interface {
+	ReplaceForUser(ctx context.Context, userID uint, codes []models.RecoveryCode) error
+	GetUnusedByHash(ctx context.Context, userID uint, hash string) (*models.RecoveryCode, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type RecoveryCodeRepositoryImpl struct {
+	db Database
+}
+
+func NewRecoveryCodeRepository(db Database) RecoveryCodeRepository {
+	return &RecoveryCodeRepositoryImpl{
+		db: db,
+	}
+}
+
+// ReplaceForUser deletes the user's existing codes and stores the new ones
+func (r *RecoveryCodeRepositoryImpl) ReplaceForUser(ctx context.Context, userID uint, codes []models.RecoveryCode) error {
+	if err := r.DeleteByUser(ctx, userID); err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Create(&codes).Error
+}
+
+func (r *RecoveryCodeRepositoryImpl) GetUnusedByHash(ctx context.Context, userID uint, hash string) (*models.RecoveryCode, error) {
+	var code models.RecoveryCode
+	err := r.db.WithContext(ctx).Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).First(&code).Error
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkUsed consumes a code. It reports false when the code was already used.
+func (r *RecoveryCodeRepositoryImpl) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND used_at IS NULL", id).
+		Model(&models.RecoveryCode{}).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RecoveryCodeRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
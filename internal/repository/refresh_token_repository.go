@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	RevokeForClient(ctx context.Context, clientID uint, userID *uint) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type RefreshTokenRepositoryImpl struct {
+	db Database
+}
+
+func NewRefreshTokenRepository(db Database) RefreshTokenRepository {
+	return &RefreshTokenRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *RefreshTokenRepositoryImpl) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.WithContext(ctx).Create(token).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) GetByHash(ctx context.Context, hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	err := r.db.WithContext(ctx).Where("token_hash = ?", hash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed flags a token as rotated. It reports false when the token was
+// already used or revoked, which lets concurrent refreshes race safely.
+func (r *RefreshTokenRepositoryImpl) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Model(&models.RefreshToken{}).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenRepositoryImpl) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Model(&models.RefreshToken{}).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) RevokeAllForUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Model(&models.RefreshToken{}).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeForClient revokes the tokens issued to an OAuth2 client, only those of one user when userID is set
+func (r *RefreshTokenRepositoryImpl) RevokeForClient(ctx context.Context, clientID uint, userID *uint) error {
+	query := r.db.WithContext(ctx).Where("oauth_client_id = ? AND revoked_at IS NULL", clientID)
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	return query.Model(&models.RefreshToken{}).Update("revoked_at", time.Now()).Error
+}
+
+func (r *RefreshTokenRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RefreshToken{}).Error
+}
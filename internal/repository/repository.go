@@ -1,8 +1,17 @@
 package repository
 
-import "gorm.io/gorm"
+import (
+	"context"
 
-type Repository interface {
+	"gorm.io/gorm"
+)
+
+// Database wraps the database handle for the repositories that are not yet
+// built on the typed Repository. Repositories call WithContext with the
+// request context first, so that queries are cancelled with the request and
+// take part in the transaction bound to it.
+type Database interface {
+	WithContext(ctx context.Context) Database
 	Create(value interface{}) *gorm.DB
 	Save(value interface{}) *gorm.DB
 	First(dest interface{}, conds ...interface{}) *gorm.DB
@@ -11,44 +20,75 @@ type Repository interface {
 	Where(query interface{}, args ...interface{}) *gorm.DB
 	Offset(offset int) *gorm.DB
 	Limit(limit int) *gorm.DB
+	Model(value interface{}) *gorm.DB
+	Preload(query string, args ...interface{}) *gorm.DB
+	Exec(sql string, values ...interface{}) *gorm.DB
+	Unscoped() *gorm.DB
+}
+
+// UserDataRepository is implemented by the repositories holding records that
+// belong to a user, so that they can be removed when the user is purged.
+type UserDataRepository interface {
+	DeleteByUser(ctx context.Context, userID uint) error
 }
 
-type GormRepository struct {
+type GormDatabase struct {
 	db *gorm.DB
 }
 
-func NewGormRepository(db *gorm.DB) Repository {
-	return &GormRepository{db: db}
+func NewDatabase(db *gorm.DB) Database {
+	return &GormDatabase{db: db}
 }
 
-func (r *GormRepository) Create(value interface{}) *gorm.DB {
+// WithContext returns a database whose queries run with ctx
+func (r *GormDatabase) WithContext(ctx context.Context) Database {
+	return &GormDatabase{db: conn(ctx, r.db)}
+}
+
+func (r *GormDatabase) Create(value interface{}) *gorm.DB {
 	return r.db.Create(value)
 }
 
-func (r *GormRepository) Save(value interface{}) *gorm.DB {
+func (r *GormDatabase) Save(value interface{}) *gorm.DB {
 	return r.db.Save(value)
 }
 
-func (r *GormRepository) First(dest interface{}, conds ...interface{}) *gorm.DB {
+func (r *GormDatabase) First(dest interface{}, conds ...interface{}) *gorm.DB {
 	return r.db.First(dest, conds...)
 }
 
-func (r *GormRepository) Find(dest interface{}, conds ...interface{}) *gorm.DB {
+func (r *GormDatabase) Find(dest interface{}, conds ...interface{}) *gorm.DB {
 	return r.db.Find(dest, conds...)
 }
 
-func (r *GormRepository) Delete(value interface{}, conds ...interface{}) *gorm.DB {
+func (r *GormDatabase) Delete(value interface{}, conds ...interface{}) *gorm.DB {
 	return r.db.Delete(value, conds...)
 }
 
-func (r *GormRepository) Where(query interface{}, args ...interface{}) *gorm.DB {
+func (r *GormDatabase) Where(query interface{}, args ...interface{}) *gorm.DB {
 	return r.db.Where(query, args...)
 }
 
-func (r *GormRepository) Offset(offset int) *gorm.DB {
+func (r *GormDatabase) Offset(offset int) *gorm.DB {
 	return r.db.Offset(offset)
 }
 
-func (r *GormRepository) Limit(limit int) *gorm.DB {
+func (r *GormDatabase) Limit(limit int) *gorm.DB {
 	return r.db.Limit(limit)
 }
+
+func (r *GormDatabase) Model(value interface{}) *gorm.DB {
+	return r.db.Model(value)
+}
+
+func (r *GormDatabase) Preload(query string, args ...interface{}) *gorm.DB {
+	return r.db.Preload(query, args...)
+}
+
+func (r *GormDatabase) Exec(sql string, values ...interface{}) *gorm.DB {
+	return r.db.Exec(sql, values...)
+}
+
+func (r *GormDatabase) Unscoped() *gorm.DB {
+	return r.db.Unscoped()
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type RoleRepository interface {
+	Create(ctx context.Context, role *models.Role) error
+	GetByID(ctx context.Context, id uint) (*models.Role, error)
+	GetByName(ctx context.Context, name string) (*models.Role, error)
+	Update(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, id uint) error
+	List(ctx context.Context) ([]models.Role, error)
+	ReplacePermissions(ctx context.Context, role *models.Role, permissions []models.Permission) error
+}
+
+type RoleRepositoryImpl struct {
+	db Database
+}
+
+func NewRoleRepository(db Database) RoleRepository {
+	return &RoleRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *RoleRepositoryImpl) Create(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Create(role).Error
+}
+
+func (r *RoleRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.Role, error) {
+	var role models.Role
+	err := r.db.WithContext(ctx).Preload("Permissions").First(&role, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepositoryImpl) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	err := r.db.WithContext(ctx).Preload("Permissions").Where("name = ?", name).First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *RoleRepositoryImpl) Update(ctx context.Context, role *models.Role) error {
+	return r.db.WithContext(ctx).Model(role).Select("description").Updates(role).Error
+}
+
+func (r *RoleRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	if err := r.db.WithContext(ctx).Exec("DELETE FROM role_permissions WHERE role_id = ?", id).Error; err != nil {
+		return err
+	}
+	return r.db.WithContext(ctx).Delete(&models.Role{}, id).Error
+}
+
+func (r *RoleRepositoryImpl) List(ctx context.Context) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.WithContext(ctx).Preload("Permissions").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *RoleRepositoryImpl) ReplacePermissions(ctx context.Context, role *models.Role, permissions []models.Permission) error {
+	return r.db.WithContext(ctx).Model(role).Association("Permissions").Replace(permissions)
+}
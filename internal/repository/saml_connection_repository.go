@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type SAMLConnectionRepository interface {
+	Create(ctx context.Context, connection *models.SAMLConnection) error
+	GetByID(ctx context.Context, id uint) (*models.SAMLConnection, error)
+	GetByOrganization(ctx context.Context, organization string) (*models.SAMLConnection, error)
+	List(ctx context.Context) ([]models.SAMLConnection, error)
+	Update(ctx context.Context, connection *models.SAMLConnection) error
+	Delete(ctx context.Context, id uint) (bool, error)
+}
+
+type SAMLConnectionRepositoryImpl struct {
+	db Database
+}
+
+func NewSAMLConnectionRepository(db Database) SAMLConnectionRepository {
+	return &SAMLConnectionRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *SAMLConnectionRepositoryImpl) Create(ctx context.Context, connection *models.SAMLConnection) error {
+	return r.db.WithContext(ctx).Create(connection).Error
+}
+
+func (r *SAMLConnectionRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.SAMLConnection, error) {
+	var connection models.SAMLConnection
+	err := r.db.WithContext(ctx).First(&connection, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
+
+func (r *SAMLConnectionRepositoryImpl) GetByOrganization(ctx context.Context, organization string) (*models.SAMLConnection, error) {
+	var connection models.SAMLConnection
+	err := r.db.WithContext(ctx).Where("organization = ?", organization).First(&connection).Error
+	if err != nil {
+		return nil, err
+	}
+	return &connection, nil
+}
+
+func (r *SAMLConnectionRepositoryImpl) List(ctx context.Context) ([]models.SAMLConnection, error) {
+	var connections []models.SAMLConnection
+	err := r.db.WithContext(ctx).Model(&models.SAMLConnection{}).Order("organization").Find(&connections).Error
+	if err != nil {
+		return nil, err
+	}
+	return connections, nil
+}
+
+func (r *SAMLConnectionRepositoryImpl) Update(ctx context.Context, connection *models.SAMLConnection) error {
+	return r.db.WithContext(ctx).Save(connection).Error
+}
+
+// Delete removes a connection. It reports false when there was no such connection.
+func (r *SAMLConnectionRepositoryImpl) Delete(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(&models.SAMLConnection{}, id)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+type SessionRepository interface {
+	Create(ctx context.Context, session *models.Session) error
+	GetActive(ctx context.Context, id, userID uint) (*models.Session, error)
+	ListActiveByUser(ctx context.Context, userID uint) ([]models.Session, error)
+	TouchLastSeen(ctx context.Context, familyID string, at time.Time) error
+	Extend(ctx context.Context, familyID string, expiresAt time.Time) error
+	Revoke(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+type SessionRepositoryImpl struct {
+	db Database
+}
+
+func NewSessionRepository(db Database) SessionRepository {
+	return &SessionRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *SessionRepositoryImpl) Create(ctx context.Context, session *models.Session) error {
+	return r.db.WithContext(ctx).Create(session).Error
+}
+
+// GetActive returns a session of the user that has neither been revoked nor expired
+func (r *SessionRepositoryImpl) GetActive(ctx context.Context, id, userID uint) (*models.Session, error) {
+	var session models.Session
+	err := r.db.WithContext(ctx).Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListActiveByUser returns the user's active sessions, most recently seen first
+func (r *SessionRepositoryImpl) ListActiveByUser(ctx context.Context, userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// TouchLastSeen records activity on a session without changing updated_at
+func (r *SessionRepositoryImpl) TouchLastSeen(ctx context.Context, familyID string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Session{}).Where("family_id = ?", familyID).UpdateColumn("last_seen_at", at).Error
+}
+
+// Extend records a token refresh: the session was seen now and lives until expiresAt
+func (r *SessionRepositoryImpl) Extend(ctx context.Context, familyID string, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Model(&models.Session{}).
+		Updates(map[string]interface{}{"last_seen_at": time.Now(), "expires_at": expiresAt}).Error
+}
+
+func (r *SessionRepositoryImpl) Revoke(ctx context.Context, familyID string) error {
+	return r.db.WithContext(ctx).Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Model(&models.Session{}).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *SessionRepositoryImpl) RevokeAllForUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ? AND revoked_at IS NULL", userID).
+		Model(&models.Session{}).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *SessionRepositoryImpl) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.Session{}).Error
+}
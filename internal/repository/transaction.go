@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+const (
+	// txMaxAttempts bounds how often a transaction is run when it keeps
+	// failing on serialization conflicts
+	txMaxAttempts = 3
+	txRetryDelay  = 20 * time.Millisecond
+)
+
+// TxManager runs units of work in database transactions
+type TxManager interface {
+	// WithinTransaction runs fn in a transaction, which is committed when fn
+	// returns nil and rolled back otherwise. Repositories called with the
+	// context passed to fn take part in the transaction. Called again inside
+	// fn, it opens a savepoint, so a failing nested unit is rolled back
+	// without failing the outer one when its error is handled. A transaction
+	// that fails on a serialization conflict or deadlock is run again, so fn
+	// must not have side effects outside the database.
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error
+}
+
+// TxOption configures a transaction started by WithinTransaction
+type TxOption func(opts *sql.TxOptions)
+
+// Isolation runs the transaction at the isolation level instead of the
+// database's default, which is READ COMMITTED for Postgres. Serialization
+// conflicts, and so retries, mostly happen at sql.LevelRepeatableRead and
+// above. A savepoint runs at the level of its transaction, so the option has
+// no effect on nested calls.
+func Isolation(level sql.IsolationLevel) TxOption {
+	return func(opts *sql.TxOptions) {
+		opts.Isolation = level
+	}
+}
+
+type txKey struct{}
+
+type GormTxManager struct {
+	db *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) TxManager {
+	return &GormTxManager{db: db}
+}
+
+func (m *GormTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...TxOption) error {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return run(ctx, tx, fn, nil)
+	}
+
+	txOpts := &sql.TxOptions{}
+	for _, opt := range opts {
+		opt(txOpts)
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := run(ctx, m.db, fn, txOpts)
+		if err == nil || attempt == txMaxAttempts || !isSerializationFailure(err) {
+			return err
+		}
+
+		timer := time.NewTimer(time.Duration(attempt) * txRetryDelay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// run calls fn in a transaction on db, which becomes a savepoint when db is a
+// transaction itself. opts only apply to new transactions.
+func run(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error, opts *sql.TxOptions) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	}, opts)
+}
+
+// conn returns the transaction bound to ctx, or db when there is none
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
+
+// isSerializationFailure reports whether err is a conflict with a concurrent
+// transaction that a retry may resolve
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	// serialization_failure and deadlock_detected
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
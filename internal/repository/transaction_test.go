@@ -0,0 +1,294 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recorder is a database driver that logs the statements and transaction
+// boundaries it sees instead of running them
+type recorder struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (r *recorder) record(entry string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.log = append(r.log, entry)
+}
+
+func (r *recorder) entries() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.log...)
+}
+
+func (r *recorder) Connect(ctx context.Context) (driver.Conn, error) {
+	return &recordingConn{r}, nil
+}
+
+func (r *recorder) Driver() driver.Driver {
+	return nil
+}
+
+type recordingConn struct {
+	r *recorder
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (c *recordingConn) Close() error {
+	return nil
+}
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *recordingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if level := sql.IsolationLevel(opts.Isolation); level != sql.LevelDefault {
+		c.r.record("BEGIN " + level.String())
+	} else {
+		c.r.record("BEGIN")
+	}
+	return recordingTx{c.r}, nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	// Savepoint names are generated, so only their statements are logged
+	switch {
+	case strings.HasPrefix(query, "ROLLBACK TO SAVEPOINT "):
+		query = "ROLLBACK TO SAVEPOINT"
+	case strings.HasPrefix(query, "SAVEPOINT "):
+		query = "SAVEPOINT"
+	}
+	c.r.record(query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.r.record(query)
+	return noRows{}, nil
+}
+
+type recordingTx struct {
+	r *recorder
+}
+
+func (t recordingTx) Commit() error {
+	t.r.record("COMMIT")
+	return nil
+}
+
+func (t recordingTx) Rollback() error {
+	t.r.record("ROLLBACK")
+	return nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string              { return nil }
+func (noRows) Close() error                   { return nil }
+func (noRows) Next(dest []driver.Value) error { return io.EOF }
+
+func newRecordingTxManager(t *testing.T) (TxManager, *gorm.DB, *recorder) {
+	t.Helper()
+	r := &recorder{}
+	conn := sql.OpenDB(r)
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTxManager(db), db, r
+}
+
+// exec runs a statement on the transaction bound to ctx, if any
+func exec(ctx context.Context, db *gorm.DB, statement string) error {
+	return conn(ctx, db).Exec(statement).Error
+}
+
+func TestWithinTransaction(t *testing.T) {
+	failure := errors.New("failed")
+	serializationFailure := &pgconn.PgError{Code: "40001"}
+
+	tests := []struct {
+		name    string
+		opts    []TxOption
+		run     func(ctx context.Context, m TxManager, db *gorm.DB) error
+		wantErr error
+		wantLog []string
+	}{
+		{
+			name: "commits when fn succeeds",
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				return exec(ctx, db, "INSERT a")
+			},
+			wantLog: []string{"BEGIN", "INSERT a", "COMMIT"},
+		},
+		{
+			name: "rolls back when fn fails",
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				if err := exec(ctx, db, "INSERT a"); err != nil {
+					return err
+				}
+				return failure
+			},
+			wantErr: failure,
+			wantLog: []string{"BEGIN", "INSERT a", "ROLLBACK"},
+		},
+		{
+			name: "nested failure only rolls back its savepoint",
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				if err := exec(ctx, db, "INSERT a"); err != nil {
+					return err
+				}
+				err := m.WithinTransaction(ctx, func(ctx context.Context) error {
+					if err := exec(ctx, db, "INSERT b"); err != nil {
+						return err
+					}
+					return failure
+				})
+				if !errors.Is(err, failure) {
+					return errors.New("nested error was not returned")
+				}
+				return exec(ctx, db, "INSERT c")
+			},
+			wantLog: []string{"BEGIN", "INSERT a", "SAVEPOINT", "INSERT b", "ROLLBACK TO SAVEPOINT", "INSERT c", "COMMIT"},
+		},
+		{
+			name: "nested failure that is returned rolls back everything",
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				if err := exec(ctx, db, "INSERT a"); err != nil {
+					return err
+				}
+				return m.WithinTransaction(ctx, func(ctx context.Context) error {
+					return failure
+				})
+			},
+			wantErr: failure,
+			wantLog: []string{"BEGIN", "INSERT a", "SAVEPOINT", "ROLLBACK TO SAVEPOINT", "ROLLBACK"},
+		},
+		{
+			name: "runs at the requested isolation level",
+			opts: []TxOption{Isolation(sql.LevelSerializable)},
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				return exec(ctx, db, "INSERT a")
+			},
+			wantLog: []string{"BEGIN Serializable", "INSERT a", "COMMIT"},
+		},
+		{
+			name: "does not retry other errors",
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				return failure
+			},
+			wantErr: failure,
+			wantLog: []string{"BEGIN", "ROLLBACK"},
+		},
+		{
+			name: "gives up after repeated serialization failures",
+			opts: []TxOption{Isolation(sql.LevelSerializable)},
+			run: func(ctx context.Context, m TxManager, db *gorm.DB) error {
+				return serializationFailure
+			},
+			wantErr: serializationFailure,
+			wantLog: []string{"BEGIN Serializable", "ROLLBACK", "BEGIN Serializable", "ROLLBACK", "BEGIN Serializable", "ROLLBACK"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, db, r := newRecordingTxManager(t)
+
+			err := m.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				return tt.run(ctx, m, db)
+			}, tt.opts...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+			if got := r.entries(); !reflect.DeepEqual(got, tt.wantLog) {
+				t.Errorf("got statements %q, want %q", got, tt.wantLog)
+			}
+		})
+	}
+}
+
+func TestWithinTransactionRetriesSerializationFailures(t *testing.T) {
+	for _, code := range []string{"40001", "40P01"} {
+		t.Run(code, func(t *testing.T) {
+			m, db, r := newRecordingTxManager(t)
+
+			attempts := 0
+			err := m.WithinTransaction(context.Background(), func(ctx context.Context) error {
+				attempts++
+				if err := exec(ctx, db, "UPDATE a"); err != nil {
+					return err
+				}
+				if attempts == 1 {
+					return &pgconn.PgError{Code: code}
+				}
+				return nil
+			}, Isolation(sql.LevelSerializable))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if attempts != 2 {
+				t.Errorf("ran %d times, want 2", attempts)
+			}
+			want := []string{"BEGIN Serializable", "UPDATE a", "ROLLBACK", "BEGIN Serializable", "UPDATE a", "COMMIT"}
+			if got := r.entries(); !reflect.DeepEqual(got, want) {
+				t.Errorf("got statements %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWithinTransactionStopsRetryingWhenCancelled(t *testing.T) {
+	m, _, _ := newRecordingTxManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	attempts := 0
+	err := m.WithinTransaction(ctx, func(ctx context.Context) error {
+		attempts++
+		cancel()
+		return &pgconn.PgError{Code: "40001"}
+	})
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		t.Errorf("got %v, want the serialization failure", err)
+	}
+	if attempts != 1 {
+		t.Errorf("ran %d times after cancellation, want 1", attempts)
+	}
+}
+
+func TestConnOutsideTransaction(t *testing.T) {
+	_, db, r := newRecordingTxManager(t)
+
+	if err := exec(context.Background(), db, "INSERT a"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"INSERT a"}
+	if got := r.entries(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got statements %q, want %q", got, want)
+	}
+}
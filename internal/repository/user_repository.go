@@ -2,64 +2,188 @@ package repository
 
 import (
 	"context"
+	"strings"
+	"time"
 
 	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/query"
 )
 
+// User statuses accepted by UserFilter
+const (
+	UserStatusActive    = "active"
+	UserStatusSuspended = "suspended"
+	UserStatusDeleted   = "deleted"
+)
+
+// UserFilter narrows down a user search. Empty fields are ignored.
+type UserFilter struct {
+	Query  string
+	Role   string
+	Status string
+}
+
+// UserQuerySchema lists the fields users can be filtered and sorted by in the user listing
+var UserQuerySchema = &query.Schema{
+	Fields: map[string]query.Field{
+		"id":         {Column: "id", Type: query.Int, Operators: []query.Operator{query.Eq, query.Ne, query.Gt, query.Gte, query.Lt, query.Lte, query.In}, Sortable: true},
+		"email":      {Column: "email", Type: query.String, Operators: []query.Operator{query.Eq, query.Ne, query.Like, query.In}, Sortable: true},
+		"name":       {Column: "name", Type: query.String, Operators: []query.Operator{query.Eq, query.Ne, query.Like}, Sortable: true},
+		"role":       {Column: "role", Type: query.String, Operators: []query.Operator{query.Eq, query.Ne, query.In}, Sortable: true},
+		"created_at": {Column: "created_at", Type: query.Time, Operators: []query.Operator{query.Gt, query.Gte, query.Lt, query.Lte}, Sortable: true},
+	},
+	SearchColumns: []string{"email", "name"},
+}
+
+// UserCursor is the position of a user in the order of creation
+type UserCursor struct {
+	CreatedAt time.Time
+	ID        uint
+}
+
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByIDUnscoped(ctx context.Context, id uint) (*models.User, error)
+	GetByIDForUpdate(ctx context.Context, id uint) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
+	UpdatePasswordHash(ctx context.Context, id uint, hash string) error
 	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context, offset, limit int) ([]models.User, error)
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, id uint) error
+	List(ctx context.Context, q *query.Query, offset, limit int) ([]models.User, error)
+	ListByCursor(ctx context.Context, q *query.Query, cursor *UserCursor, backward bool, limit int) ([]models.User, error)
+	Count(ctx context.Context, q *query.Query) (int64, error)
+	Search(ctx context.Context, filter UserFilter, offset, limit int) ([]models.User, int64, error)
 }
 
 type UserRepositoryImpl struct {
-	db Repository
+	users Repository[models.User, uint]
 }
 
-func NewUserRepository(db Repository) UserRepository {
+func NewUserRepository(users Repository[models.User, uint]) UserRepository {
 	return &UserRepositoryImpl{
-		db: db,
+		users: users,
 	}
 }
 
 func (r *UserRepositoryImpl) Create(ctx context.Context, user *models.User) error {
-	return r.db.Create(user).Error
+	return r.users.Create(ctx, user)
 }
 
 func (r *UserRepositoryImpl) GetByID(ctx context.Context, id uint) (*models.User, error) {
-	var user models.User
-	err := r.db.First(&user, id).Error
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
+	return r.users.Get(ctx, id)
+}
+
+// GetByIDUnscoped fetches a user including soft-deleted ones
+func (r *UserRepositoryImpl) GetByIDUnscoped(ctx context.Context, id uint) (*models.User, error) {
+	return r.users.Get(ctx, id, IncludeDeleted())
+}
+
+// GetByIDForUpdate fetches a user and locks the row until the transaction ends
+func (r *UserRepositoryImpl) GetByIDForUpdate(ctx context.Context, id uint) (*models.User, error) {
+	return r.users.Get(ctx, id, ForUpdate())
 }
 
 func (r *UserRepositoryImpl) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	var user models.User
-	err := r.db.Where("email = ?", email).First(&user).Error
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
+	return r.users.First(ctx, Where("email = ?", email))
 }
 
 func (r *UserRepositoryImpl) Update(ctx context.Context, user *models.User) error {
-	return r.db.Save(user).Error
+	return r.users.Update(ctx, user)
+}
+
+// UpdatePasswordHash replaces the stored hash without touching other columns or updated_at
+func (r *UserRepositoryImpl) UpdatePasswordHash(ctx context.Context, id uint, hash string) error {
+	return r.users.UpdateColumns(ctx, id, map[string]interface{}{"password": hash})
 }
 
 func (r *UserRepositoryImpl) Delete(ctx context.Context, id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+	return r.users.Delete(ctx, id)
+}
+
+// Restore clears the soft-delete marker of a user
+func (r *UserRepositoryImpl) Restore(ctx context.Context, id uint) error {
+	return r.users.UpdateFields(ctx, id, map[string]interface{}{"deleted_at": nil}, IncludeDeleted())
+}
+
+// Purge permanently removes a user, whether soft-deleted or not
+func (r *UserRepositoryImpl) Purge(ctx context.Context, id uint) error {
+	return r.users.Delete(ctx, id, IncludeDeleted())
 }
 
-func (r *UserRepositoryImpl) List(ctx context.Context, offset, limit int) ([]models.User, error) {
-	var users []models.User
-	err := r.db.Offset(offset).Limit(limit).Find(&users).Error
+// List returns a page of the users matching q, in order of creation unless q sorts them
+func (r *UserRepositoryImpl) List(ctx context.Context, q *query.Query, offset, limit int) ([]models.User, error) {
+	return r.users.List(ctx, Matching(q), SortedBy(q, "created_at, id"), Page(offset, limit))
+}
+
+// ListByCursor returns up to limit users matching q in order of creation
+// following the cursor, or preceding it when backward is set. Without a cursor
+// it starts at the first user. The sorts of q are ignored.
+func (r *UserRepositoryImpl) ListByCursor(ctx context.Context, q *query.Query, cursor *UserCursor, backward bool, limit int) ([]models.User, error) {
+	specs := []Spec{OrderBy("created_at, id"), Page(0, limit), Matching(q)}
+	if backward {
+		specs[0] = OrderBy("created_at DESC, id DESC")
+	}
+	if cursor != nil {
+		if backward {
+			specs = append(specs, Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID))
+		} else {
+			specs = append(specs, Where("(created_at, id) > (?, ?)", cursor.CreatedAt, cursor.ID))
+		}
+	}
+
+	users, err := r.users.List(ctx, specs...)
 	if err != nil {
 		return nil, err
 	}
+	if backward {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
 	return users, nil
 }
+
+func (r *UserRepositoryImpl) Count(ctx context.Context, q *query.Query) (int64, error) {
+	return r.users.Count(ctx, Matching(q))
+}
+
+// Search returns a page of users matching the filter along with the total number of matches
+func (r *UserRepositoryImpl) Search(ctx context.Context, filter UserFilter, offset, limit int) ([]models.User, int64, error) {
+	var specs []Spec
+
+	switch filter.Status {
+	case UserStatusDeleted:
+		specs = append(specs, IncludeDeleted(), Where("deleted_at IS NOT NULL"))
+	case UserStatusSuspended:
+		specs = append(specs, Where("suspended_at IS NOT NULL"))
+	case UserStatusActive:
+		specs = append(specs, Where("suspended_at IS NULL"))
+	}
+
+	if filter.Query != "" {
+		like := likePattern(filter.Query)
+		specs = append(specs, Where("LOWER(email) LIKE ? OR LOWER(name) LIKE ?", like, like))
+	}
+	if filter.Role != "" {
+		specs = append(specs, Where("role = ?", filter.Role))
+	}
+
+	total, err := r.users.Count(ctx, specs...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	users, err := r.users.List(ctx, All(specs...), OrderBy("id"), Page(offset, limit))
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// escapeLike escapes the wildcard characters of a LIKE pattern
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,79 @@
+package saml
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Credentials are the key and certificate the service provider signs
+// AuthnRequests with and identity providers encrypt assertions to
+type Credentials struct {
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+}
+
+// LoadCredentials reads a PEM encoded RSA private key and certificate. It
+// returns nil when neither file is configured.
+func LoadCredentials(keyFile, certFile string) (*Credentials, error) {
+	if keyFile == "" && certFile == "" {
+		return nil, nil
+	}
+	if keyFile == "" || certFile == "" {
+		return nil, errors.New("both a key and a certificate are required")
+	}
+
+	keyBlock, err := readPEM(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	creds := &Credentials{}
+	switch keyBlock.Type {
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", keyFile, err)
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("%s: not an RSA key", keyFile)
+		}
+		creds.Key = rsaKey
+	case "RSA PRIVATE KEY":
+		if creds.Key, err = x509.ParsePKCS1PrivateKey(keyBlock.Bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", keyFile, err)
+		}
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", keyFile, keyBlock.Type)
+	}
+
+	certBlock, err := readPEM(certFile)
+	if err != nil {
+		return nil, err
+	}
+	if certBlock.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: unsupported PEM block %q", certFile, certBlock.Type)
+	}
+	if creds.Certificate, err = x509.ParseCertificate(certBlock.Bytes); err != nil {
+		return nil, fmt.Errorf("%s: %w", certFile, err)
+	}
+	if !creds.Key.PublicKey.Equal(creds.Certificate.PublicKey) {
+		return nil, fmt.Errorf("%s does not match the key in %s", certFile, keyFile)
+	}
+	return creds, nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM data found", path)
+	}
+	return block, nil
+}
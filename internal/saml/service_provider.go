@@ -0,0 +1,226 @@
+package saml
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+var ErrInvalidMetadata = errors.New("invalid identity provider metadata")
+
+const (
+	// httpTimeout bounds fetching metadata from an identity provider
+	httpTimeout = 10 * time.Second
+	// maxMetadataSize bounds metadata documents read from an identity provider
+	maxMetadataSize = 1 << 20
+	// maxResponseSize bounds the base64 encoded SAMLResponse accepted from the browser
+	maxResponseSize = 1 << 20
+)
+
+// Assertion holds the subject and attributes of a verified assertion
+type Assertion struct {
+	NameID       string
+	NameIDFormat string
+	Attributes   map[string][]string
+}
+
+// Transient reports whether the NameID changes on every login and so cannot identify the user
+func (a *Assertion) Transient() bool {
+	return a.NameIDFormat == string(gosaml.TransientNameIDFormat)
+}
+
+// Values returns the values of the attribute with the given name or friendly name
+func (a *Assertion) Values(name string) []string {
+	return a.Attributes[name]
+}
+
+// Value returns the first value of the attribute with the given name or friendly name
+func (a *Assertion) Value(name string) string {
+	if values := a.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ServiceProvider is the service provider side of one identity provider connection
+type ServiceProvider struct {
+	sp gosaml.ServiceProvider
+}
+
+// NewServiceProvider creates a service provider that is known to the identity
+// provider by its metadata URL and receives responses at acsURL. creds may be
+// nil, in which case requests are not signed and assertions cannot be encrypted.
+func NewServiceProvider(metadataURL, acsURL string, idpMetadata []byte, creds *Credentials) (*ServiceProvider, error) {
+	descriptor, err := ParseMetadata(idpMetadata)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := url.Parse(metadataURL)
+	if err != nil {
+		return nil, err
+	}
+	acs, err := url.Parse(acsURL)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &ServiceProvider{sp: gosaml.ServiceProvider{
+		EntityID:          metadataURL,
+		MetadataURL:       *metadata,
+		AcsURL:            *acs,
+		IDPMetadata:       descriptor,
+		AuthnNameIDFormat: gosaml.UnspecifiedNameIDFormat,
+	}}
+	if creds != nil {
+		p.sp.Key = creds.Key
+		p.sp.Certificate = creds.Certificate
+		p.sp.SignatureMethod = dsig.RSASHA256SignatureMethod
+	}
+	return p, nil
+}
+
+// Metadata returns the service provider metadata to register at the identity provider
+func (p *ServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// AuthnRequestURL returns the URL that sends the user to the identity provider
+// with an AuthnRequest over the HTTP-Redirect binding, along with the request ID
+// the response must answer
+func (p *ServiceProvider) AuthnRequestURL(relayState string) (string, string, error) {
+	location := p.sp.GetSSOBindingLocation(gosaml.HTTPRedirectBinding)
+	if location == "" {
+		return "", "", fmt.Errorf("identity provider %s has no HTTP-Redirect single sign-on service", p.sp.IDPMetadata.EntityID)
+	}
+
+	req, err := p.sp.MakeAuthenticationRequest(location, gosaml.HTTPRedirectBinding, gosaml.HTTPPostBinding)
+	if err != nil {
+		return "", "", err
+	}
+	redirect, err := req.Redirect(url.QueryEscape(relayState), &p.sp)
+	if err != nil {
+		return "", "", err
+	}
+	return redirect.String(), req.ID, nil
+}
+
+// ParseResponse verifies a base64 encoded SAMLResponse posted to the ACS URL.
+// The response must be signed by the identity provider, addressed to this
+// service provider, current, and answer the AuthnRequest with requestID.
+func (p *ServiceProvider) ParseResponse(samlResponse, requestID string) (*Assertion, error) {
+	if len(samlResponse) > maxResponseSize {
+		return nil, errors.New("SAMLResponse is too large")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("SAMLResponse is not base64: %w", err)
+	}
+
+	assertion, err := p.sp.ParseXMLResponse(decoded, []string{requestID}, p.sp.AcsURL)
+	if err != nil {
+		// The library hides the reason behind a generic message
+		var invalid *gosaml.InvalidResponseError
+		if errors.As(err, &invalid) && invalid.PrivateErr != nil {
+			return nil, invalid.PrivateErr
+		}
+		return nil, err
+	}
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, errors.New("assertion has no NameID")
+	}
+
+	result := &Assertion{
+		NameID:       assertion.Subject.NameID.Value,
+		NameIDFormat: assertion.Subject.NameID.Format,
+		Attributes:   make(map[string][]string),
+	}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			var values []string
+			for _, value := range attribute.Values {
+				values = append(values, strings.TrimSpace(value.Value))
+			}
+			for _, name := range []string{attribute.Name, attribute.FriendlyName} {
+				if name != "" {
+					result.Attributes[name] = append(result.Attributes[name], values...)
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// ParseMetadata parses identity provider metadata. A document holding several
+// entities is accepted when exactly one of them is an identity provider.
+func ParseMetadata(data []byte) (*gosaml.EntityDescriptor, error) {
+	var descriptor gosaml.EntityDescriptor
+	if err := xml.Unmarshal(data, &descriptor); err != nil {
+		var entities gosaml.EntitiesDescriptor
+		if xml.Unmarshal(data, &entities) != nil {
+			return nil, ErrInvalidMetadata
+		}
+
+		found := 0
+		for _, entity := range entities.EntityDescriptors {
+			if len(entity.IDPSSODescriptors) > 0 {
+				descriptor = entity
+				found++
+			}
+		}
+		if found != 1 {
+			return nil, ErrInvalidMetadata
+		}
+	}
+
+	if descriptor.EntityID == "" || len(descriptor.IDPSSODescriptors) == 0 {
+		return nil, ErrInvalidMetadata
+	}
+	// Assertions are only accepted with a signature from one of these certificates
+	for _, idp := range descriptor.IDPSSODescriptors {
+		for _, key := range idp.KeyDescriptors {
+			if (key.Use == "" || key.Use == "signing") && len(key.KeyInfo.X509Data.X509Certificates) > 0 {
+				return &descriptor, nil
+			}
+		}
+	}
+	return nil, ErrInvalidMetadata
+}
+
+// FetchMetadata downloads identity provider metadata from its metadata URL
+func FetchMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, metadataURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{Timeout: httpTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetching metadata failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching metadata failed with status %d", resp.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxMetadataSize))
+	if err != nil {
+		return nil, fmt.Errorf("reading metadata failed: %w", err)
+	}
+	return bytes.TrimSpace(data), nil
+}
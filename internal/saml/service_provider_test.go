@@ -0,0 +1,192 @@
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/xml"
+	"math/big"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+)
+
+const (
+	testSPMetadataURL = "https://sp.example.com/saml/metadata"
+	testSPACSURL      = "https://sp.example.com/saml/acs"
+)
+
+// testIdP is an identity provider with a freshly generated key and certificate
+type testIdP struct {
+	idp gosaml.IdentityProvider
+}
+
+func newTestIdP(t *testing.T, entityID string) *testIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: entityID},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	metadataURL, _ := url.Parse(entityID)
+	ssoURL, _ := url.Parse(entityID + "/sso")
+	return &testIdP{idp: gosaml.IdentityProvider{
+		Key:             key,
+		Certificate:     cert,
+		MetadataURL:     *metadataURL,
+		SSOURL:          *ssoURL,
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}}
+}
+
+func (p *testIdP) metadata(t *testing.T) []byte {
+	t.Helper()
+	data, err := xml.Marshal(p.idp.Metadata())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// responseOptions change a response before it is signed
+type responseOptions struct {
+	unsigned bool
+	audience string
+	issuedAt time.Time
+}
+
+// response returns a base64 encoded response asserting nameID to the service
+// provider, answering the AuthnRequest with requestID
+func (p *testIdP) response(t *testing.T, sp *ServiceProvider, requestID, nameID string, opts responseOptions) string {
+	t.Helper()
+	now := opts.issuedAt
+	if now.IsZero() {
+		now = gosaml.TimeNow()
+	}
+
+	spMetadata := sp.sp.Metadata()
+	descriptor := &spMetadata.SPSSODescriptors[0]
+	var acs *gosaml.IndexedEndpoint
+	for i, endpoint := range descriptor.AssertionConsumerServices {
+		if endpoint.Binding == gosaml.HTTPPostBinding {
+			acs = &descriptor.AssertionConsumerServices[i]
+		}
+	}
+
+	req := &gosaml.IdpAuthnRequest{
+		IDP:                     &p.idp,
+		HTTPRequest:             httptest.NewRequest("POST", testSPACSURL, nil),
+		Request:                 gosaml.AuthnRequest{ID: requestID, IssueInstant: now},
+		ServiceProviderMetadata: spMetadata,
+		SPSSODescriptor:         descriptor,
+		ACSEndpoint:             acs,
+		Now:                     now,
+	}
+	session := &gosaml.Session{
+		ID:           "session",
+		CreateTime:   now,
+		NameID:       nameID,
+		NameIDFormat: string(gosaml.EmailAddressNameIDFormat),
+		UserEmail:    nameID,
+	}
+	if err := (gosaml.DefaultAssertionMaker{}).MakeAssertion(req, session); err != nil {
+		t.Fatal(err)
+	}
+	if opts.audience != "" {
+		req.Assertion.Conditions.AudienceRestrictions[0].Audience.Value = opts.audience
+	}
+
+	if opts.unsigned {
+		data, err := xml.Marshal(&gosaml.Response{
+			ID:           "id-unsigned",
+			InResponseTo: requestID,
+			Version:      "2.0",
+			IssueInstant: now,
+			Destination:  acs.Location,
+			Issuer:       &gosaml.Issuer{Value: p.idp.MetadataURL.String()},
+			Status:       gosaml.Status{StatusCode: gosaml.StatusCode{Value: gosaml.StatusSuccess}},
+			Assertion:    req.Assertion,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.StdEncoding.EncodeToString(data)
+	}
+
+	form, err := req.PostBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.SAMLResponse
+}
+
+func TestParseResponse(t *testing.T) {
+	idp := newTestIdP(t, "https://idp.example.com")
+	other := newTestIdP(t, "https://idp.example.com")
+
+	sp, err := NewServiceProvider(testSPMetadataURL, testSPACSURL, idp.metadata(t), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		issuer    *testIdP
+		requestID string
+		opts      responseOptions
+		wantErr   string
+	}{
+		{name: "signed response", issuer: idp, requestID: "id-1"},
+		{name: "unsigned response", issuer: idp, requestID: "id-1", opts: responseOptions{unsigned: true}, wantErr: "signature"},
+		{name: "signed by another key", issuer: other, requestID: "id-1", wantErr: "certificate"},
+		{name: "wrong audience", issuer: idp, requestID: "id-1", opts: responseOptions{audience: "https://other.example.com/saml/metadata"}, wantErr: "AudienceRestriction"},
+		{name: "expired response", issuer: idp, requestID: "id-1", opts: responseOptions{issuedAt: time.Now().Add(-time.Hour)}, wantErr: "expired"},
+		// A response captured from an earlier login answers that login's request
+		{name: "replayed response", issuer: idp, requestID: "id-0", wantErr: "InResponseTo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := tt.issuer.response(t, sp, tt.requestID, "user@example.com", tt.opts)
+
+			// Every response is checked against the AuthnRequest of the current login
+			assertion, err := sp.ParseResponse(response, "id-1")
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if assertion.NameID != "user@example.com" || assertion.Value("mail") != "user@example.com" {
+					t.Errorf("got NameID %q and mail %q", assertion.NameID, assertion.Value("mail"))
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("response was accepted")
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("got error %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid or expired API key")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrInvalidScope   = errors.New("unknown scope")
+	ErrInvalidExpiry  = errors.New("expiry must be in the future")
+)
+
+const (
+	// apiKeyPrefix marks API keys so they are easy to recognise, e.g. by secret scanners
+	apiKeyPrefix = "gpl_"
+	// apiKeyLookupBytes is the size of the random lookup part of the prefix
+	apiKeyLookupBytes = 6
+	// lastUsedResolution limits how often last-used timestamps are written
+	lastUsedResolution = time.Minute
+)
+
+type APIKeyService interface {
+	Create(ctx context.Context, userID uint, req *models.CreateAPIKeyRequest) (*models.APIKeyCreated, error)
+	List(ctx context.Context, userID uint) ([]models.APIKeyResponse, error)
+	Revoke(ctx context.Context, userID, id uint) error
+	Authenticate(ctx context.Context, key string) (*utils.JWTClaims, error)
+}
+
+type APIKeyServiceImpl struct {
+	repo        repository.APIKeyRepository
+	userRepo    repository.UserRepository
+	rbacService RBACService
+	config      *config.Config
+}
+
+func NewAPIKeyService(repo repository.APIKeyRepository, userRepo repository.UserRepository, rbacService RBACService, config *config.Config) APIKeyService {
+	return &APIKeyServiceImpl{
+		repo:        repo,
+		userRepo:    userRepo,
+		rbacService: rbacService,
+		config:      config,
+	}
+}
+
+// Create generates a new key for the user. Scopes are permission names; they
+// only narrow what the key can do and never grant more than the user's role.
+func (s *APIKeyServiceImpl) Create(ctx context.Context, userID uint, req *models.CreateAPIKeyRequest) (*models.APIKeyCreated, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	scopes := uniqueStrings(req.Scopes)
+	if err := validateScopes(ctx, s.rbacService, scopes); err != nil {
+		return nil, err
+	}
+
+	lookup := make([]byte, apiKeyLookupBytes)
+	if _, err := rand.Read(lookup); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+	prefix := apiKeyPrefix + hex.EncodeToString(lookup)
+	key := prefix + "_" + secret
+
+	record := &models.APIKey{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   utils.HashToken(key),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.repo.Create(ctx, record); err != nil {
+		return nil, err
+	}
+
+	return &models.APIKeyCreated{
+		APIKeyResponse: newAPIKeyResponse(record),
+		Key:            key,
+	}, nil
+}
+
+// List returns the user's keys that have not been revoked
+func (s *APIKeyServiceImpl) List(ctx context.Context, userID uint) ([]models.APIKeyResponse, error) {
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = newAPIKeyResponse(&keys[i])
+	}
+	return responses, nil
+}
+
+// Revoke revokes one of the user's keys
+func (s *APIKeyServiceImpl) Revoke(ctx context.Context, userID, id uint) error {
+	revoked, err := s.repo.Revoke(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
+
+// Authenticate checks an API key and returns claims equivalent to an access
+// token of its owner, restricted to the key's scopes
+func (s *APIKeyServiceImpl) Authenticate(ctx context.Context, key string) (*utils.JWTClaims, error) {
+	rest, ok := strings.CutPrefix(key, apiKeyPrefix)
+	lookupLen := hex.EncodedLen(apiKeyLookupBytes)
+	if !ok || len(rest) <= lookupLen || rest[lookupLen] != '_' {
+		return nil, ErrInvalidAPIKey
+	}
+
+	record, err := s.repo.GetByPrefix(ctx, apiKeyPrefix+rest[:lookupLen])
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if subtle.ConstantTimeCompare([]byte(record.KeyHash), []byte(utils.HashToken(key))) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if !record.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil || user.SuspendedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+	if s.config.RequireEmailVerification && !user.EmailVerified() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if record.LastUsedAt == nil || now.Sub(*record.LastUsedAt) >= lastUsedResolution {
+		if err := s.repo.TouchLastUsed(ctx, record.ID, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return &utils.JWTClaims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		Scopes:   record.ScopeList(),
+		APIKeyID: record.ID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:  s.config.JWTIssuer,
+			Subject: strconv.FormatUint(uint64(user.ID), 10),
+		},
+	}, nil
+}
+
+func newAPIKeyResponse(key *models.APIKey) models.APIKeyResponse {
+	return models.APIKeyResponse{
+		ID:         key.ID,
+		CreatedAt:  key.CreatedAt,
+		Name:       key.Name,
+		Prefix:     key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+	}
+}
@@ -0,0 +1,42 @@
+package services
+
+import (
+	"context"
+	"log"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, entry *models.AuditLog)
+	RecordRequired(ctx context.Context, entry *models.AuditLog) error
+	List(ctx context.Context, filter repository.AuditLogFilter, offset, limit int) ([]models.AuditLog, error)
+}
+
+type AuditServiceImpl struct {
+	repo repository.AuditLogRepository
+}
+
+func NewAuditService(repo repository.AuditLogRepository) AuditService {
+	return &AuditServiceImpl{
+		repo: repo,
+	}
+}
+
+// Record stores an audit log entry. Failing to audit must not fail the audited operation.
+func (s *AuditServiceImpl) Record(ctx context.Context, entry *models.AuditLog) {
+	if err := s.repo.Create(ctx, entry); err != nil {
+		log.Printf("Failed to record audit event %s: %v", entry.Action, err)
+	}
+}
+
+// RecordRequired stores an audit log entry and returns the error when it cannot,
+// for operations that must not happen without a trace
+func (s *AuditServiceImpl) RecordRequired(ctx context.Context, entry *models.AuditLog) error {
+	return s.repo.Create(ctx, entry)
+}
+
+func (s *AuditServiceImpl) List(ctx context.Context, filter repository.AuditLogFilter, offset, limit int) ([]models.AuditLog, error) {
+	return s.repo.List(ctx, filter, offset, limit)
+}
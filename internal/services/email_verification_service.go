@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/mailer"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrInvalidVerificationToken = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified         = errors.New("email address is not verified")
+	ErrVerificationThrottled    = errors.New("verification email was sent recently")
+)
+
+type EmailVerificationService interface {
+	SendVerification(ctx context.Context, user *models.User) error
+	SendEmailChange(ctx context.Context, user *models.User) error
+	Verify(ctx context.Context, token string) error
+	Resend(ctx context.Context, email string) (time.Duration, error)
+}
+
+type EmailVerificationServiceImpl struct {
+	userRepo  repository.UserRepository
+	tokenRepo repository.OneTimeTokenRepository
+	mailer    mailer.Mailer
+	redis     *redis.Client
+	config    *config.Config
+}
+
+func NewEmailVerificationService(userRepo repository.UserRepository, tokenRepo repository.OneTimeTokenRepository, mailer mailer.Mailer, redis *redis.Client, config *config.Config) EmailVerificationService {
+	return &EmailVerificationServiceImpl{
+		userRepo:  userRepo,
+		tokenRepo: tokenRepo,
+		mailer:    mailer,
+		redis:     redis,
+		config:    config,
+	}
+}
+
+// SendVerification emails a verification link for the user's current address
+func (s *EmailVerificationServiceImpl) SendVerification(ctx context.Context, user *models.User) error {
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, user.ID, models.TokenPurposeEmailVerification, s.config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.config.AppBaseURL, url.QueryEscape(token))
+	sendMailAsync(s.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your email address by opening the link below. It expires in %s.\n\n%s\n\nIf you did not create an account you can ignore this email.\n",
+			user.Name, s.config.EmailVerificationTTL, link),
+	})
+
+	return nil
+}
+
+// SendEmailChange emails a confirmation link for the user's pending address
+// and tells the current address about the change. Only the most recent link
+// is valid, so it always confirms the last address asked for.
+func (s *EmailVerificationServiceImpl) SendEmailChange(ctx context.Context, user *models.User) error {
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, user.ID, models.TokenPurposeEmailChange, s.config.EmailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/verify-email?token=%s", s.config.AppBaseURL, url.QueryEscape(token))
+	sendMailAsync(s.mailer, mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm your new email address by opening the link below. It expires in %s. Until then you keep signing in with your current address.\n\n%s\n\nIf you did not ask for this change you can ignore this email.\n",
+			user.Name, s.config.EmailVerificationTTL, link),
+	})
+	sendMailAsync(s.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to change the email address of your account to %s. The change takes effect once the new address is confirmed.\n\nIf this was not you, change your password and contact support.\n",
+			user.Name, user.PendingEmail),
+	})
+
+	return nil
+}
+
+// Verify consumes a verification token and marks the email address as
+// verified. A token from an email change switches the account to the new
+// address, unless another account has taken it meanwhile.
+func (s *EmailVerificationServiceImpl) Verify(ctx context.Context, token string) error {
+	record, ok, err := consumeOneTimeToken(ctx, s.tokenRepo, models.TokenPurposeEmailVerification, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return s.confirmEmailChange(ctx, token)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+	if user.EmailVerified() {
+		return nil
+	}
+
+	now := time.Now()
+	user.EmailVerifiedAt = &now
+	return s.userRepo.Update(ctx, user)
+}
+
+// confirmEmailChange consumes an email change token and moves the account to
+// its pending address
+func (s *EmailVerificationServiceImpl) confirmEmailChange(ctx context.Context, token string) error {
+	record, ok, err := consumeOneTimeToken(ctx, s.tokenRepo, models.TokenPurposeEmailChange, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidVerificationToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, record.UserID)
+	if err != nil || user.PendingEmail == "" {
+		return ErrInvalidVerificationToken
+	}
+	if _, err := s.userRepo.GetByEmail(ctx, user.PendingEmail); err == nil {
+		return ErrEmailExists
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+
+	now := time.Now()
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	user.EmailVerifiedAt = &now
+	err = s.userRepo.Update(ctx, user)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrEmailExists
+	}
+	if err != nil {
+		return err
+	}
+
+	// Invalidate cache
+	s.redis.Del(ctx, fmt.Sprintf("user:%d", user.ID))
+	return nil
+}
+
+// Resend sends a new verification link, at most once per resend interval for each
+// address. The result does not depend on whether the account exists. When throttled
+// it returns ErrVerificationThrottled and how long to wait.
+func (s *EmailVerificationServiceImpl) Resend(ctx context.Context, email string) (time.Duration, error) {
+	interval := s.config.EmailVerificationResendInterval
+	key := "email:verify:resend:" + utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+
+	fresh, err := s.redis.SetNX(ctx, key, 1, interval).Result()
+	if err != nil {
+		return 0, err
+	}
+	if !fresh {
+		ttl, err := s.redis.TTL(ctx, key).Result()
+		if err != nil || ttl <= 0 {
+			ttl = interval
+		}
+		return ttl, ErrVerificationThrottled
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil || user.EmailVerified() {
+		return 0, nil
+	}
+	return 0, s.SendVerification(ctx, user)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrAccountLocked        = errors.New("account temporarily locked")
+	ErrTooManyLoginAttempts = errors.New("too many failed login attempts")
+)
+
+// lockoutMemory is how long past lockouts count towards the back-off
+const lockoutMemory = 24 * time.Hour
+
+// LockoutError is returned while logins are blocked. Err is ErrAccountLocked
+// or ErrTooManyLoginAttempts.
+type LockoutError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LockoutError) Unwrap() error {
+	return e.Err
+}
+
+// LoginGuard limits failed login attempts per account and per client IP.
+// Accounts are keyed by email so unknown addresses are throttled the same way
+// as registered ones. Wrong two-factor codes are counted per user.
+type LoginGuard interface {
+	Check(ctx context.Context, email, ip string) error
+	RecordFailure(ctx context.Context, email, ip string, userID *uint)
+	RecordSuccess(ctx context.Context, email string)
+	RecordMFAFailure(ctx context.Context, user *models.User, ip string)
+	RecordMFASuccess(ctx context.Context, user *models.User)
+	Unlock(ctx context.Context, user *models.User) error
+}
+
+type LoginGuardImpl struct {
+	auditService AuditService
+	redis        *redis.Client
+	config       *config.Config
+}
+
+func NewLoginGuard(auditService AuditService, redis *redis.Client, config *config.Config) LoginGuard {
+	return &LoginGuardImpl{
+		auditService: auditService,
+		redis:        redis,
+		config:       config,
+	}
+}
+
+// Check returns a *LockoutError while the account or the IP is locked
+func (g *LoginGuardImpl) Check(ctx context.Context, email, ip string) error {
+	account := accountSubject(email)
+	if ttl := g.lockTTL(ctx, account); ttl > 0 {
+		return &LockoutError{Err: ErrAccountLocked, RetryAfter: ttl}
+	}
+	if ttl := g.lockTTL(ctx, ipSubject(ip)); ttl > 0 {
+		return &LockoutError{Err: ErrTooManyLoginAttempts, RetryAfter: ttl}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed attempt and locks the account or the IP once
+// its limit is reached. Every further lockout doubles the lock duration.
+func (g *LoginGuardImpl) RecordFailure(ctx context.Context, email, ip string, userID *uint) {
+	if d := g.fail(ctx, accountSubject(email), g.config.LoginMaxAccountFailures); d > 0 {
+		g.auditService.Record(ctx, &models.AuditLog{
+			Action:  models.AuditLoginLockout,
+			UserID:  userID,
+			IP:      ip,
+			Details: fmt.Sprintf("locked for %s after %d failed attempts", d, g.config.LoginMaxAccountFailures),
+		})
+	}
+	if d := g.fail(ctx, ipSubject(ip), g.config.LoginMaxIPFailures); d > 0 {
+		g.auditService.Record(ctx, &models.AuditLog{
+			Action:  models.AuditLoginIPLockout,
+			IP:      ip,
+			Details: fmt.Sprintf("locked for %s after %d failed attempts", d, g.config.LoginMaxIPFailures),
+		})
+	}
+}
+
+// RecordSuccess clears the failed attempts of the account. The back-off history is kept.
+func (g *LoginGuardImpl) RecordSuccess(ctx context.Context, email string) {
+	g.redis.Del(ctx, failuresKey(accountSubject(email)))
+}
+
+// RecordMFAFailure counts a wrong two-factor code and locks the account once
+// LoginMaxAccountFailures is reached, like failed passwords do. The count is
+// kept apart from password failures, so a correct password does not reset it.
+func (g *LoginGuardImpl) RecordMFAFailure(ctx context.Context, user *models.User, ip string) {
+	if !g.count(ctx, mfaSubject(user.ID), g.config.LoginMaxAccountFailures) {
+		return
+	}
+	if d := g.lock(ctx, accountSubject(user.Email)); d > 0 {
+		g.auditService.Record(ctx, &models.AuditLog{
+			Action:  models.AuditMFALockout,
+			UserID:  &user.ID,
+			IP:      ip,
+			Details: fmt.Sprintf("locked for %s after %d wrong two-factor codes", d, g.config.LoginMaxAccountFailures),
+		})
+	}
+}
+
+// RecordMFASuccess clears the wrong two-factor codes of the user
+func (g *LoginGuardImpl) RecordMFASuccess(ctx context.Context, user *models.User) {
+	g.redis.Del(ctx, failuresKey(mfaSubject(user.ID)))
+}
+
+// Unlock lifts a lockout and resets the back-off of the account
+func (g *LoginGuardImpl) Unlock(ctx context.Context, user *models.User) error {
+	subject := accountSubject(user.Email)
+	return g.redis.Del(ctx, failuresKey(subject), failuresKey(mfaSubject(user.ID)), lockKey(subject), lockoutsKey(subject)).Err()
+}
+
+// fail counts a failure of subject and locks it once limit is reached. It
+// returns the lock duration when this failure triggered a lockout.
+func (g *LoginGuardImpl) fail(ctx context.Context, subject string, limit int) time.Duration {
+	if !g.count(ctx, subject, limit) {
+		return 0
+	}
+	return g.lock(ctx, subject)
+}
+
+// count increments the failure counter of subject and reports whether it
+// reached limit, in which case the counter starts over
+func (g *LoginGuardImpl) count(ctx context.Context, subject string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+
+	key := failuresKey(subject)
+	failures, err := g.redis.Incr(ctx, key).Result()
+	if err != nil {
+		return false
+	}
+	if failures == 1 {
+		g.redis.Expire(ctx, key, g.config.LoginFailureWindow)
+	}
+	if failures < int64(limit) {
+		return false
+	}
+	g.redis.Del(ctx, key)
+	return true
+}
+
+// lock locks subject and returns the lock duration, which doubles with every
+// lockout of the subject
+func (g *LoginGuardImpl) lock(ctx context.Context, subject string) time.Duration {
+	lockouts, err := g.redis.Incr(ctx, lockoutsKey(subject)).Result()
+	if err != nil {
+		return 0
+	}
+	g.redis.Expire(ctx, lockoutsKey(subject), lockoutMemory)
+
+	d := g.lockoutDuration(lockouts)
+	g.redis.Set(ctx, lockKey(subject), 1, d)
+	return d
+}
+
+// lockoutDuration doubles the base duration for every previous lockout, up to the maximum
+func (g *LoginGuardImpl) lockoutDuration(lockouts int64) time.Duration {
+	d := g.config.LoginLockoutDuration
+	for i := int64(1); i < lockouts && d < g.config.LoginMaxLockoutDuration; i++ {
+		d *= 2
+	}
+	if d > g.config.LoginMaxLockoutDuration {
+		d = g.config.LoginMaxLockoutDuration
+	}
+	return d
+}
+
+func (g *LoginGuardImpl) lockTTL(ctx context.Context, subject string) time.Duration {
+	ttl, err := g.redis.PTTL(ctx, lockKey(subject)).Result()
+	if err != nil || ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// accountSubject hashes the normalized email so addresses are not stored in Redis
+func accountSubject(email string) string {
+	return "account:" + utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+func mfaSubject(userID uint) string {
+	return fmt.Sprintf("mfa:%d", userID)
+}
+
+func failuresKey(subject string) string {
+	return "login:failures:" + subject
+}
+
+func lockKey(subject string) string {
+	return "login:lock:" + subject
+}
+
+func lockoutsKey(subject string) string {
+	return "login:lockouts:" + subject
+}
@@ -0,0 +1,277 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrMFAAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+	ErrMFANotEnabled     = errors.New("two-factor authentication is not enabled")
+	ErrMFANotEnrolled    = errors.New("no pending two-factor enrollment")
+	ErrInvalidMFACode    = errors.New("invalid two-factor code")
+	ErrInvalidMFAToken   = errors.New("invalid or expired MFA token")
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes issued on enrollment
+	recoveryCodeCount = 10
+	// maxMFAAttempts is the number of wrong codes accepted per MFA token
+	maxMFAAttempts = 5
+	// totpReplayWindow covers every time step ValidateTOTP accepts
+	totpReplayWindow = 2 * time.Minute
+)
+
+var recoveryCodeEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+type MFAService interface {
+	BeginEnrollment(ctx context.Context, userID uint) (*models.TOTPEnrollment, error)
+	ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error)
+	Disable(ctx context.Context, userID uint, code string) error
+	CreateChallenge(ctx context.Context, user *models.User) (string, error)
+	VerifyChallenge(ctx context.Context, mfaToken, code string, client ClientInfo) (*models.TokenPair, error)
+}
+
+type MFAServiceImpl struct {
+	userRepo         repository.UserRepository
+	recoveryCodeRepo repository.RecoveryCodeRepository
+	tokenService     TokenService
+	loginGuard       LoginGuard
+	keys             *utils.KeyRing
+	redis            *redis.Client
+	config           *config.Config
+}
+
+func NewMFAService(userRepo repository.UserRepository, recoveryCodeRepo repository.RecoveryCodeRepository, tokenService TokenService, loginGuard LoginGuard, keys *utils.KeyRing, redis *redis.Client, config *config.Config) MFAService {
+	return &MFAServiceImpl{
+		userRepo:         userRepo,
+		recoveryCodeRepo: recoveryCodeRepo,
+		tokenService:     tokenService,
+		loginGuard:       loginGuard,
+		keys:             keys,
+		redis:            redis,
+		config:           config,
+	}
+}
+
+// BeginEnrollment generates a new TOTP secret for the user. The secret stays
+// inactive until it is confirmed with a first code.
+func (s *MFAServiceImpl) BeginEnrollment(ctx context.Context, userID uint) (*models.TOTPEnrollment, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := utils.Encrypt(secret, s.config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	user.TOTPSecret = encrypted
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return &models.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(secret, s.config.MFAIssuer, user.Email),
+	}, nil
+}
+
+// ConfirmEnrollment activates the pending secret and returns fresh recovery codes.
+// The codes are only ever returned here.
+func (s *MFAServiceImpl) ConfirmEnrollment(ctx context.Context, userID uint, code string) ([]string, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if user.MFAEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrMFANotEnrolled
+	}
+
+	if err := s.verifyTOTP(ctx, user, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user.TOTPEnabledAt = &now
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication after checking a TOTP or recovery code
+func (s *MFAServiceImpl) Disable(ctx context.Context, userID uint, code string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	if !user.MFAEnabled() {
+		return ErrMFANotEnabled
+	}
+
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		return err
+	}
+
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+	return s.recoveryCodeRepo.DeleteByUser(ctx, user.ID)
+}
+
+// CreateChallenge issues the MFA token returned by the password step of a login
+func (s *MFAServiceImpl) CreateChallenge(ctx context.Context, user *models.User) (string, error) {
+	return utils.GenerateMFAToken(user, s.config, s.keys)
+}
+
+// VerifyChallenge exchanges an MFA token and a TOTP or recovery code for
+// access and refresh tokens. Each MFA token can be exchanged once and allows
+// a limited number of wrong codes. Wrong codes also count towards locking the
+// account, and a locked account gets a *LockoutError.
+func (s *MFAServiceImpl) VerifyChallenge(ctx context.Context, mfaToken, code string, client ClientInfo) (*models.TokenPair, error) {
+	claims, err := utils.ValidateToken(mfaToken, s.config, s.keys)
+	if err != nil || claims.Purpose != utils.PurposeMFAPending || claims.ExpiresAt == nil {
+		return nil, ErrInvalidMFAToken
+	}
+	ttl := time.Until(claims.ExpiresAt.Time)
+
+	attemptsKey := fmt.Sprintf("mfa:challenge:%s:attempts", claims.ID)
+	attempts, err := s.redis.Incr(ctx, attemptsKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	s.redis.Expire(ctx, attemptsKey, ttl)
+	if attempts > maxMFAAttempts {
+		return nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, claims.UserID)
+	if err != nil || !user.MFAEnabled() || user.SuspendedAt != nil {
+		return nil, ErrInvalidMFAToken
+	}
+	if err := s.loginGuard.Check(ctx, user.Email, client.IP); err != nil {
+		return nil, err
+	}
+
+	if err := s.verifyCode(ctx, user, code); err != nil {
+		if err == ErrInvalidMFACode {
+			s.loginGuard.RecordMFAFailure(ctx, user, client.IP)
+		}
+		return nil, err
+	}
+	s.loginGuard.RecordMFASuccess(ctx, user)
+
+	// Consume the MFA token
+	fresh, err := s.redis.SetNX(ctx, fmt.Sprintf("mfa:challenge:%s:used", claims.ID), 1, ttl).Result()
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrInvalidMFAToken
+	}
+
+	return s.tokenService.IssueTokenPair(ctx, user, client)
+}
+
+// verifyCode accepts either a 6-digit TOTP code or an unused recovery code
+func (s *MFAServiceImpl) verifyCode(ctx context.Context, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == 6 {
+		return s.verifyTOTP(ctx, user, code)
+	}
+
+	stored, err := s.recoveryCodeRepo.GetUnusedByHash(ctx, user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return ErrInvalidMFACode
+	}
+
+	used, err := s.recoveryCodeRepo.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// verifyTOTP checks a TOTP code and rejects codes that were already used
+func (s *MFAServiceImpl) verifyTOTP(ctx context.Context, user *models.User, code string) error {
+	secret, err := utils.Decrypt(user.TOTPSecret, s.config.EncryptionKey)
+	if err != nil {
+		return err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	fresh, err := s.redis.SetNX(ctx, fmt.Sprintf("mfa:totp:%d:%d", user.ID, step), 1, totpReplayWindow).Result()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes and stores their hashes
+func (s *MFAServiceImpl) replaceRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.RecoveryCode, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		raw := strings.ToLower(recoveryCodeEncoding.EncodeToString(b))
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		records[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(raw)}
+	}
+
+	if err := s.recoveryCodeRepo.ReplaceForUser(ctx, userID, records); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode strips separators so codes can be typed in any case and grouping
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
@@ -0,0 +1,705 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/oidc"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrOAuthClientNotFound = errors.New("OAuth2 client not found")
+	ErrConsentNotFound     = errors.New("consent not found")
+	ErrRedirectURIRequired = errors.New("authorization_code clients need at least one redirect URI")
+	ErrInvalidRedirectURI  = errors.New("redirect URIs must be absolute and must not contain a fragment")
+	ErrInvalidGrantTypes   = errors.New("client_credentials needs a confidential client and refresh_token needs authorization_code")
+)
+
+// Error codes of the OAuth2 specifications (RFC 6749 sections 4.1.2.1 and 5.2)
+const (
+	OAuthInvalidRequest          = "invalid_request"
+	OAuthInvalidClient           = "invalid_client"
+	OAuthInvalidGrant            = "invalid_grant"
+	OAuthUnauthorizedClient      = "unauthorized_client"
+	OAuthUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthUnsupportedResponseType = "unsupported_response_type"
+	OAuthInvalidScope            = "invalid_scope"
+	OAuthAccessDenied            = "access_denied"
+)
+
+const (
+	// codeReplayWindow is how long a redeemed authorization code is remembered,
+	// so that presenting it again revokes the tokens issued for it
+	codeReplayWindow = 24 * time.Hour
+	// Code verifier length limits from RFC 7636 section 4.1
+	minCodeVerifierLength = 43
+	maxCodeVerifierLength = 128
+)
+
+// OAuthError is an error response defined by the OAuth2 specifications
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+type OAuthService interface {
+	// Client registration
+	CreateClient(ctx context.Context, req *models.CreateOAuthClientRequest) (*models.OAuthClientCreated, error)
+	ListClients(ctx context.Context) ([]models.OAuthClientResponse, error)
+	DeleteClient(ctx context.Context, id uint) error
+
+	// Authorization and consent
+	PrepareAuthorization(ctx context.Context, userID uint, req *models.OAuthAuthorizeRequest) (*models.OAuthAuthorizePrompt, error)
+	Authorize(ctx context.Context, userID uint, decision *models.OAuthConsentDecision) (string, error)
+	ListConsents(ctx context.Context, userID uint) ([]models.OAuthConsentResponse, error)
+	RevokeConsent(ctx context.Context, userID, id uint) error
+
+	// Token endpoints
+	Token(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error)
+	Introspect(ctx context.Context, clientID, clientSecret, token, tokenTypeHint string) (*models.OAuthIntrospection, error)
+	Revoke(ctx context.Context, clientID, clientSecret, token string) error
+}
+
+type OAuthServiceImpl struct {
+	clientRepo   repository.OAuthClientRepository
+	consentRepo  repository.OAuthConsentRepository
+	refreshRepo  repository.RefreshTokenRepository
+	userRepo     repository.UserRepository
+	tokenService TokenService
+	rbacService  RBACService
+	keys         *utils.KeyRing
+	redis        *redis.Client
+	config       *config.Config
+}
+
+func NewOAuthService(clientRepo repository.OAuthClientRepository, consentRepo repository.OAuthConsentRepository, refreshRepo repository.RefreshTokenRepository, userRepo repository.UserRepository, tokenService TokenService, rbacService RBACService, keys *utils.KeyRing, redis *redis.Client, config *config.Config) OAuthService {
+	return &OAuthServiceImpl{
+		clientRepo:   clientRepo,
+		consentRepo:  consentRepo,
+		refreshRepo:  refreshRepo,
+		userRepo:     userRepo,
+		tokenService: tokenService,
+		rbacService:  rbacService,
+		keys:         keys,
+		redis:        redis,
+		config:       config,
+	}
+}
+
+// authorizationCode is kept in Redis until the client redeems the code
+type authorizationCode struct {
+	ClientID      uint     `json:"client_id"`
+	UserID        uint     `json:"user_id"`
+	RedirectURI   string   `json:"redirect_uri"`
+	Scopes        []string `json:"scopes"`
+	CodeChallenge string   `json:"code_challenge"`
+}
+
+// CreateClient registers a client. The secret of a confidential client is only returned here.
+func (s *OAuthServiceImpl) CreateClient(ctx context.Context, req *models.CreateOAuthClientRequest) (*models.OAuthClientCreated, error) {
+	grantTypes := uniqueStrings(req.GrantTypes)
+	redirectURIs := uniqueStrings(req.RedirectURIs)
+	scopes := uniqueStrings(req.Scopes)
+
+	client := &models.OAuthClient{
+		Name:         req.Name,
+		RedirectURIs: strings.Join(redirectURIs, " "),
+		GrantTypes:   strings.Join(grantTypes, " "),
+		Scopes:       strings.Join(scopes, " "),
+	}
+
+	for _, uri := range redirectURIs {
+		parsed, err := url.Parse(uri)
+		if err != nil || parsed.Scheme == "" || parsed.Host == "" || parsed.Fragment != "" || strings.ContainsAny(uri, " #") {
+			return nil, ErrInvalidRedirectURI
+		}
+	}
+	if client.AllowsGrant(models.GrantTypeAuthorizationCode) && len(redirectURIs) == 0 {
+		return nil, ErrRedirectURIRequired
+	}
+	if (client.AllowsGrant(models.GrantTypeClientCredentials) && !req.Confidential) ||
+		(client.AllowsGrant(models.GrantTypeRefreshToken) && !client.AllowsGrant(models.GrantTypeAuthorizationCode)) {
+		return nil, ErrInvalidGrantTypes
+	}
+	if err := validateScopes(ctx, s.rbacService, scopes); err != nil {
+		return nil, err
+	}
+
+	clientID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	client.ClientID = clientID
+
+	var secret string
+	if req.Confidential {
+		if secret, err = utils.GenerateRandomToken(32); err != nil {
+			return nil, err
+		}
+		client.SecretHash = utils.HashToken(secret)
+	}
+
+	if err := s.clientRepo.Create(ctx, client); err != nil {
+		return nil, err
+	}
+
+	return &models.OAuthClientCreated{
+		OAuthClientResponse: newOAuthClientResponse(client),
+		ClientSecret:        secret,
+	}, nil
+}
+
+func (s *OAuthServiceImpl) ListClients(ctx context.Context) ([]models.OAuthClientResponse, error) {
+	clients, err := s.clientRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.OAuthClientResponse, len(clients))
+	for i := range clients {
+		responses[i] = newOAuthClientResponse(&clients[i])
+	}
+	return responses, nil
+}
+
+// DeleteClient removes a client with its consents and refresh tokens. Access
+// tokens already issued to the client stay valid until they expire.
+func (s *OAuthServiceImpl) DeleteClient(ctx context.Context, id uint) error {
+	if _, err := s.clientRepo.GetByID(ctx, id); err != nil {
+		return ErrOAuthClientNotFound
+	}
+
+	if err := s.refreshRepo.RevokeForClient(ctx, id, nil); err != nil {
+		return err
+	}
+	if err := s.consentRepo.DeleteByClient(ctx, id); err != nil {
+		return err
+	}
+	deleted, err := s.clientRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrOAuthClientNotFound
+	}
+	return nil
+}
+
+// PrepareAuthorization validates an authorization request for the consent screen
+// and reports whether the user still has to approve the requested scopes
+func (s *OAuthServiceImpl) PrepareAuthorization(ctx context.Context, userID uint, req *models.OAuthAuthorizeRequest) (*models.OAuthAuthorizePrompt, error) {
+	client, scopes, err := s.validateAuthorization(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	consent, err := s.consentRepo.Get(ctx, userID, client.ID)
+	return &models.OAuthAuthorizePrompt{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		RedirectURI:     req.RedirectURI,
+		Scopes:          scopes,
+		ConsentRequired: err != nil || !consent.Covers(scopes),
+	}, nil
+}
+
+// Authorize records the user's decision and returns the URL to send the user back
+// to the client, carrying either an authorization code or an access_denied error
+func (s *OAuthServiceImpl) Authorize(ctx context.Context, userID uint, decision *models.OAuthConsentDecision) (string, error) {
+	req := &decision.OAuthAuthorizeRequest
+	client, scopes, err := s.validateAuthorization(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if !decision.Approve {
+		return redirectWithParams(req.RedirectURI, url.Values{"error": {OAuthAccessDenied}}, req.State), nil
+	}
+
+	consent, err := s.consentRepo.Get(ctx, userID, client.ID)
+	if err != nil {
+		consent = &models.OAuthConsent{UserID: userID, OAuthClientID: client.ID}
+	}
+	consent.Scopes = strings.Join(uniqueStrings(append(strings.Fields(consent.Scopes), scopes...)), " ")
+	if err := s.consentRepo.Save(ctx, consent); err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(authorizationCode{
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   req.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: req.CodeChallenge,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, authorizationCodeKey(code), data, s.config.OAuthCodeTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return redirectWithParams(req.RedirectURI, url.Values{"code": {code}}, req.State), nil
+}
+
+func (s *OAuthServiceImpl) ListConsents(ctx context.Context, userID uint) ([]models.OAuthConsentResponse, error) {
+	consents, err := s.consentRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.OAuthConsentResponse, 0, len(consents))
+	for _, consent := range consents {
+		client, err := s.clientRepo.GetByID(ctx, consent.OAuthClientID)
+		if err != nil {
+			continue
+		}
+		responses = append(responses, models.OAuthConsentResponse{
+			ID:         consent.ID,
+			CreatedAt:  consent.CreatedAt,
+			UpdatedAt:  consent.UpdatedAt,
+			ClientID:   client.ClientID,
+			ClientName: client.Name,
+			Scopes:     strings.Fields(consent.Scopes),
+		})
+	}
+	return responses, nil
+}
+
+// RevokeConsent withdraws a consent and revokes the refresh tokens the client holds for the user
+func (s *OAuthServiceImpl) RevokeConsent(ctx context.Context, userID, id uint) error {
+	consent, err := s.consentRepo.GetByID(ctx, id, userID)
+	if err != nil {
+		return ErrConsentNotFound
+	}
+
+	if err := s.refreshRepo.RevokeForClient(ctx, consent.OAuthClientID, &userID); err != nil {
+		return err
+	}
+	return s.consentRepo.Delete(ctx, consent.ID)
+}
+
+// Token implements the token endpoint for the authorization_code,
+// client_credentials and refresh_token grants
+func (s *OAuthServiceImpl) Token(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch req.GrantType {
+	case models.GrantTypeAuthorizationCode, models.GrantTypeClientCredentials, models.GrantTypeRefreshToken:
+	case "":
+		return nil, oauthError(OAuthInvalidRequest, "grant_type is required")
+	default:
+		return nil, oauthError(OAuthUnsupportedGrantType, "grant type is not supported")
+	}
+	if !client.AllowsGrant(req.GrantType) {
+		return nil, oauthError(OAuthUnauthorizedClient, "client may not use this grant type")
+	}
+
+	switch req.GrantType {
+	case models.GrantTypeAuthorizationCode:
+		return s.exchangeCode(ctx, client, req)
+	case models.GrantTypeRefreshToken:
+		return s.refresh(ctx, client, req)
+	default:
+		return s.clientCredentials(ctx, client, req)
+	}
+}
+
+// exchangeCode redeems an authorization code. Each code can be redeemed once;
+// presenting it again revokes the refresh tokens issued for it (RFC 6749 section 4.1.2).
+func (s *OAuthServiceImpl) exchangeCode(ctx context.Context, client *models.OAuthClient, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		return nil, oauthError(OAuthInvalidRequest, "code, redirect_uri and code_verifier are required")
+	}
+
+	key := authorizationCodeKey(req.Code)
+	data, err := s.redis.GetDel(ctx, key).Bytes()
+	if err == redis.Nil {
+		if familyID, err := s.redis.Get(ctx, key+":family").Result(); err == nil {
+			if err := s.refreshRepo.RevokeFamily(ctx, familyID); err != nil {
+				return nil, err
+			}
+		}
+		return nil, oauthError(OAuthInvalidGrant, "authorization code is invalid, expired or already used")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var code authorizationCode
+	if err := json.Unmarshal(data, &code); err != nil || code.ClientID != client.ID {
+		return nil, oauthError(OAuthInvalidGrant, "authorization code was issued to another client")
+	}
+	if code.RedirectURI != req.RedirectURI {
+		return nil, oauthError(OAuthInvalidGrant, "redirect_uri does not match the authorization request")
+	}
+	if len(req.CodeVerifier) < minCodeVerifierLength || len(req.CodeVerifier) > maxCodeVerifierLength ||
+		subtle.ConstantTimeCompare([]byte(oidc.CodeChallenge(req.CodeVerifier)), []byte(code.CodeChallenge)) != 1 {
+		return nil, oauthError(OAuthInvalidGrant, "code_verifier does not match the code challenge")
+	}
+
+	user, err := s.activeUser(ctx, code.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.redis.Set(ctx, key+":family", familyID, codeReplayWindow).Err(); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, client, user, code.Scopes, code.Scopes, familyID)
+}
+
+// refresh rotates a refresh token issued to the client. The scope parameter may
+// narrow the scopes of the new access token but never widen the original grant.
+func (s *OAuthServiceImpl) refresh(ctx context.Context, client *models.OAuthClient, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, oauthError(OAuthInvalidRequest, "refresh_token is required")
+	}
+
+	stored, err := s.refreshRepo.GetByHash(ctx, utils.HashToken(req.RefreshToken))
+	if err != nil || stored.OAuthClientID == nil || *stored.OAuthClientID != client.ID {
+		return nil, oauthError(OAuthInvalidGrant, "refresh token is invalid")
+	}
+
+	granted := strings.Fields(stored.Scopes)
+	scopes, err := narrowScopes(req.Scope, granted)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := rotateRefreshToken(ctx, s.refreshRepo, stored); err != nil {
+		if err == ErrInvalidRefreshToken || err == ErrRefreshTokenReused {
+			return nil, oauthError(OAuthInvalidGrant, err.Error())
+		}
+		return nil, err
+	}
+
+	user, err := s.activeUser(ctx, stored.UserID)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(ctx, client, user, scopes, granted, stored.FamilyID)
+}
+
+// clientCredentials issues an access token to the client itself. No refresh
+// token is issued since the client can always authenticate again.
+func (s *OAuthServiceImpl) clientCredentials(ctx context.Context, client *models.OAuthClient, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	scopes, err := narrowScopes(req.Scope, client.ScopeList())
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := utils.NewClientClaims(client.ClientID, scopes, s.config, s.config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// Introspect reports whether a token is active (RFC 7662). Only confidential
+// clients may introspect, and refresh tokens only show as active to their own client.
+func (s *OAuthServiceImpl) Introspect(ctx context.Context, clientID, clientSecret, token, tokenTypeHint string) (*models.OAuthIntrospection, error) {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.Confidential() {
+		return nil, oauthError(OAuthInvalidClient, "public clients cannot introspect tokens")
+	}
+
+	inactive := &models.OAuthIntrospection{Active: false}
+	if token == "" {
+		return inactive, nil
+	}
+
+	lookups := []func(context.Context, *models.OAuthClient, string) (*models.OAuthIntrospection, error){
+		s.introspectAccessToken, s.introspectRefreshToken,
+	}
+	if tokenTypeHint == "refresh_token" {
+		lookups[0], lookups[1] = lookups[1], lookups[0]
+	}
+	for _, lookup := range lookups {
+		result, err := lookup(ctx, client, token)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			return result, nil
+		}
+	}
+	return inactive, nil
+}
+
+func (s *OAuthServiceImpl) introspectAccessToken(ctx context.Context, client *models.OAuthClient, token string) (*models.OAuthIntrospection, error) {
+	claims, err := s.tokenService.ValidateAccessToken(ctx, token)
+	if err == ErrInvalidToken || err == ErrTokenRevoked {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.OAuthIntrospection{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes, " "),
+		ClientID:  claims.ClientID,
+		Username:  claims.Email,
+		TokenType: "Bearer",
+		Sub:       claims.Subject,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}
+	if claims.ExpiresAt != nil {
+		result.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		result.Iat = claims.IssuedAt.Unix()
+	}
+	return result, nil
+}
+
+func (s *OAuthServiceImpl) introspectRefreshToken(ctx context.Context, client *models.OAuthClient, token string) (*models.OAuthIntrospection, error) {
+	stored, err := s.refreshRepo.GetByHash(ctx, utils.HashToken(token))
+	if err != nil || stored.OAuthClientID == nil || *stored.OAuthClientID != client.ID {
+		return nil, nil
+	}
+	if stored.UsedAt != nil || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, nil
+	}
+
+	return &models.OAuthIntrospection{
+		Active:    true,
+		Scope:     stored.Scopes,
+		ClientID:  client.ClientID,
+		TokenType: "refresh_token",
+		Exp:       stored.ExpiresAt.Unix(),
+		Iat:       stored.CreatedAt.Unix(),
+		Sub:       strconv.FormatUint(uint64(stored.UserID), 10),
+		Iss:       s.config.JWTIssuer,
+	}, nil
+}
+
+// Revoke revokes a refresh or access token issued to the client (RFC 7009).
+// Unknown tokens and tokens of other clients are ignored, as the RFC requires.
+func (s *OAuthServiceImpl) Revoke(ctx context.Context, clientID, clientSecret, token string) error {
+	client, err := s.authenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.refreshRepo.GetByHash(ctx, utils.HashToken(token))
+	if err == nil {
+		if stored.OAuthClientID != nil && *stored.OAuthClientID == client.ID {
+			return s.refreshRepo.RevokeFamily(ctx, stored.FamilyID)
+		}
+		return nil
+	}
+
+	claims, err := s.tokenService.ValidateAccessToken(ctx, token)
+	if err == ErrInvalidToken || err == ErrTokenRevoked {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if claims.ClientID != client.ClientID {
+		return nil
+	}
+	return s.tokenService.RevokeAccessToken(ctx, claims)
+}
+
+// authenticateClient checks the client credentials. Confidential clients must
+// send their secret and public clients must not send one.
+func (s *OAuthServiceImpl) authenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	if clientID == "" {
+		return nil, oauthError(OAuthInvalidClient, "client authentication is required")
+	}
+
+	client, err := s.clientRepo.GetByClientID(ctx, clientID)
+	if err != nil {
+		return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+	}
+	if client.Confidential() {
+		if subtle.ConstantTimeCompare([]byte(utils.HashToken(clientSecret)), []byte(client.SecretHash)) != 1 {
+			return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+		}
+	} else if clientSecret != "" {
+		return nil, oauthError(OAuthInvalidClient, "client authentication failed")
+	}
+	return client, nil
+}
+
+// validateAuthorization checks an authorization request and returns the client
+// and the requested scopes. Without a scope parameter all scopes of the client are requested.
+func (s *OAuthServiceImpl) validateAuthorization(ctx context.Context, req *models.OAuthAuthorizeRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.clientRepo.GetByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, nil, oauthError(OAuthInvalidClient, "unknown client")
+	}
+	if !client.AllowsRedirectURI(req.RedirectURI) {
+		return nil, nil, oauthError(OAuthInvalidRequest, "redirect_uri is not registered for the client")
+	}
+	if req.ResponseType != "code" {
+		return nil, nil, oauthError(OAuthUnsupportedResponseType, "only the code response type is supported")
+	}
+	if !client.AllowsGrant(models.GrantTypeAuthorizationCode) {
+		return nil, nil, oauthError(OAuthUnauthorizedClient, "client may not use the authorization code grant")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != "S256" {
+		return nil, nil, oauthError(OAuthInvalidRequest, "PKCE with code_challenge_method S256 is required")
+	}
+
+	scopes, err := narrowScopes(req.Scope, client.ScopeList())
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, scopes, nil
+}
+
+// activeUser returns the user a grant was issued for if they may still sign in
+func (s *OAuthServiceImpl) activeUser(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || user.SuspendedAt != nil || (s.config.RequireEmailVerification && !user.EmailVerified()) {
+		return nil, oauthError(OAuthInvalidGrant, "user account is not available")
+	}
+	return user, nil
+}
+
+// issueTokens issues an access token with the given scopes and a refresh token for
+// the granted scopes, if the client may use refresh tokens
+func (s *OAuthServiceImpl) issueTokens(ctx context.Context, client *models.OAuthClient, user *models.User, scopes, granted []string, familyID string) (*models.OAuthTokenResponse, error) {
+	claims, err := utils.NewClaims(user, s.config, s.config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	claims.Scopes = scopes
+	claims.ClientID = client.ClientID
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}
+	if !client.AllowsGrant(models.GrantTypeRefreshToken) {
+		return response, nil
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+	clientID := client.ID
+	err = s.refreshRepo.Create(ctx, &models.RefreshToken{
+		UserID:        user.ID,
+		FamilyID:      familyID,
+		TokenHash:     utils.HashToken(refreshToken),
+		ExpiresAt:     time.Now().Add(s.config.RefreshTokenTTL),
+		OAuthClientID: &clientID,
+		Scopes:        strings.Join(granted, " "),
+	})
+	if err != nil {
+		return nil, err
+	}
+	response.RefreshToken = refreshToken
+	return response, nil
+}
+
+// narrowScopes parses a scope parameter that must stay within allowed.
+// An empty parameter requests all allowed scopes.
+func narrowScopes(scope string, allowed []string) ([]string, error) {
+	requested := uniqueStrings(strings.Fields(scope))
+	if len(requested) == 0 {
+		return allowed, nil
+	}
+
+	permitted := make(map[string]bool, len(allowed))
+	for _, s := range allowed {
+		permitted[s] = true
+	}
+	for _, s := range requested {
+		if !permitted[s] {
+			return nil, oauthError(OAuthInvalidScope, "scope "+s+" is not allowed")
+		}
+	}
+	return requested, nil
+}
+
+// redirectWithParams adds params and the state to a registered redirect URI
+func redirectWithParams(redirectURI string, params url.Values, state string) string {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+func newOAuthClientResponse(client *models.OAuthClient) models.OAuthClientResponse {
+	return models.OAuthClientResponse{
+		ID:           client.ID,
+		CreatedAt:    client.CreatedAt,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		Confidential: client.Confidential(),
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		GrantTypes:   strings.Fields(client.GrantTypes),
+		Scopes:       client.ScopeList(),
+	}
+}
+
+func authorizationCodeKey(code string) string {
+	return "oauth:code:" + utils.HashToken(code)
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/oidc"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrUnknownProvider      = errors.New("unknown identity provider")
+	ErrInvalidOIDCState     = errors.New("invalid or expired login state")
+	ErrOIDCLoginFailed      = errors.New("login with identity provider failed")
+	ErrOIDCEmailNotVerified = errors.New("identity provider did not confirm the email address")
+	ErrOIDCAccountExists    = errors.New("an account with this email already exists; sign in and verify your email before linking")
+	ErrIdentityNotFound     = errors.New("linked identity not found")
+)
+
+// oidcStateTTL is how long a user has to complete the login at the provider
+const oidcStateTTL = 10 * time.Minute
+
+type OIDCService interface {
+	Providers() []string
+	BeginLogin(ctx context.Context, provider string) (string, error)
+	CompleteLogin(ctx context.Context, provider, state, code string, client ClientInfo) (*models.LoginResponse, error)
+	ListIdentities(ctx context.Context, userID uint) ([]models.LinkedIdentity, error)
+	Unlink(ctx context.Context, userID, id uint) error
+}
+
+type OIDCServiceImpl struct {
+	registry     *oidc.Registry
+	identityRepo repository.LinkedIdentityRepository
+	userRepo     repository.UserRepository
+	userService  UserService
+	redis        *redis.Client
+	config       *config.Config
+}
+
+func NewOIDCService(registry *oidc.Registry, identityRepo repository.LinkedIdentityRepository, userRepo repository.UserRepository, userService UserService, redis *redis.Client, config *config.Config) OIDCService {
+	return &OIDCServiceImpl{
+		registry:     registry,
+		identityRepo: identityRepo,
+		userRepo:     userRepo,
+		userService:  userService,
+		redis:        redis,
+		config:       config,
+	}
+}
+
+// oidcLoginState is kept in Redis between the redirect to the provider and the callback
+type oidcLoginState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+func (s *OIDCServiceImpl) Providers() []string {
+	return s.registry.Names()
+}
+
+// BeginLogin starts an authorization code flow with PKCE and returns the URL to redirect the user to
+func (s *OIDCServiceImpl) BeginLogin(ctx context.Context, provider string) (string, error) {
+	p, ok := s.registry.Get(provider)
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	loginState := oidcLoginState{Provider: provider}
+	if loginState.Nonce, err = utils.GenerateRandomToken(32); err != nil {
+		return "", err
+	}
+	if loginState.CodeVerifier, err = utils.GenerateRandomToken(32); err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(loginState)
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, oidcStateKey(state), data, oidcStateTTL).Err(); err != nil {
+		return "", err
+	}
+
+	return p.AuthCodeURL(ctx, s.redirectURI(provider), state, loginState.Nonce, oidc.CodeChallenge(loginState.CodeVerifier))
+}
+
+// CompleteLogin handles the provider callback. It redeems the code, verifies the
+// ID token and signs in the linked user, linking or creating an account on first use.
+func (s *OIDCServiceImpl) CompleteLogin(ctx context.Context, provider, state, code string, client ClientInfo) (*models.LoginResponse, error) {
+	p, ok := s.registry.Get(provider)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	// Each state is single-use, which also rejects replayed callbacks
+	data, err := s.redis.GetDel(ctx, oidcStateKey(state)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidOIDCState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var loginState oidcLoginState
+	if err := json.Unmarshal(data, &loginState); err != nil || loginState.Provider != provider {
+		return nil, ErrInvalidOIDCState
+	}
+
+	// Details of provider failures are logged rather than returned to the client
+	rawIDToken, err := p.Exchange(ctx, code, loginState.CodeVerifier, s.redirectURI(provider))
+	if err != nil {
+		log.Printf("OpenID Connect code exchange with %s failed: %v", provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+	idToken, err := p.VerifyIDToken(ctx, rawIDToken, loginState.Nonce)
+	if err != nil {
+		log.Printf("OpenID Connect ID token from %s rejected: %v", provider, err)
+		return nil, ErrOIDCLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, provider, idToken)
+	if err != nil {
+		return nil, err
+	}
+	return s.userService.CompleteLogin(ctx, user, client)
+}
+
+// resolveUser finds the user for a verified ID token. An identity seen before
+// maps to its linked user. Otherwise the identity is linked to the local account
+// with the same verified email, or a new account is created.
+func (s *OIDCServiceImpl) resolveUser(ctx context.Context, provider string, idToken *oidc.IDToken) (*models.User, error) {
+	identity, err := s.identityRepo.GetByProviderSubject(ctx, provider, idToken.Subject)
+	switch {
+	case err == nil:
+		user, err := linkedUser(ctx, s.identityRepo, s.userRepo, identity)
+		if user != nil || err != nil {
+			return user, err
+		}
+	case !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	if idToken.Email == "" || !idToken.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, idToken.Email)
+	switch {
+	case err == nil:
+		// Linking to an unverified account would let whoever registered the
+		// address first take over the account of its real owner
+		if !user.EmailVerified() {
+			return nil, ErrOIDCAccountExists
+		}
+	case errors.Is(err, repository.ErrNotFound):
+		if user, err = s.createUser(ctx, idToken); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	identity = &models.LinkedIdentity{
+		UserID:   user.ID,
+		Provider: provider,
+		Subject:  idToken.Subject,
+		Email:    idToken.Email,
+	}
+	if err := s.identityRepo.Create(ctx, identity); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// linkedUser returns the user an identity is linked to. A soft-deleted user is
+// reported as ErrUserNotFound, so the identity comes back if the user is
+// restored. An identity whose user no longer exists at all is stale; it is
+// deleted and linkedUser returns no user, so the caller can resolve the
+// identity afresh.
+func linkedUser(ctx context.Context, identityRepo repository.LinkedIdentityRepository, userRepo repository.UserRepository, identity *models.LinkedIdentity) (*models.User, error) {
+	user, err := userRepo.GetByIDUnscoped(ctx, identity.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Removing identity %d linked to missing user %d", identity.ID, identity.UserID)
+		if _, err := identityRepo.Delete(ctx, identity.ID, identity.UserID); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if user.DeletedAt.Valid {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// createUser creates an account for a first-time social login. The account
+// has no password; the user can set one through the password reset flow.
+func (s *OIDCServiceImpl) createUser(ctx context.Context, idToken *oidc.IDToken) (*models.User, error) {
+	name := strings.TrimSpace(idToken.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(idToken.Email, "@")
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:           idToken.Email,
+		Name:            name,
+		Role:            models.RoleUser,
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userService.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	log.Printf("Created user %d from OpenID Connect login", user.ID)
+	return user, nil
+}
+
+func (s *OIDCServiceImpl) ListIdentities(ctx context.Context, userID uint) ([]models.LinkedIdentity, error) {
+	return s.identityRepo.ListByUser(ctx, userID)
+}
+
+func (s *OIDCServiceImpl) Unlink(ctx context.Context, userID, id uint) error {
+	deleted, err := s.identityRepo.Delete(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+// redirectURI is the callback URL registered with the provider
+func (s *OIDCServiceImpl) redirectURI(provider string) string {
+	return fmt.Sprintf("%s/api/v1/auth/oidc/%s/callback", s.config.OIDCRedirectBaseURL, provider)
+}
+
+func oidcStateKey(state string) string {
+	return "oidc:state:" + utils.HashToken(state)
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/oidc"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+	"gorm.io/gorm"
+)
+
+const (
+	testClientID = "test-client"
+	testNonce    = "test-nonce"
+)
+
+// mockProvider is an OpenID Connect provider that issues ID tokens with the
+// claims of its next login for any authorization code
+type mockProvider struct {
+	server *httptest.Server
+	key    ed25519.PrivateKey
+	claims jwt.MapClaims
+}
+
+func newMockProvider(t *testing.T) *mockProvider {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &mockProvider{key: key}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidc.Discovery{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JWKSURI:               m.server.URL + "/jwks",
+			IDTokenSigningAlgs:    []string{"EdDSA"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(utils.JWKSet{Keys: []utils.JWK{{
+			Kty: "OKP",
+			Kid: "test",
+			Use: "sig",
+			Alg: "EdDSA",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(key.Public().(ed25519.PublicKey)),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "code" || r.PostFormValue("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, m.claims)
+		token.Header["kid"] = "test"
+		signed, err := token.SignedString(m.key)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": signed})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// login runs the code flow against the mock provider for an identity and
+// returns the verified ID token
+func (m *mockProvider) login(t *testing.T, subject, email string, emailVerified bool, nonce string) (*oidc.IDToken, error) {
+	m.claims = jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            testClientID,
+		"sub":            subject,
+		"email":          email,
+		"email_verified": emailVerified,
+		"nonce":          testNonce,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+	}
+
+	ctx := context.Background()
+	provider := oidc.NewProvider(config.OIDCProvider{Name: "mock", Issuer: m.server.URL, ClientID: testClientID}, m.server.Client())
+	raw, err := provider.Exchange(ctx, "code", "verifier", "http://localhost/callback")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return provider.VerifyIDToken(ctx, raw, nonce)
+}
+
+// fakeIdentities is an in-memory LinkedIdentityRepository
+type fakeIdentities struct {
+	identities []models.LinkedIdentity
+	err        error
+}
+
+func (f *fakeIdentities) Create(ctx context.Context, identity *models.LinkedIdentity) error {
+	identity.ID = uint(len(f.identities) + 100)
+	f.identities = append(f.identities, *identity)
+	return nil
+}
+
+func (f *fakeIdentities) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.LinkedIdentity, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, identity := range f.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeIdentities) ListByUser(ctx context.Context, userID uint) ([]models.LinkedIdentity, error) {
+	var identities []models.LinkedIdentity
+	for _, identity := range f.identities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (f *fakeIdentities) Delete(ctx context.Context, id, userID uint) (bool, error) {
+	for i, identity := range f.identities {
+		if identity.ID == id && identity.UserID == userID {
+			f.identities = append(f.identities[:i], f.identities[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (f *fakeIdentities) DeleteByUser(ctx context.Context, userID uint) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeIdentities) DeleteByProvider(ctx context.Context, provider string) error {
+	return errors.New("not implemented")
+}
+
+// fakeUsers serves the user lookups of the login flows from memory
+type fakeUsers struct {
+	repository.UserRepository
+	users []*models.User
+	err   error
+}
+
+func (f *fakeUsers) GetByIDUnscoped(ctx context.Context, id uint) (*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, user := range f.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (f *fakeUsers) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	for _, user := range f.users {
+		if user.Email == email && !user.DeletedAt.Valid {
+			return user, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// fakeUserService creates accounts in fakeUsers
+type fakeUserService struct {
+	UserService
+	users *fakeUsers
+}
+
+func (f *fakeUserService) Create(ctx context.Context, user *models.User) error {
+	user.ID = uint(len(f.users.users) + 1)
+	f.users.users = append(f.users.users, user)
+	return nil
+}
+
+func TestOIDCResolveUser(t *testing.T) {
+	provider := newMockProvider(t)
+	verified := time.Now()
+	lookupFailure := errors.New("connection refused")
+
+	tests := []struct {
+		name          string
+		users         []*models.User
+		identities    []models.LinkedIdentity
+		usersErr      error
+		identitiesErr error
+		subject       string
+		email         string
+		emailVerified bool
+		wantUser      uint
+		wantErr       error
+		wantLinks     int
+	}{
+		{
+			name:       "linked identity signs in its user",
+			users:      []*models.User{{ID: 1, Email: "old@example.com"}},
+			identities: []models.LinkedIdentity{{ID: 10, UserID: 1, Provider: "mock", Subject: "sub-1"}},
+			subject:    "sub-1",
+			email:      "new@example.com",
+			wantUser:   1,
+			wantLinks:  1,
+		},
+		{
+			name:          "verified email links the account",
+			users:         []*models.User{{ID: 1, Email: "user@example.com", EmailVerifiedAt: &verified}},
+			subject:       "sub-1",
+			email:         "user@example.com",
+			emailVerified: true,
+			wantUser:      1,
+			wantLinks:     1,
+		},
+		{
+			name:          "unknown email creates an account",
+			users:         []*models.User{{ID: 1, Email: "other@example.com"}},
+			subject:       "sub-1",
+			email:         "user@example.com",
+			emailVerified: true,
+			wantUser:      2,
+			wantLinks:     1,
+		},
+		{
+			name:    "unverified provider email is refused",
+			subject: "sub-1",
+			email:   "user@example.com",
+			wantErr: ErrOIDCEmailNotVerified,
+		},
+		{
+			name:          "unverified local account is not linked",
+			users:         []*models.User{{ID: 1, Email: "user@example.com"}},
+			subject:       "sub-1",
+			email:         "user@example.com",
+			emailVerified: true,
+			wantErr:       ErrOIDCAccountExists,
+		},
+		{
+			name:          "identity lookup failure is returned",
+			identitiesErr: lookupFailure,
+			subject:       "sub-1",
+			email:         "user@example.com",
+			emailVerified: true,
+			wantErr:       lookupFailure,
+		},
+		{
+			name:          "user lookup failure is returned",
+			usersErr:      lookupFailure,
+			subject:       "sub-1",
+			email:         "user@example.com",
+			emailVerified: true,
+			wantErr:       lookupFailure,
+		},
+		{
+			name:          "stale link is replaced",
+			users:         []*models.User{{ID: 1, Email: "user@example.com", EmailVerifiedAt: &verified}},
+			identities:    []models.LinkedIdentity{{ID: 10, UserID: 7, Provider: "mock", Subject: "sub-1"}},
+			subject:       "sub-1",
+			email:         "user@example.com",
+			emailVerified: true,
+			wantUser:      1,
+			wantLinks:     1,
+		},
+		{
+			name:       "deleted user keeps the link",
+			users:      []*models.User{{ID: 1, Email: "user@example.com", DeletedAt: gorm.DeletedAt{Time: verified, Valid: true}}},
+			identities: []models.LinkedIdentity{{ID: 10, UserID: 1, Provider: "mock", Subject: "sub-1"}},
+			subject:    "sub-1",
+			email:      "user@example.com",
+			wantErr:    ErrUserNotFound,
+			wantLinks:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsers{users: tt.users, err: tt.usersErr}
+			identities := &fakeIdentities{identities: tt.identities, err: tt.identitiesErr}
+			s := &OIDCServiceImpl{identityRepo: identities, userRepo: users, userService: &fakeUserService{users: users}}
+
+			idToken, err := provider.login(t, tt.subject, tt.email, tt.emailVerified, testNonce)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			user, err := s.resolveUser(context.Background(), "mock", idToken)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && user.ID != tt.wantUser {
+				t.Errorf("got user %d, want %d", user.ID, tt.wantUser)
+			}
+			if len(identities.identities) != tt.wantLinks {
+				t.Errorf("got %d linked identities, want %d", len(identities.identities), tt.wantLinks)
+			}
+			if err == nil {
+				link := identities.identities[len(identities.identities)-1]
+				if link.UserID != user.ID || link.Subject != tt.subject {
+					t.Errorf("identity %s is linked to user %d, want %s linked to %d", link.Subject, link.UserID, tt.subject, user.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestOIDCProviderRejectsWrongNonce(t *testing.T) {
+	provider := newMockProvider(t)
+
+	_, err := provider.login(t, "sub-1", "user@example.com", true, "other-nonce")
+	if !errors.Is(err, oidc.ErrInvalidIDToken) {
+		t.Errorf("got %v, want %v", err, oidc.ErrInvalidIDToken)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/mailer"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// mailTimeout bounds how long a background email delivery may take
+const mailTimeout = 30 * time.Second
+
+// issueOneTimeToken replaces any outstanding token of the same purpose with a new one
+// and returns the plaintext token to send to the user
+func issueOneTimeToken(ctx context.Context, repo repository.OneTimeTokenRepository, userID uint, purpose string, ttl time.Duration) (string, error) {
+	// Only the most recent token stays valid
+	if err := repo.InvalidateForUser(ctx, userID, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	record := &models.OneTimeToken{
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+	if err := repo.Create(ctx, record); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// findOneTimeToken returns a valid token without using it up.
+// It returns false when the token is unknown, expired or already used.
+func findOneTimeToken(ctx context.Context, repo repository.OneTimeTokenRepository, purpose, token string) (*models.OneTimeToken, bool) {
+	record, err := repo.GetUnusedByHash(ctx, purpose, utils.HashToken(token))
+	if err != nil || record.Expired() {
+		return nil, false
+	}
+	return record, true
+}
+
+// consumeOneTimeToken marks a valid token as used and returns it.
+// It returns false when the token is unknown, expired or already used.
+func consumeOneTimeToken(ctx context.Context, repo repository.OneTimeTokenRepository, purpose, token string) (*models.OneTimeToken, bool, error) {
+	record, err := repo.GetUnusedByHash(ctx, purpose, utils.HashToken(token))
+	if err != nil || record.Expired() {
+		return nil, false, nil
+	}
+
+	used, err := repo.MarkUsed(ctx, record.ID)
+	if err != nil {
+		return nil, false, err
+	}
+	return record, used, nil
+}
+
+// sendMailAsync delivers msg in the background so response times do not depend
+// on the mail server, or reveal whether an account exists
+func sendMailAsync(m mailer.Mailer, msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := m.Send(ctx, msg); err != nil {
+			log.Printf("Failed to send %q email: %v", msg.Subject, err)
+		}
+	}()
+}
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// PasswordPolicyError lists the rules a new password breaks
+type PasswordPolicyError struct {
+	Violations []models.ValidationError
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the password policy"
+}
+
+type PasswordPolicy interface {
+	// Check returns a *PasswordPolicyError when password may not be set for user.
+	// The reuse check is skipped for accounts that have not been created yet.
+	Check(ctx context.Context, user *models.User, password string) error
+	// Remember adds the hash of a password the user has set to their history
+	Remember(ctx context.Context, userID uint, hash string)
+	// Forget deletes the user's password history
+	Forget(ctx context.Context, userID uint) error
+}
+
+type PasswordPolicyImpl struct {
+	historyRepo repository.PasswordHistoryRepository
+	txManager   repository.TxManager
+	hasher      utils.PasswordHasher
+	breaches    *utils.BreachCorpus
+	config      *config.Config
+}
+
+// NewPasswordPolicy creates the policy configured by config. breaches may be
+// nil, in which case passwords are not screened against breached passwords.
+func NewPasswordPolicy(historyRepo repository.PasswordHistoryRepository, txManager repository.TxManager, hasher utils.PasswordHasher, breaches *utils.BreachCorpus, config *config.Config) PasswordPolicy {
+	return &PasswordPolicyImpl{
+		historyRepo: historyRepo,
+		txManager:   txManager,
+		hasher:      hasher,
+		breaches:    breaches,
+		config:      config,
+	}
+}
+
+func (p *PasswordPolicyImpl) Check(ctx context.Context, user *models.User, password string) error {
+	var violations []models.ValidationError
+	violate := func(format string, args ...interface{}) {
+		violations = append(violations, models.ValidationError{
+			Field: "Password",
+			Error: fmt.Sprintf(format, args...),
+		})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.config.PasswordMinLength {
+		violate("Should be at least %d characters long", p.config.PasswordMinLength)
+	}
+	if p.config.PasswordMaxLength > 0 && length > p.config.PasswordMaxLength {
+		violate("Should be at most %d characters long", p.config.PasswordMaxLength)
+	}
+
+	if p.config.PasswordRequireUpper && !containsRune(password, unicode.IsUpper) {
+		violate("Should contain an uppercase letter")
+	}
+	if p.config.PasswordRequireLower && !containsRune(password, unicode.IsLower) {
+		violate("Should contain a lowercase letter")
+	}
+	if p.config.PasswordRequireDigit && !containsRune(password, unicode.IsDigit) {
+		violate("Should contain a digit")
+	}
+	if p.config.PasswordRequireSymbol && !containsRune(password, isSymbol) {
+		violate("Should contain a symbol")
+	}
+
+	// The remaining checks are only worth their cost for an otherwise valid password
+	if violations != nil {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	if utils.EstimatePasswordStrength(password, user.Email, user.Name) < p.config.PasswordMinStrength {
+		violate("Is too easy to guess")
+	}
+
+	if p.breaches != nil {
+		count, err := p.breaches.Count(password)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			violate("Has appeared in a data breach and cannot be used")
+		}
+	}
+
+	if user.ID != 0 && p.config.PasswordHistory > 0 {
+		reused, err := p.reused(ctx, user, password)
+		if err != nil {
+			return err
+		}
+		if reused {
+			violate("Should not be one of your last %d passwords", p.config.PasswordHistory)
+		}
+	}
+
+	if violations != nil {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// reused reports whether password matches the current password or one in the history
+func (p *PasswordPolicyImpl) reused(ctx context.Context, user *models.User, password string) (bool, error) {
+	history, err := p.historyRepo.ListRecent(ctx, user.ID, p.config.PasswordHistory)
+	if err != nil {
+		return false, err
+	}
+
+	// Accounts from before the history was kept only have their current password
+	hashes := []string{user.Password}
+	for _, entry := range history {
+		if entry.PasswordHash != user.Password {
+			hashes = append(hashes, entry.PasswordHash)
+		}
+	}
+
+	for _, hash := range hashes {
+		match, err := p.hasher.Verify(password, hash)
+		if err != nil && err != utils.ErrUnknownPasswordHash {
+			return false, err
+		}
+		if match {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Remember records the hash and drops entries beyond the configured history.
+// Failing to record history must not undo the password change.
+func (p *PasswordPolicyImpl) Remember(ctx context.Context, userID uint, hash string) {
+	if p.config.PasswordHistory <= 0 {
+		return
+	}
+
+	// Inside a transaction this is a savepoint, so a failure does not abort the caller's writes
+	err := p.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := p.historyRepo.Create(ctx, &models.PasswordHistory{UserID: userID, PasswordHash: hash}); err != nil {
+			return err
+		}
+		return p.historyRepo.Prune(ctx, userID, p.config.PasswordHistory)
+	})
+	if err != nil {
+		log.Printf("Failed to record password history for user %d: %v", userID, err)
+	}
+}
+
+func (p *PasswordPolicyImpl) Forget(ctx context.Context, userID uint) error {
+	return p.historyRepo.DeleteByUser(ctx, userID)
+}
+
+func containsRune(s string, f func(rune) bool) bool {
+	for _, r := range s {
+		if f(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+// fakePasswordHistory is an in-memory PasswordHistoryRepository
+type fakePasswordHistory struct {
+	entries []models.PasswordHistory
+	err     error
+}
+
+func (f *fakePasswordHistory) Create(ctx context.Context, entry *models.PasswordHistory) error {
+	entry.ID = 1
+	if len(f.entries) > 0 {
+		entry.ID = f.entries[len(f.entries)-1].ID + 1
+	}
+	f.entries = append(f.entries, *entry)
+	return nil
+}
+
+func (f *fakePasswordHistory) ListRecent(ctx context.Context, userID uint, limit int) ([]models.PasswordHistory, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	var entries []models.PasswordHistory
+	for i := len(f.entries) - 1; i >= 0 && len(entries) < limit; i-- {
+		if f.entries[i].UserID == userID {
+			entries = append(entries, f.entries[i])
+		}
+	}
+	return entries, nil
+}
+
+func (f *fakePasswordHistory) Prune(ctx context.Context, userID uint, keep int) error {
+	recent, _ := f.ListRecent(ctx, userID, keep)
+	kept := make(map[uint]bool, len(recent))
+	for _, entry := range recent {
+		kept[entry.ID] = true
+	}
+	entries := f.entries[:0]
+	for _, entry := range f.entries {
+		if entry.UserID != userID || kept[entry.ID] {
+			entries = append(entries, entry)
+		}
+	}
+	f.entries = entries
+	return nil
+}
+
+func (f *fakePasswordHistory) DeleteByUser(ctx context.Context, userID uint) error {
+	return errors.New("not implemented")
+}
+
+// fakeTxManager runs fn without a transaction
+type fakeTxManager struct{}
+
+func (fakeTxManager) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error, opts ...repository.TxOption) error {
+	return fn(ctx)
+}
+
+// newTestPasswordHasher returns the production hasher with cheap bcrypt hashes
+func newTestPasswordHasher(t *testing.T) utils.PasswordHasher {
+	t.Helper()
+	hasher, err := utils.NewPasswordHasher(&config.Config{
+		PasswordHashAlgorithm: utils.PasswordHashBcrypt,
+		Argon2Memory:          64,
+		Argon2Iterations:      1,
+		Argon2Parallelism:     1,
+		BcryptCost:            4,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	hasher := newTestPasswordHasher(t)
+	hash := func(password string) string {
+		encoded, err := hasher.Hash(password)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	current := hash("Current-Passw0rd")
+	previous := hash("Previous-Passw0rd")
+	oldest := hash("Oldest-Passw0rd")
+	lookupFailure := errors.New("connection refused")
+
+	defaults := config.Config{
+		PasswordMinLength:   8,
+		PasswordMaxLength:   64,
+		PasswordMinStrength: utils.PasswordSomewhatGuessable,
+		PasswordHistory:     2,
+	}
+	existing := &models.User{ID: 1, Email: "alice.smith@example.com", Name: "Alice Smith", Password: current}
+
+	tests := []struct {
+		name       string
+		change     func(cfg *config.Config)
+		user       *models.User
+		history    []models.PasswordHistory
+		historyErr error
+		password   string
+		want       []string
+		wantErr    error
+	}{
+		{
+			name:     "strong new password",
+			user:     existing,
+			password: "Fresh-Passw0rd",
+		},
+		{
+			name:     "too short",
+			user:     existing,
+			password: "xQ7!",
+			want:     []string{"Should be at least 8 characters long"},
+		},
+		{
+			name:     "too long",
+			user:     existing,
+			password: "Fresh-Passw0rd-Fresh-Passw0rd-Fresh-Passw0rd-Fresh-Passw0rd-Fresh",
+			want:     []string{"Should be at most 64 characters long"},
+		},
+		{
+			name: "every composition rule broken",
+			change: func(cfg *config.Config) {
+				cfg.PasswordRequireUpper = true
+				cfg.PasswordRequireLower = true
+				cfg.PasswordRequireDigit = true
+				cfg.PasswordRequireSymbol = true
+			},
+			user:     existing,
+			password: "        ",
+			want: []string{
+				"Should contain an uppercase letter",
+				"Should contain a lowercase letter",
+				"Should contain a digit",
+				"Should contain a symbol",
+			},
+		},
+		{
+			name:     "strength is not estimated for passwords breaking the rules",
+			user:     existing,
+			password: "pass",
+			want:     []string{"Should be at least 8 characters long"},
+		},
+		{
+			name:     "common password",
+			user:     existing,
+			password: "password1",
+			want:     []string{"Is too easy to guess"},
+		},
+		{
+			name:     "keyboard run",
+			user:     existing,
+			password: "qwertyuiop123",
+			want:     []string{"Is too easy to guess"},
+		},
+		{
+			name:     "name of the account",
+			user:     existing,
+			password: "SmithAlice",
+			want:     []string{"Is too easy to guess"},
+		},
+		{
+			name:     "email of the account",
+			user:     existing,
+			password: "alice.example.com",
+			want:     []string{"Is too easy to guess"},
+		},
+		{
+			name:     "guessable password allowed by a lower minimum strength",
+			change:   func(cfg *config.Config) { cfg.PasswordMinStrength = utils.PasswordTooGuessable },
+			user:     existing,
+			password: "password1",
+		},
+		{
+			name:     "current password",
+			user:     existing,
+			password: "Current-Passw0rd",
+			want:     []string{"Should not be one of your last 2 passwords"},
+		},
+		{
+			name:     "password from the history",
+			user:     existing,
+			history:  []models.PasswordHistory{{UserID: 1, PasswordHash: previous}, {UserID: 1, PasswordHash: current}},
+			password: "Previous-Passw0rd",
+			want:     []string{"Should not be one of your last 2 passwords"},
+		},
+		{
+			name:     "password older than the history",
+			user:     existing,
+			history:  []models.PasswordHistory{{UserID: 1, PasswordHash: oldest}, {UserID: 1, PasswordHash: previous}, {UserID: 1, PasswordHash: current}},
+			password: "Oldest-Passw0rd",
+		},
+		{
+			name:     "password from another user's history",
+			user:     existing,
+			history:  []models.PasswordHistory{{UserID: 2, PasswordHash: previous}},
+			password: "Previous-Passw0rd",
+		},
+		{
+			name:     "history entries in an unknown format are skipped",
+			user:     existing,
+			history:  []models.PasswordHistory{{UserID: 1, PasswordHash: "5f4dcc3b5aa765d61d8327deb882cf99"}},
+			password: "Fresh-Passw0rd",
+		},
+		{
+			name:     "history disabled",
+			change:   func(cfg *config.Config) { cfg.PasswordHistory = 0 },
+			user:     existing,
+			password: "Current-Passw0rd",
+		},
+		{
+			name:       "new accounts have no history",
+			user:       &models.User{Email: "alice.smith@example.com"},
+			historyErr: lookupFailure,
+			password:   "Fresh-Passw0rd",
+		},
+		{
+			name:       "history lookup failure",
+			user:       existing,
+			historyErr: lookupFailure,
+			password:   "Fresh-Passw0rd",
+			wantErr:    lookupFailure,
+		},
+		{
+			name:     "every later violation is reported",
+			user:     &models.User{ID: 1, Name: "Alice Smith", Password: hash("alicesmith")},
+			password: "alicesmith",
+			want:     []string{"Is too easy to guess", "Should not be one of your last 2 passwords"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := defaults
+			if tt.change != nil {
+				tt.change(&cfg)
+			}
+			history := &fakePasswordHistory{entries: tt.history, err: tt.historyErr}
+			policy := NewPasswordPolicy(history, fakeTxManager{}, hasher, nil, &cfg)
+
+			err := policy.Check(context.Background(), tt.user, tt.password)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("got error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+
+			var got []string
+			var policyErr *PasswordPolicyError
+			if errors.As(err, &policyErr) {
+				for _, violation := range policyErr.Violations {
+					if violation.Field != "Password" {
+						t.Errorf("got violation of %q, want Password", violation.Field)
+					}
+					got = append(got, violation.Error)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got violations %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPasswordPolicyRemember(t *testing.T) {
+	history := &fakePasswordHistory{entries: []models.PasswordHistory{{ID: 1, UserID: 2, PasswordHash: "other"}}}
+	policy := NewPasswordPolicy(history, fakeTxManager{}, newTestPasswordHasher(t), nil, &config.Config{PasswordHistory: 2})
+
+	for _, hash := range []string{"first", "second", "third"} {
+		policy.Remember(context.Background(), 1, hash)
+	}
+
+	var got []string
+	for _, entry := range history.entries {
+		got = append(got, entry.PasswordHash)
+	}
+	want := []string{"other", "second", "third"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got history %q, want %q", got, want)
+	}
+}
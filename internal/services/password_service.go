@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/mailer"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+)
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+)
+
+type PasswordService interface {
+	RequestReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+}
+
+type PasswordServiceImpl struct {
+	userRepo    repository.UserRepository
+	tokenRepo   repository.OneTimeTokenRepository
+	userService UserService
+	mailer      mailer.Mailer
+	config      *config.Config
+}
+
+func NewPasswordService(userRepo repository.UserRepository, tokenRepo repository.OneTimeTokenRepository, userService UserService, mailer mailer.Mailer, config *config.Config) PasswordService {
+	return &PasswordServiceImpl{
+		userRepo:    userRepo,
+		tokenRepo:   tokenRepo,
+		userService: userService,
+		mailer:      mailer,
+		config:      config,
+	}
+}
+
+// RequestReset emails a reset link to the account. It succeeds whether or not
+// the account exists so the response cannot be used to discover registered emails.
+func (s *PasswordServiceImpl) RequestReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	token, err := issueOneTimeToken(ctx, s.tokenRepo, user.ID, models.TokenPurposePasswordReset, s.config.PasswordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", s.config.AppBaseURL, url.QueryEscape(token))
+	sendMailAsync(s.mailer, mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s.\n\n%s\n\nIf you did not ask to reset your password you can ignore this email.\n",
+			user.Name, s.config.PasswordResetTTL, link),
+	})
+
+	return nil
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the user out everywhere.
+// A password rejected by the password policy leaves the token valid for another attempt.
+func (s *PasswordServiceImpl) ResetPassword(ctx context.Context, token, password string) error {
+	pending, ok := findOneTimeToken(ctx, s.tokenRepo, models.TokenPurposePasswordReset, token)
+	if !ok {
+		return ErrInvalidResetToken
+	}
+	if err := s.userService.CheckPassword(ctx, pending.UserID, password); err != nil {
+		if err == ErrUserNotFound {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	record, ok, err := consumeOneTimeToken(ctx, s.tokenRepo, models.TokenPurposePasswordReset, token)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidResetToken
+	}
+
+	if err := s.userService.ChangePassword(ctx, record.UserID, password); err != nil {
+		if err == ErrUserNotFound {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+)
+
+var (
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrProtectedRole      = errors.New("built-in roles cannot be deleted")
+	ErrAdminPermissions   = errors.New("permissions cannot be removed from the admin role")
+	ErrPermissionNotFound = errors.New("permission not found")
+	ErrPermissionExists   = errors.New("permission already exists")
+)
+
+// defaultPermissions are created on startup and granted to the admin role
+var defaultPermissions = []models.Permission{
+	{Name: models.PermissionAdminAccess, Description: "Access the admin API"},
+	{Name: models.PermissionRolesManage, Description: "Manage roles and permissions"},
+	{Name: models.PermissionUsersRead, Description: "Read any user account"},
+	{Name: models.PermissionUsersWrite, Description: "Modify any user account"},
+	{Name: models.PermissionUsersDelete, Description: "Delete user accounts"},
+	{Name: models.PermissionAuditRead, Description: "Read the audit log"},
+	{Name: models.PermissionClientsManage, Description: "Register and remove OAuth2 clients"},
+	{Name: models.PermissionImpersonate, Description: "Act as another user for support"},
+	{Name: models.PermissionSSOManage, Description: "Configure SAML single sign-on for organizations"},
+}
+
+type RBACService interface {
+	SeedDefaults(ctx context.Context) error
+	HasPermission(ctx context.Context, role, permission string) (bool, error)
+	GetRolePermissions(ctx context.Context, role string) ([]string, error)
+	RoleExists(ctx context.Context, role string) (bool, error)
+	CreateRole(ctx context.Context, role *models.Role) error
+	GetRole(ctx context.Context, id uint) (*models.Role, error)
+	UpdateRole(ctx context.Context, role *models.Role) error
+	DeleteRole(ctx context.Context, id uint) error
+	ListRoles(ctx context.Context) ([]models.Role, error)
+	SetRolePermissions(ctx context.Context, id uint, permissions []string) (*models.Role, error)
+	CreatePermission(ctx context.Context, permission *models.Permission) error
+	DeletePermission(ctx context.Context, id uint) error
+	ListPermissions(ctx context.Context) ([]models.Permission, error)
+}
+
+type RBACServiceImpl struct {
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	redis          *redis.Client
+}
+
+func NewRBACService(roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, redis *redis.Client) RBACService {
+	return &RBACServiceImpl{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		redis:          redis,
+	}
+}
+
+// SeedDefaults makes sure the built-in roles and permissions exist. Existing
+// mappings are left alone so changes made through the admin API survive restarts;
+// only permissions created by this call are granted to an existing admin role.
+func (s *RBACServiceImpl) SeedDefaults(ctx context.Context) error {
+	var created []string
+	for _, permission := range defaultPermissions {
+		if _, err := s.permissionRepo.GetByName(ctx, permission.Name); err == nil {
+			continue
+		}
+		permission := permission
+		if err := s.permissionRepo.Create(ctx, &permission); err != nil {
+			return fmt.Errorf("failed to seed permission %s: %w", permission.Name, err)
+		}
+		created = append(created, permission.Name)
+	}
+
+	if admin, err := s.roleRepo.GetByName(ctx, models.RoleAdmin); err != nil {
+		names := make([]string, len(defaultPermissions))
+		for i, permission := range defaultPermissions {
+			names[i] = permission.Name
+		}
+		permissions, err := s.permissionRepo.GetByNames(ctx, names)
+		if err != nil {
+			return err
+		}
+		admin := &models.Role{Name: models.RoleAdmin, Description: "Full access", Permissions: permissions}
+		if err := s.roleRepo.Create(ctx, admin); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", models.RoleAdmin, err)
+		}
+	} else if len(created) > 0 {
+		added, err := s.permissionRepo.GetByNames(ctx, created)
+		if err != nil {
+			return err
+		}
+		if err := s.roleRepo.ReplacePermissions(ctx, admin, append(admin.Permissions, added...)); err != nil {
+			return fmt.Errorf("failed to grant new permissions to %s: %w", models.RoleAdmin, err)
+		}
+		s.redis.Del(ctx, rolePermissionsKey(models.RoleAdmin))
+	}
+
+	if _, err := s.roleRepo.GetByName(ctx, models.RoleUser); err != nil {
+		user := &models.Role{Name: models.RoleUser, Description: "Regular user"}
+		if err := s.roleRepo.Create(ctx, user); err != nil {
+			return fmt.Errorf("failed to seed role %s: %w", models.RoleUser, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *RBACServiceImpl) HasPermission(ctx context.Context, role, permission string) (bool, error) {
+	permissions, err := s.GetRolePermissions(ctx, role)
+	if err != nil {
+		if err == ErrRoleNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetRolePermissions returns the permission names granted to a role, cached in Redis
+func (s *RBACServiceImpl) GetRolePermissions(ctx context.Context, role string) ([]string, error) {
+	cacheKey := rolePermissionsKey(role)
+
+	// Check cache
+	var permissions []string
+	if data, err := s.redis.Get(ctx, cacheKey).Bytes(); err == nil {
+		if err := json.Unmarshal(data, &permissions); err == nil {
+			return permissions, nil
+		}
+	}
+
+	// If not in cache, get from database
+	r, err := s.roleRepo.GetByName(ctx, role)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	permissions = make([]string, len(r.Permissions))
+	for i, p := range r.Permissions {
+		permissions[i] = p.Name
+	}
+
+	// Cache the result
+	if data, err := json.Marshal(permissions); err == nil {
+		s.redis.Set(ctx, cacheKey, data, 10*time.Minute)
+	}
+
+	return permissions, nil
+}
+
+func (s *RBACServiceImpl) RoleExists(ctx context.Context, role string) (bool, error) {
+	_, err := s.GetRolePermissions(ctx, role)
+	if err == ErrRoleNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// CreateRole creates a role without permissions. They are granted through
+// SetRolePermissions, where the role policy checks them against the actor.
+func (s *RBACServiceImpl) CreateRole(ctx context.Context, role *models.Role) error {
+	if _, err := s.roleRepo.GetByName(ctx, role.Name); err == nil {
+		return ErrRoleExists
+	}
+	role.Permissions = nil
+	return s.roleRepo.Create(ctx, role)
+}
+
+func (s *RBACServiceImpl) GetRole(ctx context.Context, id uint) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// UpdateRole updates the description of a role. Roles are referenced by name,
+// so renaming is not supported.
+func (s *RBACServiceImpl) UpdateRole(ctx context.Context, role *models.Role) error {
+	existing, err := s.roleRepo.GetByID(ctx, role.ID)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+
+	existing.Description = role.Description
+	if err := s.roleRepo.Update(ctx, existing); err != nil {
+		return err
+	}
+	*role = *existing
+	return nil
+}
+
+func (s *RBACServiceImpl) DeleteRole(ctx context.Context, id uint) error {
+	role, err := s.roleRepo.GetByID(ctx, id)
+	if err != nil {
+		return ErrRoleNotFound
+	}
+	if role.Name == models.RoleAdmin || role.Name == models.RoleUser {
+		return ErrProtectedRole
+	}
+
+	if err := s.roleRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	// Invalidate cache
+	s.redis.Del(ctx, rolePermissionsKey(role.Name))
+	return nil
+}
+
+func (s *RBACServiceImpl) ListRoles(ctx context.Context) ([]models.Role, error) {
+	return s.roleRepo.List(ctx)
+}
+
+// SetRolePermissions replaces the permissions granted to a role. The admin
+// role may gain permissions but never lose any, so that it cannot be locked
+// out of role management.
+func (s *RBACServiceImpl) SetRolePermissions(ctx context.Context, id uint, names []string) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrRoleNotFound
+	}
+	if role.Name == models.RoleAdmin {
+		requested := make(map[string]bool, len(names))
+		for _, name := range names {
+			requested[name] = true
+		}
+		for _, permission := range role.Permissions {
+			if !requested[permission.Name] {
+				return nil, ErrAdminPermissions
+			}
+		}
+	}
+
+	permissions := []models.Permission{}
+	if len(names) > 0 {
+		permissions, err = s.permissionRepo.GetByNames(ctx, names)
+		if err != nil {
+			return nil, err
+		}
+		if len(permissions) != len(uniqueStrings(names)) {
+			return nil, ErrPermissionNotFound
+		}
+	}
+
+	if err := s.roleRepo.ReplacePermissions(ctx, role, permissions); err != nil {
+		return nil, err
+	}
+	role.Permissions = permissions
+
+	// Invalidate cache
+	s.redis.Del(ctx, rolePermissionsKey(role.Name))
+	return role, nil
+}
+
+func (s *RBACServiceImpl) CreatePermission(ctx context.Context, permission *models.Permission) error {
+	if _, err := s.permissionRepo.GetByName(ctx, permission.Name); err == nil {
+		return ErrPermissionExists
+	}
+	return s.permissionRepo.Create(ctx, permission)
+}
+
+func (s *RBACServiceImpl) DeletePermission(ctx context.Context, id uint) error {
+	roles, err := s.roleRepo.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.permissionRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	// Invalidate cache for every role, any of them may have held the permission
+	for _, role := range roles {
+		s.redis.Del(ctx, rolePermissionsKey(role.Name))
+	}
+	return nil
+}
+
+func (s *RBACServiceImpl) ListPermissions(ctx context.Context) ([]models.Permission, error) {
+	return s.permissionRepo.List(ctx)
+}
+
+func rolePermissionsKey(role string) string {
+	return fmt.Sprintf("rbac:role:%s:permissions", role)
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// validateScopes checks that every scope names an existing permission
+func validateScopes(ctx context.Context, rbacService RBACService, scopes []string) error {
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	permissions, err := rbacService.ListPermissions(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool, len(permissions))
+	for _, permission := range permissions {
+		known[permission.Name] = true
+	}
+	for _, scope := range scopes {
+		if !known[scope] {
+			return ErrInvalidScope
+		}
+	}
+	return nil
+}
@@ -0,0 +1,464 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/saml"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrSAMLConnectionNotFound = errors.New("SAML connection not found")
+	ErrSAMLConnectionExists   = errors.New("organization already has a SAML connection")
+	ErrInvalidIDPMetadata     = errors.New("identity provider metadata is invalid or unreachable")
+	ErrInvalidSAMLState       = errors.New("invalid or expired login state")
+	ErrSAMLLoginFailed        = errors.New("login with identity provider failed")
+	ErrSAMLEmailMissing       = errors.New("identity provider did not send an email address")
+	ErrSAMLDomainNotAllowed   = errors.New("email address is not in a domain of the organization")
+	ErrSAMLAccountExists      = errors.New("an account with this email already exists; sign in and verify your email before linking")
+	ErrSAMLNotProvisioned     = errors.New("no account exists for this user and the organization does not create accounts on first login")
+)
+
+// samlStateTTL is how long a user has to complete the login at the identity provider
+const samlStateTTL = 10 * time.Minute
+
+type SAMLService interface {
+	Metadata(ctx context.Context, organization string) ([]byte, error)
+	BeginLogin(ctx context.Context, organization string) (string, error)
+	CompleteLogin(ctx context.Context, organization, samlResponse, relayState string, client ClientInfo) (*models.LoginResponse, error)
+	ListConnections(ctx context.Context) ([]models.SAMLConnectionResponse, error)
+	CreateConnection(ctx context.Context, req *models.SAMLConnectionRequest) (*models.SAMLConnectionResponse, error)
+	UpdateConnection(ctx context.Context, id uint, req *models.SAMLConnectionRequest) (*models.SAMLConnectionResponse, error)
+	DeleteConnection(ctx context.Context, id uint) error
+}
+
+type SAMLServiceImpl struct {
+	connectionRepo repository.SAMLConnectionRepository
+	identityRepo   repository.LinkedIdentityRepository
+	userRepo       repository.UserRepository
+	userService    UserService
+	rbacService    RBACService
+	credentials    *saml.Credentials
+	redis          *redis.Client
+	config         *config.Config
+}
+
+// NewSAMLService creates the SAML service provider. credentials may be nil, in
+// which case AuthnRequests are not signed and assertions cannot be encrypted.
+func NewSAMLService(connectionRepo repository.SAMLConnectionRepository, identityRepo repository.LinkedIdentityRepository, userRepo repository.UserRepository, userService UserService, rbacService RBACService, credentials *saml.Credentials, redis *redis.Client, config *config.Config) SAMLService {
+	return &SAMLServiceImpl{
+		connectionRepo: connectionRepo,
+		identityRepo:   identityRepo,
+		userRepo:       userRepo,
+		userService:    userService,
+		rbacService:    rbacService,
+		credentials:    credentials,
+		redis:          redis,
+		config:         config,
+	}
+}
+
+// samlLoginState is kept in Redis between the redirect to the identity provider and the response
+type samlLoginState struct {
+	Organization string `json:"organization"`
+	RequestID    string `json:"request_id"`
+}
+
+// Metadata returns the service provider metadata for an organization's identity provider
+func (s *SAMLServiceImpl) Metadata(ctx context.Context, organization string) ([]byte, error) {
+	_, sp, err := s.serviceProvider(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+	return sp.Metadata()
+}
+
+// BeginLogin creates an AuthnRequest and returns the URL to redirect the user to
+func (s *SAMLServiceImpl) BeginLogin(ctx context.Context, organization string) (string, error) {
+	_, sp, err := s.serviceProvider(ctx, organization)
+	if err != nil {
+		return "", err
+	}
+
+	relayState, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+	authURL, requestID, err := sp.AuthnRequestURL(relayState)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := json.Marshal(samlLoginState{Organization: organization, RequestID: requestID})
+	if err != nil {
+		return "", err
+	}
+	if err := s.redis.Set(ctx, samlStateKey(relayState), data, samlStateTTL).Err(); err != nil {
+		return "", err
+	}
+	return authURL, nil
+}
+
+// CompleteLogin handles the response posted to the ACS URL. It verifies the
+// assertion and signs in the user it names, linking or provisioning an account
+// on first use. Logins started at the identity provider are not accepted.
+func (s *SAMLServiceImpl) CompleteLogin(ctx context.Context, organization, samlResponse, relayState string, client ClientInfo) (*models.LoginResponse, error) {
+	connection, sp, err := s.serviceProvider(ctx, organization)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each relay state is single-use, which also rejects replayed responses
+	data, err := s.redis.GetDel(ctx, samlStateKey(relayState)).Bytes()
+	if err == redis.Nil {
+		return nil, ErrInvalidSAMLState
+	}
+	if err != nil {
+		return nil, err
+	}
+	var loginState samlLoginState
+	if err := json.Unmarshal(data, &loginState); err != nil || loginState.Organization != organization {
+		return nil, ErrInvalidSAMLState
+	}
+
+	// Details of rejected responses are logged rather than returned to the client
+	assertion, err := sp.ParseResponse(samlResponse, loginState.RequestID)
+	if err != nil {
+		log.Printf("SAML response for %s rejected: %v", organization, err)
+		return nil, ErrSAMLLoginFailed
+	}
+
+	user, err := s.resolveUser(ctx, connection, assertion)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.syncRole(ctx, connection, assertion, user); err != nil {
+		return nil, err
+	}
+	return s.userService.CompleteLogin(ctx, user, client)
+}
+
+// resolveUser finds the user for a verified assertion. A persistent NameID seen
+// before maps to its linked user. Otherwise the user is found by email, which
+// must be in one of the organization's domains, or provisioned just in time.
+func (s *SAMLServiceImpl) resolveUser(ctx context.Context, connection *models.SAMLConnection, assertion *saml.Assertion) (*models.User, error) {
+	provider := samlProvider(connection.Organization)
+	if !assertion.Transient() {
+		identity, err := s.identityRepo.GetByProviderSubject(ctx, provider, assertion.NameID)
+		switch {
+		case err == nil:
+			user, err := linkedUser(ctx, s.identityRepo, s.userRepo, identity)
+			if user != nil || err != nil {
+				return user, err
+			}
+		case !errors.Is(err, repository.ErrNotFound):
+			return nil, err
+		}
+	}
+
+	email := strings.ToLower(assertion.Value(connection.EmailAttribute))
+	if connection.EmailAttribute == "" || email == "" {
+		if !strings.Contains(assertion.NameID, "@") {
+			return nil, ErrSAMLEmailMissing
+		}
+		email = strings.ToLower(assertion.NameID)
+	}
+	// The identity provider is only trusted for the organization's own domains,
+	// so it cannot sign in to accounts of other organizations
+	if !connection.AllowsEmail(email) {
+		log.Printf("SAML login for %s asserted %s outside the organization's domains", connection.Organization, email)
+		return nil, ErrSAMLDomainNotAllowed
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	switch {
+	case err == nil:
+		// Linking to an unverified account would let whoever registered the
+		// address first take over the account of its real owner
+		if !user.EmailVerified() {
+			return nil, ErrSAMLAccountExists
+		}
+	case errors.Is(err, repository.ErrNotFound):
+		if !connection.JITProvisioning {
+			return nil, ErrSAMLNotProvisioned
+		}
+		if user, err = s.createUser(ctx, connection, assertion, email); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	// Transient NameIDs change on every login, so such users are always found by email
+	if !assertion.Transient() {
+		identity := &models.LinkedIdentity{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  assertion.NameID,
+			Email:    email,
+		}
+		if err := s.identityRepo.Create(ctx, identity); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// createUser provisions an account for a first-time SSO login. The account has
+// no password and the role mapped from the assertion.
+func (s *SAMLServiceImpl) createUser(ctx context.Context, connection *models.SAMLConnection, assertion *saml.Assertion, email string) (*models.User, error) {
+	name := assertion.Value(connection.NameAttribute)
+	if connection.NameAttribute == "" || name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:           email,
+		Name:            name,
+		Role:            mappedRole(connection, assertion),
+		EmailVerifiedAt: &now,
+	}
+	if err := s.userService.Create(ctx, user); err != nil {
+		return nil, err
+	}
+	log.Printf("Created user %d from SAML login for %s", user.ID, connection.Organization)
+	return user, nil
+}
+
+// syncRole applies the role mapped from the assertion, so role changes at the
+// identity provider take effect on the next login. Without a role attribute the
+// role is only set when the account is provisioned.
+func (s *SAMLServiceImpl) syncRole(ctx context.Context, connection *models.SAMLConnection, assertion *saml.Assertion, user *models.User) error {
+	if connection.RoleAttribute == "" {
+		return nil
+	}
+	role := mappedRole(connection, assertion)
+	if role == user.Role {
+		return nil
+	}
+
+	if _, err := s.userService.ChangeRole(ctx, user.ID, role); err != nil {
+		return err
+	}
+	log.Printf("Changed role of user %d from %s to %s after SAML login for %s", user.ID, user.Role, role, connection.Organization)
+	user.Role = role
+	return nil
+}
+
+// mappedRole returns the role of the first value of the role attribute found in
+// the role mapping, or the default role
+func mappedRole(connection *models.SAMLConnection, assertion *saml.Assertion) string {
+	if connection.RoleAttribute != "" {
+		for _, value := range assertion.Values(connection.RoleAttribute) {
+			if role, ok := connection.RoleMapping[value]; ok {
+				return role
+			}
+		}
+	}
+	return connection.DefaultRole
+}
+
+func (s *SAMLServiceImpl) ListConnections(ctx context.Context) ([]models.SAMLConnectionResponse, error) {
+	connections, err := s.connectionRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SAMLConnectionResponse, len(connections))
+	for i := range connections {
+		responses[i] = s.newConnectionResponse(&connections[i])
+	}
+	return responses, nil
+}
+
+func (s *SAMLServiceImpl) CreateConnection(ctx context.Context, req *models.SAMLConnectionRequest) (*models.SAMLConnectionResponse, error) {
+	if _, err := s.connectionRepo.GetByOrganization(ctx, req.Organization); err == nil {
+		return nil, ErrSAMLConnectionExists
+	}
+
+	connection := &models.SAMLConnection{}
+	if err := s.applyConnectionRequest(ctx, connection, req); err != nil {
+		return nil, err
+	}
+	if err := s.connectionRepo.Create(ctx, connection); err != nil {
+		return nil, err
+	}
+
+	resp := s.newConnectionResponse(connection)
+	return &resp, nil
+}
+
+// UpdateConnection replaces the settings of a connection. Changing the
+// organization identifier changes the URLs registered at the identity provider.
+func (s *SAMLServiceImpl) UpdateConnection(ctx context.Context, id uint, req *models.SAMLConnectionRequest) (*models.SAMLConnectionResponse, error) {
+	connection, err := s.connectionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrSAMLConnectionNotFound
+	}
+	if req.Organization != connection.Organization {
+		if _, err := s.connectionRepo.GetByOrganization(ctx, req.Organization); err == nil {
+			return nil, ErrSAMLConnectionExists
+		}
+	}
+
+	previous := connection.Organization
+	if err := s.applyConnectionRequest(ctx, connection, req); err != nil {
+		return nil, err
+	}
+	if err := s.connectionRepo.Update(ctx, connection); err != nil {
+		return nil, err
+	}
+	// Identities are scoped to the organization identifier they were linked under
+	if previous != connection.Organization {
+		if err := s.identityRepo.DeleteByProvider(ctx, samlProvider(previous)); err != nil {
+			return nil, err
+		}
+	}
+
+	resp := s.newConnectionResponse(connection)
+	return &resp, nil
+}
+
+// DeleteConnection removes a connection and the identities linked through it.
+// The accounts themselves are kept.
+func (s *SAMLServiceImpl) DeleteConnection(ctx context.Context, id uint) error {
+	connection, err := s.connectionRepo.GetByID(ctx, id)
+	if err != nil {
+		return ErrSAMLConnectionNotFound
+	}
+
+	deleted, err := s.connectionRepo.Delete(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrSAMLConnectionNotFound
+	}
+	return s.identityRepo.DeleteByProvider(ctx, samlProvider(connection.Organization))
+}
+
+// applyConnectionRequest validates the request and copies it onto the connection
+func (s *SAMLServiceImpl) applyConnectionRequest(ctx context.Context, connection *models.SAMLConnection, req *models.SAMLConnectionRequest) error {
+	metadata := []byte(strings.TrimSpace(req.IDPMetadata))
+	if len(metadata) == 0 {
+		var err error
+		if metadata, err = saml.FetchMetadata(ctx, req.IDPMetadataURL); err != nil {
+			log.Printf("Fetching SAML metadata for %s failed: %v", req.Organization, err)
+			return ErrInvalidIDPMetadata
+		}
+	}
+	descriptor, err := saml.ParseMetadata(metadata)
+	if err != nil {
+		return ErrInvalidIDPMetadata
+	}
+
+	defaultRole := req.DefaultRole
+	if defaultRole == "" {
+		defaultRole = models.RoleUser
+	}
+	for _, role := range append([]string{defaultRole}, mapValues(req.RoleMapping)...) {
+		exists, err := s.rbacService.RoleExists(ctx, role)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrRoleNotFound
+		}
+	}
+
+	domains := make([]string, len(req.Domains))
+	for i, domain := range req.Domains {
+		domains[i] = strings.ToLower(domain)
+	}
+
+	connection.Organization = req.Organization
+	connection.Name = req.Name
+	connection.IDPEntityID = descriptor.EntityID
+	connection.IDPMetadata = string(metadata)
+	connection.Domains = strings.Join(domains, " ")
+	connection.EmailAttribute = req.EmailAttribute
+	connection.NameAttribute = req.NameAttribute
+	connection.RoleAttribute = req.RoleAttribute
+	connection.RoleMapping = req.RoleMapping
+	connection.DefaultRole = defaultRole
+	connection.JITProvisioning = req.JITProvisioning
+	return nil
+}
+
+// serviceProvider loads an organization's connection and the service provider for it
+func (s *SAMLServiceImpl) serviceProvider(ctx context.Context, organization string) (*models.SAMLConnection, *saml.ServiceProvider, error) {
+	connection, err := s.connectionRepo.GetByOrganization(ctx, organization)
+	if err != nil {
+		return nil, nil, ErrSAMLConnectionNotFound
+	}
+
+	sp, err := saml.NewServiceProvider(s.entityID(organization), s.acsURL(organization), []byte(connection.IDPMetadata), s.credentials)
+	if err != nil {
+		return nil, nil, fmt.Errorf("SAML connection for %s: %w", organization, err)
+	}
+	return connection, sp, nil
+}
+
+func (s *SAMLServiceImpl) newConnectionResponse(connection *models.SAMLConnection) models.SAMLConnectionResponse {
+	return models.SAMLConnectionResponse{
+		ID:              connection.ID,
+		CreatedAt:       connection.CreatedAt,
+		UpdatedAt:       connection.UpdatedAt,
+		Organization:    connection.Organization,
+		Name:            connection.Name,
+		IDPEntityID:     connection.IDPEntityID,
+		Domains:         strings.Fields(connection.Domains),
+		EmailAttribute:  connection.EmailAttribute,
+		NameAttribute:   connection.NameAttribute,
+		RoleAttribute:   connection.RoleAttribute,
+		RoleMapping:     connection.RoleMapping,
+		DefaultRole:     connection.DefaultRole,
+		JITProvisioning: connection.JITProvisioning,
+		EntityID:        s.entityID(connection.Organization),
+		ACSURL:          s.acsURL(connection.Organization),
+		LoginURL:        s.samlURL(connection.Organization, "login"),
+	}
+}
+
+// entityID identifies the service provider to an organization's identity
+// provider. It is the URL the service provider metadata is served at.
+func (s *SAMLServiceImpl) entityID(organization string) string {
+	return s.samlURL(organization, "metadata")
+}
+
+// acsURL is the assertion consumer service URL the identity provider posts responses to
+func (s *SAMLServiceImpl) acsURL(organization string) string {
+	return s.samlURL(organization, "acs")
+}
+
+func (s *SAMLServiceImpl) samlURL(organization, endpoint string) string {
+	return fmt.Sprintf("%s/api/v1/auth/saml/%s/%s", s.config.SAMLBaseURL, organization, endpoint)
+}
+
+// samlProvider is the provider name of identities linked through an organization's connection
+func samlProvider(organization string) string {
+	return "saml:" + organization
+}
+
+func samlStateKey(relayState string) string {
+	return "saml:state:" + utils.HashToken(relayState)
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, value := range m {
+		values = append(values, value)
+	}
+	return values
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/saml"
+)
+
+func TestSAMLResolveUser(t *testing.T) {
+	verified := time.Now()
+	lookupFailure := errors.New("connection refused")
+	connection := &models.SAMLConnection{Organization: "acme", Domains: "example.com", JITProvisioning: true}
+	assertion := &saml.Assertion{NameID: "user@example.com", NameIDFormat: "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"}
+
+	tests := []struct {
+		name          string
+		users         []*models.User
+		identities    []models.LinkedIdentity
+		identitiesErr error
+		wantUser      uint
+		wantErr       error
+		wantLinks     int
+	}{
+		{
+			name:       "linked identity signs in its user",
+			users:      []*models.User{{ID: 1, Email: "old@example.com"}},
+			identities: []models.LinkedIdentity{{ID: 10, UserID: 1, Provider: "saml:acme", Subject: "user@example.com"}},
+			wantUser:   1,
+			wantLinks:  1,
+		},
+		{
+			name:      "unknown user is provisioned",
+			wantUser:  1,
+			wantLinks: 1,
+		},
+		{
+			name:       "stale link is replaced",
+			users:      []*models.User{{ID: 1, Email: "user@example.com", EmailVerifiedAt: &verified}},
+			identities: []models.LinkedIdentity{{ID: 10, UserID: 7, Provider: "saml:acme", Subject: "user@example.com"}},
+			wantUser:   1,
+			wantLinks:  1,
+		},
+		{
+			name:          "identity lookup failure is returned",
+			identitiesErr: lookupFailure,
+			wantErr:       lookupFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &fakeUsers{users: tt.users}
+			identities := &fakeIdentities{identities: tt.identities, err: tt.identitiesErr}
+			s := &SAMLServiceImpl{identityRepo: identities, userRepo: users, userService: &fakeUserService{users: users}}
+
+			user, err := s.resolveUser(context.Background(), connection, assertion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && user.ID != tt.wantUser {
+				t.Errorf("got user %d, want %d", user.ID, tt.wantUser)
+			}
+			if len(identities.identities) != tt.wantLinks {
+				t.Errorf("got %d linked identities, want %d", len(identities.identities), tt.wantLinks)
+			}
+		})
+	}
+}
@@ -0,0 +1,381 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+	"github.com/yourusername/go-production-level/internal/utils"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidToken        = errors.New("invalid token")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// refreshTokenBytes is the amount of randomness in an opaque refresh token
+const refreshTokenBytes = 32
+
+// legacyWatermarkLimit separates watermarks stored in seconds from those in
+// milliseconds: as milliseconds it is early 1973, as seconds the year 5138
+const legacyWatermarkLimit = 100_000_000_000
+
+type TokenService interface {
+	IssueTokenPair(ctx context.Context, user *models.User, client ClientInfo) (*models.TokenPair, error)
+	IssueImpersonationToken(ctx context.Context, user, actor *models.User) (*models.ImpersonationToken, error)
+	Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error)
+	ValidateAccessToken(ctx context.Context, token string) (*utils.JWTClaims, error)
+	RevokeAccessToken(ctx context.Context, claims *utils.JWTClaims) error
+	RevokeRefreshToken(ctx context.Context, userID uint, refreshToken string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionResponse, error)
+	RevokeSession(ctx context.Context, userID, id uint) error
+}
+
+type TokenServiceImpl struct {
+	userRepo    repository.UserRepository
+	refreshRepo repository.RefreshTokenRepository
+	sessionRepo repository.SessionRepository
+	keys        *utils.KeyRing
+	redis       *redis.Client
+	config      *config.Config
+}
+
+func NewTokenService(userRepo repository.UserRepository, refreshRepo repository.RefreshTokenRepository, sessionRepo repository.SessionRepository, keys *utils.KeyRing, redis *redis.Client, config *config.Config) TokenService {
+	return &TokenServiceImpl{
+		userRepo:    userRepo,
+		refreshRepo: refreshRepo,
+		sessionRepo: sessionRepo,
+		keys:        keys,
+		redis:       redis,
+		config:      config,
+	}
+}
+
+// IssueTokenPair starts a new session, and with it a new refresh token family, for the user
+func (s *TokenServiceImpl) IssueTokenPair(ctx context.Context, user *models.User, client ClientInfo) (*models.TokenPair, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	err = s.sessionRepo.Create(ctx, &models.Session{
+		UserID:     user.ID,
+		FamilyID:   familyID,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(s.config.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, user, familyID)
+}
+
+// IssueImpersonationToken issues an access token for user that records actor as
+// the admin acting on their behalf. It starts no session and has no refresh token.
+func (s *TokenServiceImpl) IssueImpersonationToken(ctx context.Context, user, actor *models.User) (*models.ImpersonationToken, error) {
+	accessToken, err := utils.GenerateImpersonationToken(user, actor, s.config, s.keys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ImpersonationToken{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.config.ImpersonationTTL.Seconds()),
+		UserID:      user.ID,
+		ActorID:     actor.ID,
+	}, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that has already been
+// rotated revokes every token in its family, since either the client or an
+// attacker is holding a stolen copy.
+func (s *TokenServiceImpl) Refresh(ctx context.Context, refreshToken string) (*models.TokenPair, error) {
+	stored, err := s.refreshRepo.GetByHash(ctx, utils.HashToken(refreshToken))
+	// Tokens issued to OAuth2 clients are refreshed through the token endpoint
+	if err != nil || stored.OAuthClientID != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := rotateRefreshToken(ctx, s.refreshRepo, stored); err != nil {
+		if err == ErrRefreshTokenReused {
+			// The family is revoked, so the session ends with it
+			if err := s.revokeSession(ctx, stored.FamilyID); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil || user.SuspendedAt != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if err := s.sessionRepo.Extend(ctx, stored.FamilyID, time.Now().Add(s.config.RefreshTokenTTL)); err != nil {
+		return nil, err
+	}
+	return s.issue(ctx, user, stored.FamilyID)
+}
+
+// ValidateAccessToken verifies a JWT and checks it against the denylist, the
+// revoked sessions and the "tokens issued before" watermark of the user and,
+// for impersonation tokens, of the admin acting as the user
+func (s *TokenServiceImpl) ValidateAccessToken(ctx context.Context, token string) (*utils.JWTClaims, error) {
+	claims, err := utils.ValidateToken(token, s.config, s.keys)
+	if err != nil || claims.Purpose != "" {
+		return nil, ErrInvalidToken
+	}
+
+	var revokedKeys []string
+	if claims.ID != "" {
+		revokedKeys = append(revokedKeys, denylistKey(claims.ID))
+	}
+	if claims.SessionID != "" {
+		revokedKeys = append(revokedKeys, sessionRevokedKey(claims.SessionID))
+	}
+	if len(revokedKeys) > 0 {
+		revoked, err := s.redis.Exists(ctx, revokedKeys...).Result()
+		if err != nil {
+			return nil, err
+		}
+		if revoked > 0 {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	userIDs := []uint{claims.UserID}
+	if claims.Act != nil {
+		userIDs = append(userIDs, claims.Act.UserID)
+	}
+	for _, userID := range userIDs {
+		validAfter, err := s.redis.Get(ctx, validAfterKey(userID)).Int64()
+		if err != nil && err != redis.Nil {
+			return nil, err
+		}
+		// A token issued in the same millisecond as the revocation is rejected too.
+		// iat is parsed from a float and may come out a millisecond early, which
+		// can only reject a token issued right after the revocation.
+		if err == nil && (claims.IssuedAt == nil || claims.IssuedAt.UnixMilli() <= watermarkMillis(validAfter)) {
+			return nil, ErrTokenRevoked
+		}
+	}
+
+	if claims.SessionID != "" {
+		s.touchSession(ctx, claims.SessionID)
+	}
+
+	return claims, nil
+}
+
+// RevokeAccessToken adds the token's jti to the denylist until it would have
+// expired. A token issued for a session ends the whole session.
+func (s *TokenServiceImpl) RevokeAccessToken(ctx context.Context, claims *utils.JWTClaims) error {
+	if claims.ID == "" || claims.ExpiresAt == nil {
+		return ErrInvalidToken
+	}
+
+	if claims.SessionID != "" {
+		if err := s.revokeSession(ctx, claims.SessionID); err != nil {
+			return err
+		}
+	}
+
+	ttl := time.Until(claims.ExpiresAt.Time)
+	if ttl <= 0 {
+		return nil
+	}
+	return s.redis.Set(ctx, denylistKey(claims.ID), 1, ttl).Err()
+}
+
+// RevokeRefreshToken ends the session of a refresh token owned by the user
+func (s *TokenServiceImpl) RevokeRefreshToken(ctx context.Context, userID uint, refreshToken string) error {
+	stored, err := s.refreshRepo.GetByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil || stored.UserID != userID {
+		return ErrInvalidRefreshToken
+	}
+	return s.revokeSession(ctx, stored.FamilyID)
+}
+
+// RevokeAllForUser invalidates every access token issued to the user so far
+// and revokes all of their refresh tokens
+func (s *TokenServiceImpl) RevokeAllForUser(ctx context.Context, userID uint) error {
+	// The watermark only needs to live until the tokens issued before it have expired
+	err := s.redis.Set(ctx, validAfterKey(userID), time.Now().UnixMilli(), s.revocationTTL()).Err()
+	if err != nil {
+		return err
+	}
+	if err := s.sessionRepo.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeAllForUser(ctx, userID)
+}
+
+// ListSessions returns the user's active sessions, flagging the one the request was made with
+func (s *TokenServiceImpl) ListSessions(ctx context.Context, userID uint, currentSessionID string) ([]models.SessionResponse, error) {
+	sessions, err := s.sessionRepo.ListActiveByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	responses := make([]models.SessionResponse, len(sessions))
+	for i, session := range sessions {
+		responses[i] = models.SessionResponse{
+			ID:         session.ID,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			Location:   session.Location,
+			Current:    session.FamilyID == currentSessionID,
+		}
+	}
+	return responses, nil
+}
+
+// RevokeSession signs the user out of one of their sessions. Its access
+// tokens stop working immediately and its refresh tokens are revoked.
+func (s *TokenServiceImpl) RevokeSession(ctx context.Context, userID, id uint) error {
+	session, err := s.sessionRepo.GetActive(ctx, id, userID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+	return s.revokeSession(ctx, session.FamilyID)
+}
+
+// revokeSession rejects the access tokens of a session until they would have
+// expired anyway, then revokes its refresh token family
+func (s *TokenServiceImpl) revokeSession(ctx context.Context, familyID string) error {
+	err := s.redis.Set(ctx, sessionRevokedKey(familyID), 1, s.revocationTTL()).Err()
+	if err != nil {
+		return err
+	}
+	if err := s.sessionRepo.Revoke(ctx, familyID); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeFamily(ctx, familyID)
+}
+
+// revocationTTL is the longest an access token lives, which is how long a
+// revocation must be kept. Impersonation tokens may outlive regular ones.
+func (s *TokenServiceImpl) revocationTTL() time.Duration {
+	if s.config.ImpersonationTTL > s.config.AccessTokenTTL {
+		return s.config.ImpersonationTTL
+	}
+	return s.config.AccessTokenTTL
+}
+
+// touchSession records activity on a session at most once per lastUsedResolution.
+// Failing to record it must not fail the request.
+func (s *TokenServiceImpl) touchSession(ctx context.Context, familyID string) {
+	fresh, err := s.redis.SetNX(ctx, sessionSeenKey(familyID), 1, lastUsedResolution).Result()
+	if err != nil || !fresh {
+		return
+	}
+	if err := s.sessionRepo.TouchLastSeen(ctx, familyID, time.Now()); err != nil {
+		log.Printf("Failed to record session activity: %v", err)
+	}
+}
+
+func (s *TokenServiceImpl) issue(ctx context.Context, user *models.User, familyID string) (*models.TokenPair, error) {
+	claims, err := utils.NewClaims(user, s.config, s.config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	claims.SessionID = familyID
+
+	accessToken, err := s.keys.Sign(claims)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := utils.GenerateRandomToken(refreshTokenBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.refreshRepo.Create(ctx, &models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: time.Now().Add(s.config.RefreshTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// rotateRefreshToken marks a stored refresh token as used so it cannot be
+// presented again. A token that was already rotated revokes its whole family.
+func rotateRefreshToken(ctx context.Context, repo repository.RefreshTokenRepository, stored *models.RefreshToken) error {
+	if stored.UsedAt != nil {
+		if err := repo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return ErrInvalidRefreshToken
+	}
+
+	rotated, err := repo.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return err
+	}
+	if !rotated {
+		// Lost a race with another refresh of the same token
+		if err := repo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			return err
+		}
+		return ErrRefreshTokenReused
+	}
+	return nil
+}
+
+func denylistKey(jti string) string {
+	return fmt.Sprintf("auth:denylist:%s", jti)
+}
+
+func validAfterKey(userID uint) string {
+	return fmt.Sprintf("auth:valid_after:%d", userID)
+}
+
+// watermarkMillis returns a valid-after watermark in milliseconds. Watermarks
+// stored in seconds by earlier versions cover the whole of their second.
+func watermarkMillis(validAfter int64) int64 {
+	if validAfter < legacyWatermarkLimit {
+		return validAfter*1000 + 999
+	}
+	return validAfter
+}
+
+func sessionRevokedKey(sessionID string) string {
+	return fmt.Sprintf("auth:session:%s:revoked", sessionID)
+}
+
+func sessionSeenKey(sessionID string) string {
+	return fmt.Sprintf("auth:session:%s:seen", sessionID)
+}
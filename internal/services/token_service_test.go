@@ -0,0 +1,22 @@
+package services
+
+import "testing"
+
+func TestWatermarkMillis(t *testing.T) {
+	tests := []struct {
+		name       string
+		validAfter int64
+		want       int64
+	}{
+		{"milliseconds are kept", 1700000000123, 1700000000123},
+		{"seconds cover their whole second", 1700000000, 1700000000999},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := watermarkMillis(tt.validAfter); got != tt.want {
+				t.Errorf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
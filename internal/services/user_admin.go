@@ -0,0 +1,246 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/repository"
+)
+
+// Search returns users matching the filter for the admin API
+func (s *UserServiceImpl) Search(ctx context.Context, filter repository.UserFilter, offset, limit int) ([]models.AdminUserResponse, int64, error) {
+	users, total, err := s.repo.Search(ctx, filter, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	userResponses := make([]models.AdminUserResponse, len(users))
+	for i := range users {
+		userResponses[i] = newAdminUserResponse(&users[i])
+	}
+
+	return userResponses, total, nil
+}
+
+// GetAccount returns a user with their account state, including soft-deleted users
+func (s *UserServiceImpl) GetAccount(ctx context.Context, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.repo.GetByIDUnscoped(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp := newAdminUserResponse(user)
+	return &resp, nil
+}
+
+// ChangeRole assigns a new role and signs the user out so the role takes effect immediately
+func (s *UserServiceImpl) ChangeRole(ctx context.Context, id uint, role string) (*models.AdminUserResponse, error) {
+	if err := s.ensureRoleExists(ctx, role); err != nil {
+		return nil, err
+	}
+
+	return s.updateAccount(ctx, id, true, func(user *models.User) {
+		user.Role = role
+	})
+}
+
+// Suspend blocks the user from logging in and revokes their tokens
+func (s *UserServiceImpl) Suspend(ctx context.Context, id uint, reason string) (*models.AdminUserResponse, error) {
+	return s.updateAccount(ctx, id, true, func(user *models.User) {
+		now := time.Now()
+		user.SuspendedAt = &now
+		user.SuspensionReason = reason
+	})
+}
+
+func (s *UserServiceImpl) Unsuspend(ctx context.Context, id uint) (*models.AdminUserResponse, error) {
+	return s.updateAccount(ctx, id, false, func(user *models.User) {
+		user.SuspendedAt = nil
+		user.SuspensionReason = ""
+	})
+}
+
+// ForcePasswordReset revokes the user's tokens and refuses logins until the password is changed
+func (s *UserServiceImpl) ForcePasswordReset(ctx context.Context, id uint) (*models.AdminUserResponse, error) {
+	return s.updateAccount(ctx, id, true, func(user *models.User) {
+		user.PasswordResetRequired = true
+	})
+}
+
+// Restore brings back a soft-deleted user
+func (s *UserServiceImpl) Restore(ctx context.Context, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.repo.GetByIDUnscoped(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.DeletedAt.Valid {
+		return nil, ErrUserNotDeleted
+	}
+
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return nil, err
+	}
+	user.DeletedAt.Valid = false
+
+	resp := newAdminUserResponse(user)
+	return &resp, nil
+}
+
+// Purge permanently removes a user together with every record that belongs to
+// them, such as sessions, tokens, API keys and linked identities. Audit logs are
+// kept.
+func (s *UserServiceImpl) Purge(ctx context.Context, id uint) error {
+	if _, err := s.repo.GetByIDUnscoped(ctx, id); err != nil {
+		return ErrUserNotFound
+	}
+
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		for _, repo := range s.userDataRepos {
+			if err := repo.DeleteByUser(ctx, id); err != nil {
+				return err
+			}
+		}
+		if err := s.passwordPolicy.Forget(ctx, id); err != nil {
+			return err
+		}
+		return s.repo.Purge(ctx, id)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	// Access tokens are only invalidated once the user is gone, since Redis
+	// writes cannot be rolled back with the transaction
+	if err := s.tokenService.RevokeAllForUser(ctx, id); err != nil {
+		return err
+	}
+
+	// Invalidate cache
+	s.redis.Del(ctx, fmt.Sprintf("user:%d", id))
+	return nil
+}
+
+func (s *UserServiceImpl) LoginHistory(ctx context.Context, id uint, offset, limit int) ([]models.LoginEvent, error) {
+	if _, err := s.repo.GetByIDUnscoped(ctx, id); err != nil {
+		return nil, ErrUserNotFound
+	}
+	return s.loginEventRepo.ListByUser(ctx, id, offset, limit)
+}
+
+// Unlock lifts a login lockout of the user
+func (s *UserServiceImpl) Unlock(ctx context.Context, id, actorID uint) (*models.AdminUserResponse, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.loginGuard.Unlock(ctx, user); err != nil {
+		return nil, err
+	}
+	s.auditService.Record(ctx, &models.AuditLog{
+		Action:  models.AuditUserUnlock,
+		ActorID: &actorID,
+		UserID:  &user.ID,
+	})
+
+	resp := newAdminUserResponse(user)
+	return &resp, nil
+}
+
+// Impersonate records an impersonation in the audit log and issues a short-lived
+// token for acting as the user. Suspended users, the admin themselves and users whose role grants
+// admin access cannot be impersonated, so impersonation never widens privileges.
+func (s *UserServiceImpl) Impersonate(ctx context.Context, id, actorID uint, reason string, client ClientInfo) (*models.ImpersonationToken, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	actor, err := s.repo.GetByID(ctx, actorID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	if user.ID == actor.ID || user.SuspendedAt != nil {
+		return nil, ErrCannotImpersonate
+	}
+	privileged, err := s.rbacService.HasPermission(ctx, user.Role, models.PermissionAdminAccess)
+	if err != nil {
+		return nil, err
+	}
+	if privileged {
+		return nil, ErrCannotImpersonate
+	}
+
+	// No token is issued unless the impersonation is in the audit log
+	err = s.auditService.RecordRequired(ctx, &models.AuditLog{
+		Action:  models.AuditUserImpersonate,
+		ActorID: &actor.ID,
+		UserID:  &user.ID,
+		IP:      client.IP,
+		Details: fmt.Sprintf("for %s: %s", s.config.ImpersonationTTL, reason),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.tokenService.IssueImpersonationToken(ctx, user, actor)
+}
+
+// updateAccount loads and locks a user, applies change and saves it in one
+// transaction, then optionally revokes every token issued to the user
+func (s *UserServiceImpl) updateAccount(ctx context.Context, id uint, revokeTokens bool, change func(user *models.User)) (*models.AdminUserResponse, error) {
+	var user *models.User
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrUserNotFound
+		}
+		change(user)
+		return s.repo.Update(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if revokeTokens {
+		if err := s.tokenService.RevokeAllForUser(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Invalidate cache
+	s.redis.Del(ctx, fmt.Sprintf("user:%d", id))
+
+	resp := newAdminUserResponse(user)
+	return &resp, nil
+}
+
+func newAdminUserResponse(user *models.User) models.AdminUserResponse {
+	resp := models.AdminUserResponse{
+		ID:                    user.ID,
+		CreatedAt:             user.CreatedAt,
+		UpdatedAt:             user.UpdatedAt,
+		Email:                 user.Email,
+		Name:                  user.Name,
+		Role:                  user.Role,
+		EmailVerifiedAt:       user.EmailVerifiedAt,
+		SuspendedAt:           user.SuspendedAt,
+		SuspensionReason:      user.SuspensionReason,
+		PasswordResetRequired: user.PasswordResetRequired,
+	}
+	if user.DeletedAt.Valid {
+		deletedAt := user.DeletedAt.Time
+		resp.DeletedAt = &deletedAt
+	}
+	return resp
+}
@@ -4,60 +4,153 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"log"
 	"strconv"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/yourusername/go-production-level/config"
 	"github.com/yourusername/go-production-level/internal/models"
+	"github.com/yourusername/go-production-level/internal/query"
 	"github.com/yourusername/go-production-level/internal/repository"
 	"github.com/yourusername/go-production-level/internal/utils"
-	"golang.org/x/crypto/bcrypt"
 )
 
 var (
-	ErrUserNotFound       = errors.New("user not found")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrEmailExists        = errors.New("email already exists")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrEmailExists           = errors.New("email already exists")
+	ErrAccountSuspended      = errors.New("account is suspended")
+	ErrPasswordResetRequired = errors.New("password reset required")
+	ErrUserNotDeleted        = errors.New("user is not deleted")
+	ErrCannotImpersonate     = errors.New("user cannot be impersonated")
+	ErrInvalidCursor         = errors.New("invalid cursor")
+	ErrCursorSort            = errors.New("sort is not supported with cursor pagination")
+	ErrCurrentPassword       = errors.New("current password is missing or incorrect")
 )
 
+// ClientInfo describes the client making a request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
 type UserService interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id uint) (*models.UserResponse, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
+	UpdateOwn(ctx context.Context, user *models.User, currentPassword string) error
 	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context, offset, limit int) ([]models.UserResponse, error)
-	Login(ctx context.Context, email, password string) (string, error)
+	List(ctx context.Context, q *query.Query, page, limit int, withTotal bool) (*models.UserPage, error)
+	ListByCursor(ctx context.Context, q *query.Query, cursor string, limit int, withTotal bool) (*models.UserPage, error)
+	Login(ctx context.Context, email, password string, client ClientInfo) (*models.LoginResponse, error)
+	CompleteLogin(ctx context.Context, user *models.User, client ClientInfo) (*models.LoginResponse, error)
+	ChangePassword(ctx context.Context, id uint, password string) error
+	CheckPassword(ctx context.Context, id uint, password string) error
+
+	// Admin operations
+	Search(ctx context.Context, filter repository.UserFilter, offset, limit int) ([]models.AdminUserResponse, int64, error)
+	GetAccount(ctx context.Context, id uint) (*models.AdminUserResponse, error)
+	ChangeRole(ctx context.Context, id uint, role string) (*models.AdminUserResponse, error)
+	Suspend(ctx context.Context, id uint, reason string) (*models.AdminUserResponse, error)
+	Unsuspend(ctx context.Context, id uint) (*models.AdminUserResponse, error)
+	ForcePasswordReset(ctx context.Context, id uint) (*models.AdminUserResponse, error)
+	Restore(ctx context.Context, id uint) (*models.AdminUserResponse, error)
+	Purge(ctx context.Context, id uint) error
+	LoginHistory(ctx context.Context, id uint, offset, limit int) ([]models.LoginEvent, error)
+	Unlock(ctx context.Context, id, actorID uint) (*models.AdminUserResponse, error)
+	Impersonate(ctx context.Context, id, actorID uint, reason string, client ClientInfo) (*models.ImpersonationToken, error)
 }
 
 type UserServiceImpl struct {
-	repo   repository.UserRepository
-	redis  *redis.Client
-	config *config.Config
+	repo                repository.UserRepository
+	loginEventRepo      repository.LoginEventRepository
+	userDataRepos       []repository.UserDataRepository
+	tokenService        TokenService
+	rbacService         RBACService
+	mfaService          MFAService
+	verificationService EmailVerificationService
+	loginGuard          LoginGuard
+	auditService        AuditService
+	hasher              utils.PasswordHasher
+	passwordPolicy      PasswordPolicy
+	txManager           repository.TxManager
+	redis               *redis.Client
+	config              *config.Config
 }
 
-func NewUserService(repo repository.UserRepository, redis *redis.Client, config *config.Config) UserService {
+func NewUserService(repo repository.UserRepository, loginEventRepo repository.LoginEventRepository, userDataRepos []repository.UserDataRepository, tokenService TokenService, rbacService RBACService, mfaService MFAService, verificationService EmailVerificationService, loginGuard LoginGuard, auditService AuditService, hasher utils.PasswordHasher, passwordPolicy PasswordPolicy, txManager repository.TxManager, redis *redis.Client, config *config.Config) UserService {
 	return &UserServiceImpl{
-		repo:   repo,
-		redis:  redis,
-		config: config,
+		repo:                repo,
+		loginEventRepo:      loginEventRepo,
+		userDataRepos:       userDataRepos,
+		tokenService:        tokenService,
+		rbacService:         rbacService,
+		mfaService:          mfaService,
+		verificationService: verificationService,
+		loginGuard:          loginGuard,
+		auditService:        auditService,
+		hasher:              hasher,
+		passwordPolicy:      passwordPolicy,
+		txManager:           txManager,
+		redis:               redis,
+		config:              config,
 	}
 }
 
 func (s *UserServiceImpl) Create(ctx context.Context, user *models.User) error {
 	// Check if email already exists
-	existingUser, err := s.repo.GetByEmail(ctx, user.Email)
-	if err == nil && existingUser != nil {
+	_, err := s.repo.GetByEmail(ctx, user.Email)
+	if err == nil {
 		return ErrEmailExists
 	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	if err := s.ensureRoleExists(ctx, user.Role); err != nil {
+		return err
+	}
+
+	// Accounts created from an external identity may have no password
+	if user.Password != "" {
+		if err := s.passwordPolicy.Check(ctx, user, user.Password); err != nil {
+			return err
+		}
+		hashedPassword, err := s.hasher.Hash(user.Password)
+		if err != nil {
+			return err
+		}
+		user.Password = hashedPassword
+	}
+	// The verification email is only sent once the account is committed
+	err = s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, user); err != nil {
+			return err
+		}
+		if user.Password != "" {
+			s.passwordPolicy.Remember(ctx, user.ID, user.Password)
+		}
+		return nil
+	})
+	// The address may have been taken since it was checked
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrEmailExists
+	}
 	if err != nil {
 		return err
 	}
-	user.Password = string(hashedPassword)
-	return s.repo.Create(ctx, user)
+
+	if user.EmailVerified() {
+		return nil
+	}
+
+	// The account is usable without the email; a failed delivery can be retried through resend
+	if err := s.verificationService.SendVerification(ctx, user); err != nil {
+		log.Printf("Failed to send verification email to user %d: %v", user.ID, err)
+	}
+	return nil
 }
 
 func (s *UserServiceImpl) GetByID(ctx context.Context, id uint) (*models.UserResponse, error) {
@@ -72,9 +165,12 @@ func (s *UserServiceImpl) GetByID(ctx context.Context, id uint) (*models.UserRes
 
 	// If not in cache, get from database
 	user, err := s.repo.GetByID(ctx, id)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrUserNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	userResp = models.UserResponse{
 		ID:        user.ID,
@@ -92,26 +188,107 @@ func (s *UserServiceImpl) GetByID(ctx context.Context, id uint) (*models.UserRes
 
 func (s *UserServiceImpl) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	user, err := s.repo.GetByEmail(ctx, email)
-	if err != nil {
+	if errors.Is(err, repository.ErrNotFound) {
 		return nil, ErrUserNotFound
 	}
+	if err != nil {
+		return nil, err
+	}
 	return user, nil
 }
 
+// Update applies the profile fields of user to the stored account.
+// Account state such as suspension is only changed through the admin operations.
 func (s *UserServiceImpl) Update(ctx context.Context, user *models.User) error {
-	if user.Password != "" {
-		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+	return s.update(ctx, user, nil)
+}
+
+// UpdateOwn applies a user's changes to their own account. Changing the email
+// or password requires the current password, so that a stolen session cannot
+// take the account over. Accounts created through an identity provider without
+// a password have none to confirm.
+func (s *UserServiceImpl) UpdateOwn(ctx context.Context, user *models.User, currentPassword string) error {
+	return s.update(ctx, user, &currentPassword)
+}
+
+// update applies user to the stored account, checking currentPassword when set
+func (s *UserServiceImpl) update(ctx context.Context, user *models.User, currentPassword *string) error {
+	if err := s.ensureRoleExists(ctx, user.Role); err != nil {
+		return err
+	}
+
+	var passwordChanged, emailChanged bool
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		// Locked so that admin changes made meanwhile, such as a suspension,
+		// are not overwritten when the whole record is saved
+		existing, err := s.repo.GetByIDForUpdate(ctx, user.ID)
 		if err != nil {
+			return ErrUserNotFound
+		}
+
+		// Sending the current password again leaves it unchanged
+		passwordChanged = user.Password != ""
+		if passwordChanged {
+			if same, _ := s.hasher.Verify(user.Password, existing.Password); same {
+				passwordChanged = false
+			}
+		}
+		emailChanged = existing.Email != user.Email
+		if currentPassword != nil && (passwordChanged || emailChanged) && existing.Password != "" {
+			if match, _ := s.hasher.Verify(*currentPassword, existing.Password); !match {
+				return ErrCurrentPassword
+			}
+		}
+
+		if passwordChanged {
+			if err := s.passwordPolicy.Check(ctx, existing, user.Password); err != nil {
+				return err
+			}
+			if err := s.setPassword(existing, user.Password); err != nil {
+				return err
+			}
+		}
+		// The current address stays in use until the new one is confirmed
+		if emailChanged {
+			if _, err := s.repo.GetByEmail(ctx, user.Email); err == nil {
+				return ErrEmailExists
+			} else if !errors.Is(err, repository.ErrNotFound) {
+				return err
+			}
+			existing.PendingEmail = user.Email
+		}
+		existing.Name = user.Name
+		existing.Role = user.Role
+
+		if err := s.repo.Update(ctx, existing); err != nil {
 			return err
 		}
-		user.Password = string(hashedPassword)
+		if passwordChanged {
+			s.passwordPolicy.Remember(ctx, existing.ID, existing.Password)
+		}
+		*user = *existing
+		return nil
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return ErrEmailExists
 	}
-
-	err := s.repo.Update(ctx, user)
 	if err != nil {
 		return err
 	}
 
+	if emailChanged {
+		if err := s.verificationService.SendEmailChange(ctx, user); err != nil {
+			log.Printf("Failed to send email change confirmation to user %d: %v", user.ID, err)
+		}
+	}
+
+	// Sign out everywhere after a password change
+	if passwordChanged {
+		if err := s.tokenService.RevokeAllForUser(ctx, user.ID); err != nil {
+			return err
+		}
+	}
+
 	// Invalidate cache
 	s.redis.Del(ctx, "user:"+strconv.FormatUint(uint64(user.ID), 10))
 	return nil
@@ -119,24 +296,136 @@ func (s *UserServiceImpl) Update(ctx context.Context, user *models.User) error {
 
 func (s *UserServiceImpl) Delete(ctx context.Context, id uint) error {
 	err := s.repo.Delete(ctx, id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrUserNotFound
+	}
 	if err != nil {
 		return err
 	}
 
 	// Invalidate cache
-	s.redis.Del(ctx, "user:"+string(id))
+	s.redis.Del(ctx, "user:"+strconv.FormatUint(uint64(id), 10))
 	return nil
 }
 
-func (s *UserServiceImpl) List(ctx context.Context, offset, limit int) ([]models.UserResponse, error) {
-	users, err := s.repo.List(ctx, offset, limit)
+// usersCursorScope names the user listing in its cursors
+const usersCursorScope = "users"
+
+// userCursor is the position encoded in the cursors of the user listing
+type userCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+	Backward  bool      `json:"b,omitempty"`
+}
+
+// List returns a page of the users matching q using offset pagination
+func (s *UserServiceImpl) List(ctx context.Context, q *query.Query, page, limit int, withTotal bool) (*models.UserPage, error) {
+	limit = s.pageLimit(limit)
+	users, err := s.repo.List(ctx, q, (page-1)*limit, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.UserPage{Users: newUserResponses(users), Page: page, Limit: limit}
+	if withTotal {
+		if result.Total, err = s.countUsers(ctx, q); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// ListByCursor returns a page of the users matching q in order of creation
+// using keyset pagination. An empty cursor starts at the first user. Unlike
+// offsets, cursors stay stable while users are added.
+func (s *UserServiceImpl) ListByCursor(ctx context.Context, q *query.Query, cursor string, limit int, withTotal bool) (*models.UserPage, error) {
+	if q != nil && len(q.Sorts) > 0 {
+		return nil, ErrCursorSort
+	}
+	limit = s.pageLimit(limit)
+
+	var position *repository.UserCursor
+	backward := false
+	if cursor != "" {
+		var decoded userCursor
+		if err := utils.DecodeCursor(usersCursorScope, cursor, s.config.PaginationCursorKey, &decoded); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		position = &repository.UserCursor{CreatedAt: decoded.CreatedAt, ID: decoded.ID}
+		backward = decoded.Backward
+	}
+
+	// One extra user tells whether there is another page in the direction of travel
+	users, err := s.repo.ListByCursor(ctx, q, position, backward, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	more := len(users) > limit
+	if more && backward {
+		users = users[1:]
+	} else if more {
+		users = users[:limit]
+	}
+
+	// The cursors of an empty page continue from where the request left off
+	first, last := position, position
+	if len(users) > 0 {
+		first = &repository.UserCursor{CreatedAt: users[0].CreatedAt, ID: users[0].ID}
+		last = &repository.UserCursor{CreatedAt: users[len(users)-1].CreatedAt, ID: users[len(users)-1].ID}
+	}
+
+	hasNext, hasPrev := more, position != nil
+	if backward {
+		hasNext, hasPrev = position != nil, more
+	}
+
+	result := &models.UserPage{Users: newUserResponses(users), Limit: limit}
+	if hasNext {
+		if result.NextCursor, err = s.encodeUserCursor(last, false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if result.PrevCursor, err = s.encodeUserCursor(first, true); err != nil {
+			return nil, err
+		}
+	}
+	if withTotal {
+		if result.Total, err = s.countUsers(ctx, q); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (s *UserServiceImpl) encodeUserCursor(position *repository.UserCursor, backward bool) (string, error) {
+	return utils.EncodeCursor(usersCursorScope, userCursor{
+		CreatedAt: position.CreatedAt,
+		ID:        position.ID,
+		Backward:  backward,
+	}, s.config.PaginationCursorKey)
+}
+
+// pageLimit caps the requested page size at the configured maximum
+func (s *UserServiceImpl) pageLimit(limit int) int {
+	if s.config.PaginationMaxLimit > 0 && limit > s.config.PaginationMaxLimit {
+		return s.config.PaginationMaxLimit
+	}
+	return limit
+}
+
+func (s *UserServiceImpl) countUsers(ctx context.Context, q *query.Query) (*int64, error) {
+	total, err := s.repo.Count(ctx, q)
 	if err != nil {
 		return nil, err
 	}
+	return &total, nil
+}
 
-	userResponses := make([]models.UserResponse, len(users))
+func newUserResponses(users []models.User) []models.UserResponse {
+	responses := make([]models.UserResponse, len(users))
 	for i, user := range users {
-		userResponses[i] = models.UserResponse{
+		responses[i] = models.UserResponse{
 			ID:        user.ID,
 			CreatedAt: user.CreatedAt,
 			Email:     user.Email,
@@ -144,25 +433,163 @@ func (s *UserServiceImpl) List(ctx context.Context, offset, limit int) ([]models
 			Role:      user.Role,
 		}
 	}
+	return responses
+}
+
+// ChangePassword sets a new password, clears a forced reset and signs the user out everywhere
+func (s *UserServiceImpl) ChangePassword(ctx context.Context, id uint, password string) error {
+	err := s.txManager.WithinTransaction(ctx, func(ctx context.Context) error {
+		user, err := s.repo.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return ErrUserNotFound
+		}
+
+		if err := s.passwordPolicy.Check(ctx, user, password); err != nil {
+			return err
+		}
+		if err := s.setPassword(user, password); err != nil {
+			return err
+		}
+		if err := s.repo.Update(ctx, user); err != nil {
+			return err
+		}
+		s.passwordPolicy.Remember(ctx, user.ID, user.Password)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Tokens are revoked once the new password is committed
+	return s.tokenService.RevokeAllForUser(ctx, id)
+}
 
-	return userResponses, nil
+// CheckPassword reports whether password may be set for the user, returning
+// a *PasswordPolicyError listing the violated rules when it may not
+func (s *UserServiceImpl) CheckPassword(ctx context.Context, id uint, password string) error {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return ErrUserNotFound
+	}
+	return s.passwordPolicy.Check(ctx, user, password)
 }
 
-func (s *UserServiceImpl) Login(ctx context.Context, email, password string) (string, error) {
+// Login checks the password and issues tokens. When the user has two-factor
+// authentication enabled it returns an MFA token to exchange with a code instead.
+// Repeated failures lock the account or the client IP and return a *LockoutError.
+func (s *UserServiceImpl) Login(ctx context.Context, email, password string, client ClientInfo) (*models.LoginResponse, error) {
+	if err := s.loginGuard.Check(ctx, email, client.IP); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
-		return "", ErrInvalidCredentials
+		s.loginGuard.RecordFailure(ctx, email, client.IP, nil)
+		return nil, ErrInvalidCredentials
+	}
+
+	match, err := s.hasher.Verify(password, user.Password)
+	if err != nil {
+		log.Printf("Failed to verify password of user %d: %v", user.ID, err)
+	}
+	if !match {
+		s.recordLogin(ctx, user.ID, client, false, "invalid_password")
+		s.loginGuard.RecordFailure(ctx, email, client.IP, &user.ID)
+		return nil, ErrInvalidCredentials
+	}
+	s.loginGuard.RecordSuccess(ctx, email)
+	s.rehashPassword(ctx, user, password)
+
+	return s.CompleteLogin(ctx, user, client)
+}
+
+// CompleteLogin finishes a login for a user whose identity has been established,
+// by password or an external identity provider. It enforces the account state and
+// two-factor authentication before issuing tokens.
+func (s *UserServiceImpl) CompleteLogin(ctx context.Context, user *models.User, client ClientInfo) (*models.LoginResponse, error) {
+	if user.SuspendedAt != nil {
+		s.recordLogin(ctx, user.ID, client, false, "suspended")
+		return nil, ErrAccountSuspended
+	}
+	if s.config.RequireEmailVerification && !user.EmailVerified() {
+		s.recordLogin(ctx, user.ID, client, false, "email_not_verified")
+		return nil, ErrEmailNotVerified
+	}
+	if user.PasswordResetRequired {
+		s.recordLogin(ctx, user.ID, client, false, "password_reset_required")
+		return nil, ErrPasswordResetRequired
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", ErrInvalidCredentials
+	if user.MFAEnabled() {
+		mfaToken, err := s.mfaService.CreateChallenge(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+		s.recordLogin(ctx, user.ID, client, true, "mfa_pending")
+		return &models.LoginResponse{MFARequired: true, MFAToken: mfaToken}, nil
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateToken(user, s.config)
+	s.recordLogin(ctx, user.ID, client, true, "")
+
+	// Issue access and refresh tokens
+	tokens, err := s.tokenService.IssueTokenPair(ctx, user, client)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return &models.LoginResponse{TokenPair: tokens}, nil
+}
 
-	return token, nil
+// recordLogin stores a login attempt. Failing to record history must not block the login itself.
+func (s *UserServiceImpl) recordLogin(ctx context.Context, userID uint, client ClientInfo, success bool, reason string) {
+	event := &models.LoginEvent{
+		UserID:    userID,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		Success:   success,
+		Reason:    reason,
+	}
+	if err := s.loginEventRepo.Create(ctx, event); err != nil {
+		log.Printf("Failed to record login event for user %d: %v", userID, err)
+	}
+}
+
+func (s *UserServiceImpl) ensureRoleExists(ctx context.Context, role string) error {
+	exists, err := s.rbacService.RoleExists(ctx, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// rehashPassword upgrades a hash made with an older algorithm or weaker
+// parameters, using the plaintext the user just logged in with. A failed
+// upgrade is retried on the next login and must not block this one.
+func (s *UserServiceImpl) rehashPassword(ctx context.Context, user *models.User, password string) {
+	if !s.hasher.NeedsRehash(user.Password) {
+		return
+	}
+
+	hashed, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.repo.UpdatePasswordHash(ctx, user.ID, hashed)
+	}
+	if err != nil {
+		log.Printf("Failed to upgrade password hash of user %d: %v", user.ID, err)
+		return
+	}
+	user.Password = hashed
+}
+
+// setPassword replaces the user's password and clears a forced reset
+func (s *UserServiceImpl) setPassword(user *models.User, password string) error {
+	hashed, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	user.PasswordResetRequired = false
+	return nil
 }
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachCorpus looks passwords up in a local copy of a breached-password corpus
+// in the k-anonymity range format of Have I Been Pwned. The corpus directory
+// holds one file per 5-character SHA-1 prefix, named <PREFIX>.txt, with a
+// <SUFFIX>:<count> line for every breached hash with that prefix. A lookup
+// only reads the range file of its prefix.
+type BreachCorpus struct {
+	dir string
+}
+
+// NewBreachCorpus opens the corpus in dir
+func NewBreachCorpus(dir string) (*BreachCorpus, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password corpus: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password corpus %s is not a directory", dir)
+	}
+	return &BreachCorpus{dir: dir}, nil
+}
+
+// Count returns how often the password appears in the corpus, 0 when it was never breached
+func (c *BreachCorpus) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read breached password range %s: %w", prefix, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			// Padding entries carry a count of 0
+			return 0, nil
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read breached password range %s: %w", prefix, err)
+	}
+	return 0, nil
+}
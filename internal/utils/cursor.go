@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrInvalidCursor is returned for cursors that were altered or issued for another listing
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// EncodeCursor serializes a pagination position into an opaque cursor signed
+// with key. The scope names the listing, so a cursor cannot be replayed
+// against another one.
+func EncodeCursor(scope string, position interface{}, key string) (string, error) {
+	data, err := json.Marshal(position)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + SignToken(scope+"."+payload, key), nil
+}
+
+// DecodeCursor verifies a cursor made by EncodeCursor for scope and decodes its position
+func DecodeCursor(scope, cursor, key string, position interface{}) error {
+	payload, signature, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return ErrInvalidCursor
+	}
+	if subtle.ConstantTimeCompare([]byte(SignToken(scope+"."+payload, key)), []byte(signature)) != 1 {
+		return ErrInvalidCursor
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, position); err != nil {
+		return ErrInvalidCursor
+	}
+	return nil
+}
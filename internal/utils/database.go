@@ -1,18 +1,62 @@
 package utils
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/yourusername/go-production-level/config"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+// queryCancelKey stores the cancel function of a statement's query timeout
+const queryCancelKey = "query_timeout:cancel"
+
 func InitDatabase(cfg *config.Config) (*gorm.DB, error) {
 	db, err := gorm.Open(postgres.Open(cfg.DatabaseUrl), &gorm.Config{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
 
+	if cfg.DBQueryTimeout > 0 {
+		if err := registerQueryTimeout(db, cfg.DBQueryTimeout); err != nil {
+			return nil, fmt.Errorf("failed to register query timeout: %w", err)
+		}
+	}
+
 	return db, nil
 }
+
+// registerQueryTimeout bounds every statement by timeout on top of the deadline
+// of the context it runs with. The results of Row and Rows are read after the
+// callbacks have returned, so their timeout is not cancelled when the statement
+// finishes; it covers reading the results too and is released when it expires.
+func registerQueryTimeout(db *gorm.DB, timeout time.Duration) error {
+	start := func(tx *gorm.DB) {
+		ctx, cancel := context.WithTimeout(tx.Statement.Context, timeout)
+		tx.Statement.Context = ctx
+		tx.InstanceSet(queryCancelKey, cancel)
+	}
+	finish := func(tx *gorm.DB) {
+		if cancel, ok := tx.InstanceGet(queryCancelKey); ok {
+			cancel.(context.CancelFunc)()
+		}
+	}
+
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("*").Register("query_timeout:start", start),
+		callbacks.Create().After("*").Register("query_timeout:finish", finish),
+		callbacks.Query().Before("*").Register("query_timeout:start", start),
+		callbacks.Query().After("*").Register("query_timeout:finish", finish),
+		callbacks.Update().Before("*").Register("query_timeout:start", start),
+		callbacks.Update().After("*").Register("query_timeout:finish", finish),
+		callbacks.Delete().Before("*").Register("query_timeout:start", start),
+		callbacks.Delete().After("*").Register("query_timeout:finish", finish),
+		callbacks.Raw().Before("*").Register("query_timeout:start", start),
+		callbacks.Raw().After("*").Register("query_timeout:finish", finish),
+		callbacks.Row().Before("*").Register("query_timeout:start", start),
+	)
+}
@@ -0,0 +1,195 @@
+package utils
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// slowDriver answers statements containing pg_sleep once their context ends,
+// giving up after a second, and every other query with a single row holding 1
+type slowDriver struct{}
+
+func (slowDriver) Open(name string) (driver.Conn, error) {
+	return slowConn{}, nil
+}
+
+type slowConn struct{}
+
+func (slowConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+
+func (slowConn) Close() error {
+	return nil
+}
+
+func (slowConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+func (slowConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := wait(ctx, query); err != nil {
+		return nil, err
+	}
+	return &oneRow{}, nil
+}
+
+func (slowConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := wait(ctx, query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func wait(ctx context.Context, query string) error {
+	if !strings.Contains(query, "pg_sleep") {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Second):
+		return errors.New("statement was not aborted")
+	}
+}
+
+type oneRow struct {
+	done bool
+}
+
+func (r *oneRow) Columns() []string {
+	return []string{"value"}
+}
+
+func (r *oneRow) Close() error {
+	return nil
+}
+
+func (r *oneRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+func init() {
+	sql.Register("slow", slowDriver{})
+}
+
+func openSlowDatabase(t *testing.T, timeout time.Duration) *gorm.DB {
+	t.Helper()
+	conn, err := sql.Open("slow", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{
+		SkipDefaultTransaction: true,
+		Logger:                 logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := registerQueryTimeout(db, timeout); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// statements runs a statement through each kind of callback
+var statements = map[string]func(db *gorm.DB, sleep bool) error{
+	"query": func(db *gorm.DB, sleep bool) error {
+		var values []int
+		if sleep {
+			db = db.Where("pg_sleep(10) IS NOT NULL")
+		}
+		return db.Table("numbers").Pluck("value", &values).Error
+	},
+	"raw": func(db *gorm.DB, sleep bool) error {
+		if sleep {
+			return db.Exec("SELECT pg_sleep(10)").Error
+		}
+		return db.Exec("SELECT 1").Error
+	},
+	"row": func(db *gorm.DB, sleep bool) error {
+		var value int
+		if sleep {
+			return db.Raw("SELECT pg_sleep(10)").Row().Scan(&value)
+		}
+		return db.Raw("SELECT 1").Row().Scan(&value)
+	},
+	"rows": func(db *gorm.DB, sleep bool) error {
+		query := "SELECT 1"
+		if sleep {
+			query = "SELECT pg_sleep(10)"
+		}
+		rows, err := db.Raw(query).Rows()
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+		}
+		return rows.Err()
+	},
+	"update": func(db *gorm.DB, sleep bool) error {
+		if sleep {
+			db = db.Where("pg_sleep(10) IS NOT NULL")
+		}
+		return db.Table("numbers").Where("value = ?", 1).Update("value", 2).Error
+	},
+}
+
+func TestQueryTimeoutAbortsSlowStatements(t *testing.T) {
+	db := openSlowDatabase(t, 20*time.Millisecond)
+
+	for name, run := range statements {
+		t.Run(name, func(t *testing.T) {
+			err := run(db.WithContext(context.Background()), true)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("got %v, want %v", err, context.DeadlineExceeded)
+			}
+		})
+	}
+}
+
+func TestCancelledContextAbortsStatements(t *testing.T) {
+	db := openSlowDatabase(t, time.Minute)
+
+	for name, run := range statements {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+
+			err := run(db.WithContext(ctx), true)
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("got %v, want %v", err, context.Canceled)
+			}
+		})
+	}
+}
+
+func TestQueryTimeoutLeavesFastStatements(t *testing.T) {
+	db := openSlowDatabase(t, time.Second)
+
+	for name, run := range statements {
+		t.Run(name, func(t *testing.T) {
+			if err := run(db.WithContext(context.Background()), false); err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -9,40 +9,143 @@ import (
 	"github.com/yourusername/go-production-level/internal/models"
 )
 
+// PurposeMFAPending marks a token that only proves the password step of a
+// two-step login. It must be exchanged for an access token.
+const PurposeMFAPending = "mfa_pending"
+
+func init() {
+	// Token times carry milliseconds, so a token issued in the same second as a
+	// revocation can still be told apart from one issued after it
+	jwt.TimePrecision = time.Millisecond
+}
+
 type JWTClaims struct {
-	UserID uint   `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+	Role    string `json:"role"`
+	Purpose string `json:"purpose,omitempty"`
+	// Scopes restricts the permissions of the role when set
+	Scopes []string `json:"scopes,omitempty"`
+	// SessionID is the session the token was issued for, empty for tokens not tied to a login
+	SessionID string `json:"sid,omitempty"`
+	// Act names the admin acting as the user in an impersonation token (RFC 8693 section 4.1)
+	Act *ActorClaims `json:"act,omitempty"`
+	// ClientID is the OAuth2 client the token was issued to, empty for first-party logins
+	ClientID string `json:"client_id,omitempty"`
+	// APIKeyID is set when the request authenticated with an API key instead of a token
+	APIKeyID uint `json:"-"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(user *models.User, cfg *config.Config) (string, error) {
-	claims := JWTClaims{
+// ActorClaims identifies the real user behind an impersonation token
+type ActorClaims struct {
+	Subject string `json:"sub"`
+	UserID  uint   `json:"user_id"`
+	Email   string `json:"email"`
+}
+
+// Impersonated reports whether an admin is acting as the user
+func (c *JWTClaims) Impersonated() bool {
+	return c.Act != nil
+}
+
+// AllowsScope reports whether the credential may use a permission granted to its role
+func (c *JWTClaims) AllowsScope(permission string) bool {
+	if len(c.Scopes) == 0 {
+		return true
+	}
+	for _, scope := range c.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// NewClaims builds the claims for a token issued to user that expires after ttl
+func NewClaims(user *models.User, cfg *config.Config, ttl time.Duration) (*JWTClaims, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JWTClaims{
 		UserID: user.ID,
 		Email:  user.Email,
 		Role:   user.Role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ID:        jti,
+			Issuer:    cfg.JWTIssuer,
+			Subject:   fmt.Sprintf("%d", user.ID),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
+	}, nil
+}
+
+// NewClientClaims builds the claims for a token issued to an OAuth2 client acting on
+// its own behalf. The token has no user and no role.
+func NewClientClaims(clientID string, scopes []string, cfg *config.Config, ttl time.Duration) (*JWTClaims, error) {
+	jti, err := GenerateRandomToken(16)
+	if err != nil {
+		return nil, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := token.SignedString([]byte(cfg.JWTSecret))
+	return &JWTClaims{
+		Scopes:   scopes,
+		ClientID: clientID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
+			Issuer:    cfg.JWTIssuer,
+			Subject:   clientID,
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}, nil
+}
+
+func GenerateToken(user *models.User, cfg *config.Config, keys *KeyRing) (string, error) {
+	claims, err := NewClaims(user, cfg, cfg.AccessTokenTTL)
 	if err != nil {
-		return "", fmt.Errorf("failed to sign token: %w", err)
+		return "", err
 	}
 
-	return signedToken, nil
+	return keys.Sign(claims)
 }
 
-func ValidateToken(tokenString string, cfg *config.Config) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(cfg.JWTSecret), nil
-	})
+// GenerateMFAToken issues the short-lived token returned after the password step
+// of a login when the user has two-factor authentication enabled
+func GenerateMFAToken(user *models.User, cfg *config.Config, keys *KeyRing) (string, error) {
+	claims, err := NewClaims(user, cfg, cfg.MFATokenTTL)
+	if err != nil {
+		return "", err
+	}
+	claims.Purpose = PurposeMFAPending
+
+	return keys.Sign(claims)
+}
+
+// GenerateImpersonationToken issues an access token for user that names actor
+// as the admin acting on the user's behalf
+func GenerateImpersonationToken(user, actor *models.User, cfg *config.Config, keys *KeyRing) (string, error) {
+	claims, err := NewClaims(user, cfg, cfg.ImpersonationTTL)
+	if err != nil {
+		return "", err
+	}
+	claims.Act = &ActorClaims{
+		Subject: fmt.Sprintf("%d", actor.ID),
+		UserID:  actor.ID,
+		Email:   actor.Email,
+	}
+
+	return keys.Sign(claims)
+}
+
+func ValidateToken(tokenString string, cfg *config.Config, keys *KeyRing) (*JWTClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, keys.Keyfunc,
+		jwt.WithValidMethods(keys.Algorithms()),
+		jwt.WithIssuer(cfg.JWTIssuer),
+	)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
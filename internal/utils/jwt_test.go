@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yourusername/go-production-level/config"
+	"github.com/yourusername/go-production-level/internal/models"
+)
+
+func TestTokenTimesKeepMilliseconds(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "test-secret", JWTIssuer: "test", AccessTokenTTL: time.Minute}
+	keys, err := NewKeyRing(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := NewClaims(&models.User{ID: 1}, cfg, cfg.AccessTokenTTL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A time within a second that does not fall on a whole millisecond
+	claims.IssuedAt.Time = time.Unix(1700000000, 123456789)
+
+	token, err := keys.Sign(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ValidateToken(token, cfg, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parsed float may be a hair under the encoded millisecond
+	if got := parsed.IssuedAt.UnixMilli(); got < 1700000000122 || got > 1700000000123 {
+		t.Errorf("got iat %d ms, want 1700000000123 ms", got)
+	}
+}
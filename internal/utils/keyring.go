@@ -0,0 +1,323 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yourusername/go-production-level/config"
+)
+
+// legacyKeyID identifies the shared HS256 secret, which is never published in the JWKS
+const legacyKeyID = "hs256"
+
+// SigningKey is a single JWT key identified by its kid
+type SigningKey struct {
+	ID         string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+}
+
+// CanSign reports whether the private half of the key is available
+func (k *SigningKey) CanSign() bool {
+	return k.PrivateKey != nil
+}
+
+// KeyRing holds the key used to sign new tokens together with every key that
+// is still accepted for verification. Rotating keys is done in stages: add the
+// new key so it is published in the JWKS, switch JWT_SIGNING_KEY_ID to it, and
+// remove the old key once the tokens it signed have expired.
+type KeyRing struct {
+	signing *SigningKey
+	keys    map[string]*SigningKey
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document served from /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// PublicKey decodes the public key described by the JWK
+func (j JWK) PublicKey() (crypto.PublicKey, error) {
+	switch j.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(j.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus in JWK %s: %w", j.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(j.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid RSA exponent in JWK %s", j.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch j.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q in JWK %s", j.Crv, j.Kid)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC point in JWK %s: %w", j.Kid, err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(j.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC point in JWK %s: %w", j.Kid, err)
+		}
+		pub := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("invalid EC point in JWK %s", j.Kid)
+		}
+		return pub, nil
+
+	case "OKP":
+		x, err := base64.RawURLEncoding.DecodeString(j.X)
+		if err != nil || j.Crv != "Ed25519" || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key in JWK %s", j.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type %q in JWK %s", j.Kty, j.Kid)
+}
+
+// NewKeyRing loads the keys configured in cfg. Without JWT_KEYS_DIR the ring
+// falls back to HS256 with the shared JWT secret.
+func NewKeyRing(cfg *config.Config) (*KeyRing, error) {
+	ring := &KeyRing{keys: make(map[string]*SigningKey)}
+
+	legacy := &SigningKey{
+		ID:         legacyKeyID,
+		Method:     jwt.SigningMethodHS256,
+		PrivateKey: []byte(cfg.JWTSecret),
+		PublicKey:  []byte(cfg.JWTSecret),
+	}
+
+	if cfg.JWTKeysDir == "" {
+		ring.keys[legacy.ID] = legacy
+		ring.signing = legacy
+		return ring, nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(cfg.JWTKeysDir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list JWT keys: %w", err)
+	}
+
+	for _, file := range files {
+		key, err := loadSigningKey(file)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys[key.ID] = key
+	}
+
+	if len(ring.keys) == 0 {
+		return nil, fmt.Errorf("no JWT keys found in %s", cfg.JWTKeysDir)
+	}
+
+	signingID := cfg.JWTSigningKeyID
+	if signingID == "" {
+		if len(ring.keys) > 1 {
+			return nil, fmt.Errorf("JWT_SIGNING_KEY_ID is required when more than one key is configured")
+		}
+		for id := range ring.keys {
+			signingID = id
+		}
+	}
+
+	signing, ok := ring.keys[signingID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingID, cfg.JWTKeysDir)
+	}
+	if !signing.CanSign() {
+		return nil, fmt.Errorf("signing key %q has no private key", signingID)
+	}
+	ring.signing = signing
+
+	// Keep accepting HS256 tokens while migrating away from the shared secret
+	if cfg.JWTAcceptHS256 {
+		ring.keys[legacy.ID] = legacy
+	}
+
+	return ring, nil
+}
+
+// Sign signs the claims with the current signing key and sets the kid header
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.Method, claims)
+	if k.signing.ID != legacyKeyID {
+		token.Header["kid"] = k.signing.ID
+	}
+
+	signedToken, err := token.SignedString(k.signing.PrivateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signedToken, nil
+}
+
+// Keyfunc resolves the verification key for a token from its kid header
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = legacyKeyID
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key: %s", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.PublicKey, nil
+}
+
+// Algorithms returns the signing algorithms accepted by the ring
+func (k *KeyRing) Algorithms() []string {
+	seen := make(map[string]bool)
+	var algs []string
+	for _, key := range k.keys {
+		if !seen[key.Method.Alg()] {
+			seen[key.Method.Alg()] = true
+			algs = append(algs, key.Method.Alg())
+		}
+	}
+	sort.Strings(algs)
+	return algs
+}
+
+// JWKS returns the public keys of the ring. Symmetric keys are never exposed.
+func (k *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch pub := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (pub.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = pub.Curve.Params().Name
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+			jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+// loadSigningKey reads a PEM encoded private or public key. The file name
+// without extension is used as the kid; public keys can only verify tokens.
+func loadSigningKey(path string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid PEM data in %s", path)
+	}
+
+	key := &SigningKey{ID: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		key.PrivateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key.PrivateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key.PrivateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key.PublicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block type %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT key %s: %w", path, err)
+	}
+
+	if key.PrivateKey != nil {
+		signer, ok := key.PrivateKey.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type in %s", path)
+		}
+		key.PublicKey = signer.Public()
+	}
+
+	switch pub := key.PublicKey.(type) {
+	case *rsa.PublicKey:
+		key.Method = jwt.SigningMethodRS256
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256():
+			key.Method = jwt.SigningMethodES256
+		case elliptic.P384():
+			key.Method = jwt.SigningMethodES384
+		case elliptic.P521():
+			key.Method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported elliptic curve in %s", path)
+		}
+	case ed25519.PublicKey:
+		key.Method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported key type in %s", path)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/yourusername/go-production-level/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hashing algorithms accepted by PASSWORD_HASH_ALGORITHM
+const (
+	PasswordHashArgon2id = "argon2id"
+	PasswordHashBcrypt   = "bcrypt"
+)
+
+// ErrUnknownPasswordHash is returned when a stored hash is in no supported format
+var ErrUnknownPasswordHash = errors.New("unknown password hash format")
+
+// PasswordHasher hashes passwords into self-describing strings that carry the
+// algorithm and its parameters, so hashes made with older settings still verify.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of password
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether the encoded hash was made with another
+	// algorithm or weaker parameters than the hasher would use now
+	NeedsRehash(encoded string) bool
+}
+
+// NewPasswordHasher returns a hasher that hashes new passwords with the
+// configured algorithm and verifies hashes made with any supported algorithm
+func NewPasswordHasher(cfg *config.Config) (PasswordHasher, error) {
+	argon := &Argon2idHasher{
+		Memory:      uint32(cfg.Argon2Memory),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	if argon.Memory < 8*uint32(argon.Parallelism) || argon.Iterations < 1 || argon.Parallelism < 1 {
+		return nil, fmt.Errorf("invalid argon2id parameters m=%d t=%d p=%d", cfg.Argon2Memory, cfg.Argon2Iterations, cfg.Argon2Parallelism)
+	}
+	bcryptHasher := &BcryptHasher{Cost: cfg.BcryptCost}
+	if bcryptHasher.Cost < bcrypt.MinCost || bcryptHasher.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("invalid bcrypt cost %d", cfg.BcryptCost)
+	}
+
+	hasher := &multiHasher{argon2id: argon, bcrypt: bcryptHasher}
+	switch cfg.PasswordHashAlgorithm {
+	case PasswordHashArgon2id:
+		hasher.current = argon
+	case PasswordHashBcrypt:
+		hasher.current = bcryptHasher
+	default:
+		return nil, fmt.Errorf("unknown password hash algorithm %q", cfg.PasswordHashAlgorithm)
+	}
+	return hasher, nil
+}
+
+// multiHasher hashes with the current algorithm and dispatches verification on the hash prefix
+type multiHasher struct {
+	current  PasswordHasher
+	argon2id *Argon2idHasher
+	bcrypt   *BcryptHasher
+}
+
+func (h *multiHasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *multiHasher) Verify(password, encoded string) (bool, error) {
+	switch {
+	case encoded == "":
+		// Accounts without a password never match
+		return false, nil
+	case isArgon2idHash(encoded):
+		return h.argon2id.Verify(password, encoded)
+	case isBcryptHash(encoded):
+		return h.bcrypt.Verify(password, encoded)
+	}
+	return false, ErrUnknownPasswordHash
+}
+
+func (h *multiHasher) NeedsRehash(encoded string) bool {
+	return h.current.NeedsRehash(encoded)
+}
+
+// Argon2idHasher hashes passwords with argon2id (RFC 9106) into the PHC string format:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<key>
+type Argon2idHasher struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// argon2idParams are the parameters decoded from a stored hash
+type argon2idParams struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Iterations, h.Memory, h.Parallelism, h.KeyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, h.Memory, h.Iterations, h.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify recomputes the key with the parameters stored in the hash, not the hasher's own
+func (h *Argon2idHasher) Verify(password, encoded string) (bool, error) {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h *Argon2idHasher) NeedsRehash(encoded string) bool {
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		return true
+	}
+	return params.memory < h.Memory || params.iterations < h.Iterations || params.parallelism != h.Parallelism ||
+		uint32(len(params.salt)) < h.SaltLength || uint32(len(params.key)) < h.KeyLength
+}
+
+func decodeArgon2id(encoded string) (*argon2idParams, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != PasswordHashArgon2id {
+		return nil, ErrUnknownPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	params := &argon2idParams{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, fmt.Errorf("invalid argon2id key")
+	}
+	return params, nil
+}
+
+// BcryptHasher hashes passwords with bcrypt. The cost is part of the hash.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+func (h *BcryptHasher) Verify(password, encoded string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+func isArgon2idHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+PasswordHashArgon2id+"$")
+}
+
+func isBcryptHash(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") || strings.HasPrefix(encoded, "$2y$")
+}
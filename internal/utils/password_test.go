@@ -0,0 +1,225 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/yourusername/go-production-level/config"
+)
+
+// Cheap parameters keep the tests fast; the hashes are still real
+var (
+	testArgon2id = &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+	testBcrypt   = &BcryptHasher{Cost: 4}
+)
+
+func newTestPasswordHasher(t *testing.T, algorithm string) PasswordHasher {
+	t.Helper()
+	hasher, err := NewPasswordHasher(&config.Config{
+		PasswordHashAlgorithm: algorithm,
+		Argon2Memory:          int(testArgon2id.Memory),
+		Argon2Iterations:      int(testArgon2id.Iterations),
+		Argon2Parallelism:     int(testArgon2id.Parallelism),
+		BcryptCost:            testBcrypt.Cost,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hasher
+}
+
+func TestArgon2idEncodeParseRoundTrip(t *testing.T) {
+	encoded, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "$argon2id$v=19$m=64,t=1,p=1$"; !strings.HasPrefix(encoded, want) {
+		t.Fatalf("got hash %q, want prefix %q", encoded, want)
+	}
+
+	params, err := decodeArgon2id(encoded)
+	if err != nil {
+		t.Fatalf("failed to decode %q: %v", encoded, err)
+	}
+	if params.memory != 64 || params.iterations != 1 || params.parallelism != 1 {
+		t.Errorf("got m=%d,t=%d,p=%d, want m=64,t=1,p=1", params.memory, params.iterations, params.parallelism)
+	}
+	if len(params.salt) != 16 || len(params.key) != 32 {
+		t.Errorf("got %d byte salt and %d byte key, want 16 and 32", len(params.salt), len(params.key))
+	}
+
+	other, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == encoded {
+		t.Error("hashing the same password twice gave the same hash, want a fresh salt each time")
+	}
+}
+
+func TestDecodeArgon2idRejectsMalformedHashes(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"too few fields", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA"},
+		{"other algorithm", "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5"},
+		{"other version", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5"},
+		{"bad parameters", "$argon2id$v=19$m=x,t=1,p=1$c2FsdA$a2V5"},
+		{"bad salt", "$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5"},
+		{"empty key", "$argon2id$v=19$m=64,t=1,p=1$c2FsdA$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := decodeArgon2id(tt.encoded); err == nil {
+				t.Errorf("decoded %q, want an error", tt.encoded)
+			}
+		})
+	}
+}
+
+func TestPasswordHasherVerify(t *testing.T) {
+	argonHash, err := testArgon2id.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := testBcrypt.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		encoded  string
+		want     bool
+		wantErr  error
+	}{
+		{"argon2id match", "correct horse", argonHash, true, nil},
+		{"argon2id mismatch", "battery staple", argonHash, false, nil},
+		{"bcrypt match", "correct horse", bcryptHash, true, nil},
+		{"bcrypt mismatch", "battery staple", bcryptHash, false, nil},
+		{"account without a password", "", "", false, nil},
+		{"unknown format", "correct horse", "5f4dcc3b5aa765d61d8327deb882cf99", false, ErrUnknownPasswordHash},
+		{"unknown modular crypt format", "correct horse", "$1$salt$hash", false, ErrUnknownPasswordHash},
+	}
+
+	// Both algorithms verify whichever one new hashes are made with
+	for _, algorithm := range []string{PasswordHashArgon2id, PasswordHashBcrypt} {
+		hasher := newTestPasswordHasher(t, algorithm)
+		for _, tt := range tests {
+			t.Run(algorithm+"/"+tt.name, func(t *testing.T) {
+				got, err := hasher.Verify(tt.password, tt.encoded)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("got error %v, want %v", err, tt.wantErr)
+				}
+				if got != tt.want {
+					t.Errorf("got %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestPasswordHasherHashesWithConfiguredAlgorithm(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		prefix    string
+	}{
+		{PasswordHashArgon2id, "$argon2id$"},
+		{PasswordHashBcrypt, "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			hasher := newTestPasswordHasher(t, tt.algorithm)
+			encoded, err := hasher.Hash("correct horse")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(encoded, tt.prefix) {
+				t.Errorf("got hash %q, want prefix %q", encoded, tt.prefix)
+			}
+			if ok, err := hasher.Verify("correct horse", encoded); !ok || err != nil {
+				t.Errorf("failed to verify its own hash: %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	hash := func(h PasswordHasher) string {
+		encoded, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+	argonHash := hash(testArgon2id)
+	bcryptHash := hash(testBcrypt)
+
+	tests := []struct {
+		name    string
+		hasher  PasswordHasher
+		encoded string
+		want    bool
+	}{
+		{"argon2id with current parameters", testArgon2id, argonHash, false},
+		{"argon2id memory raised", &Argon2idHasher{Memory: 128, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, argonHash, true},
+		{"argon2id iterations raised", &Argon2idHasher{Memory: 64, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32}, argonHash, true},
+		{"argon2id parallelism changed", &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 2, SaltLength: 16, KeyLength: 32}, argonHash, true},
+		{"argon2id key lengthened", &Argon2idHasher{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 64}, argonHash, true},
+		{"argon2id memory lowered", &Argon2idHasher{Memory: 32, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}, argonHash, false},
+		{"bcrypt hash under argon2id", testArgon2id, bcryptHash, true},
+		{"bcrypt with current cost", testBcrypt, bcryptHash, false},
+		{"bcrypt cost raised", &BcryptHasher{Cost: 5}, bcryptHash, true},
+		{"bcrypt cost lowered", &BcryptHasher{Cost: 4}, hash(&BcryptHasher{Cost: 5}), false},
+		{"argon2id hash under bcrypt", testBcrypt, argonHash, true},
+		{"switching algorithm", newTestPasswordHasher(t, PasswordHashBcrypt), argonHash, true},
+		{"unknown format", testArgon2id, "5f4dcc3b5aa765d61d8327deb882cf99", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.hasher.NeedsRehash(tt.encoded); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPasswordHasherRejectsInvalidConfig(t *testing.T) {
+	valid := func() *config.Config {
+		return &config.Config{
+			PasswordHashAlgorithm: PasswordHashArgon2id,
+			Argon2Memory:          64,
+			Argon2Iterations:      1,
+			Argon2Parallelism:     1,
+			BcryptCost:            4,
+		}
+	}
+
+	tests := []struct {
+		name   string
+		change func(cfg *config.Config)
+	}{
+		{"unknown algorithm", func(cfg *config.Config) { cfg.PasswordHashAlgorithm = "md5" }},
+		{"argon2id memory too low", func(cfg *config.Config) { cfg.Argon2Memory = 4 }},
+		{"argon2id without iterations", func(cfg *config.Config) { cfg.Argon2Iterations = 0 }},
+		{"argon2id without parallelism", func(cfg *config.Config) { cfg.Argon2Parallelism = 0 }},
+		{"bcrypt cost too low", func(cfg *config.Config) { cfg.BcryptCost = 3 }},
+		{"bcrypt cost too high", func(cfg *config.Config) { cfg.BcryptCost = 32 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := valid()
+			tt.change(cfg)
+			if _, err := NewPasswordHasher(cfg); err == nil {
+				t.Error("got a hasher, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package utils
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+// Password strength scores returned by EstimatePasswordStrength, on the same
+// 0-4 scale as zxcvbn
+const (
+	PasswordTooGuessable      = 0 // fewer than 10^3 guesses
+	PasswordVeryGuessable     = 1 // fewer than 10^6 guesses
+	PasswordSomewhatGuessable = 2 // fewer than 10^8 guesses
+	PasswordSafelyUnguessable = 3 // fewer than 10^10 guesses
+	PasswordVeryUnguessable   = 4
+)
+
+// commonPasswords holds frequently used passwords and password fragments,
+// most common first. Matches cost an attacker about as many guesses as their rank.
+var commonPasswords = []string{
+	"123456", "password", "12345678", "qwerty", "123456789", "12345", "1234", "111111",
+	"1234567", "dragon", "123123", "baseball", "abc123", "football", "monkey", "letmein",
+	"696969", "shadow", "master", "666666", "qwertyuiop", "123321", "mustang", "1234567890",
+	"michael", "654321", "superman", "1qaz2wsx", "7777777", "121212", "000000", "qazwsx",
+	"123qwe", "killer", "trustno1", "jordan", "jennifer", "zxcvbnm", "asdfgh", "hunter",
+	"buster", "soccer", "harley", "batman", "andrew", "tigger", "sunshine", "iloveyou",
+	"2000", "charlie", "robert", "thomas", "hockey", "ranger", "daniel", "starwars",
+	"klaster", "112233", "george", "computer", "michelle", "jessica", "pepper", "1111",
+	"zxcvbn", "555555", "11111111", "131313", "freedom", "777777", "pass", "maggie",
+	"159753", "aaaaaa", "ginger", "princess", "joshua", "cheese", "amanda", "summer",
+	"love", "ashley", "nicole", "chelsea", "biteme", "matthew", "access", "yankees",
+	"987654321", "dallas", "austin", "thunder", "taylor", "matrix", "welcome", "admin",
+	"login", "secret", "changeme", "passw0rd", "p@ssw0rd", "qwerty123", "password1",
+}
+
+// keyboardRows are adjacent key runs that count as a single pattern
+var keyboardRows = []string{
+	"qwertyuiop", "asdfghjkl", "zxcvbnm", "1234567890", "abcdefghijklmnopqrstuvwxyz",
+}
+
+// EstimatePasswordStrength scores how hard a password is to guess, in the spirit
+// of zxcvbn. Common passwords, words taken from userInputs (such as the email and
+// name of the account), repeated characters, and keyboard or alphabet sequences
+// are each counted as a single cheap guess instead of as random characters.
+func EstimatePasswordStrength(password string, userInputs ...string) int {
+	guesses := math.Log10(estimateGuesses(password, userInputs))
+	switch {
+	case guesses < 3:
+		return PasswordTooGuessable
+	case guesses < 6:
+		return PasswordVeryGuessable
+	case guesses < 8:
+		return PasswordSomewhatGuessable
+	case guesses < 10:
+		return PasswordSafelyUnguessable
+	}
+	return PasswordVeryUnguessable
+}
+
+// estimateGuesses walks the password left to right, taking the longest known
+// pattern at each position and otherwise a single brute-forced character
+func estimateGuesses(password string, userInputs []string) float64 {
+	lower := []rune(strings.ToLower(password))
+	words := dictionary(userInputs)
+	pool := float64(characterPool(password))
+
+	guesses := 1.0
+	for i := 0; i < len(lower); {
+		length, cost := longestPattern(lower, i, words)
+		if length == 0 {
+			length, cost = 1, pool
+		}
+		guesses *= cost
+		i += length
+	}
+	return math.Max(guesses, 1)
+}
+
+// longestPattern returns the length and guess cost of the longest pattern starting at i
+func longestPattern(password []rune, i int, words map[string]int) (int, float64) {
+	bestLength, bestCost := 0, 0.0
+	consider := func(length int, cost float64) {
+		// A pattern must save guesses compared to brute-forcing its characters
+		if length > bestLength && length >= 3 {
+			bestLength, bestCost = length, cost
+		}
+	}
+
+	rest := string(password[i:])
+	for word, rank := range words {
+		if strings.HasPrefix(rest, word) {
+			consider(len([]rune(word)), float64(rank+1))
+		}
+	}
+
+	// Runs of the same character
+	run := 1
+	for i+run < len(password) && password[i+run] == password[i] {
+		run++
+	}
+	consider(run, float64(10*run))
+
+	// Ascending or descending runs along a keyboard row or the alphabet
+	for _, row := range keyboardRows {
+		for _, reversed := range []bool{false, true} {
+			length := sequenceLength(password[i:], []rune(row), reversed)
+			consider(length, float64(20*length))
+		}
+	}
+
+	return bestLength, bestCost
+}
+
+// sequenceLength returns how many characters of s follow each other in row
+func sequenceLength(s, row []rune, reversed bool) int {
+	position := -1
+	for j, r := range row {
+		if r == s[0] {
+			position = j
+		}
+	}
+	if position < 0 {
+		return 0
+	}
+
+	step := 1
+	if reversed {
+		step = -1
+	}
+	length := 1
+	for length < len(s) {
+		next := position + step*length
+		if next < 0 || next >= len(row) || row[next] != s[length] {
+			break
+		}
+		length++
+	}
+	return length
+}
+
+// dictionary ranks the common passwords and the words found in the user inputs
+func dictionary(userInputs []string) map[string]int {
+	words := make(map[string]int, len(commonPasswords)+len(userInputs))
+	for rank, word := range commonPasswords {
+		words[word] = rank
+	}
+	for _, input := range userInputs {
+		for _, word := range strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		}) {
+			if len(word) >= 3 {
+				words[word] = 0
+			}
+		}
+	}
+	return words
+}
+
+// characterPool returns the size of the alphabet a brute-force attack would need
+func characterPool(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if other {
+		pool += 33
+	}
+	return pool
+}
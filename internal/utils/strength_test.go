@@ -0,0 +1,56 @@
+package utils
+
+import "testing"
+
+func TestEstimatePasswordStrength(t *testing.T) {
+	tests := []struct {
+		name       string
+		password   string
+		userInputs []string
+		want       int
+	}{
+		// Common passwords cost about as many guesses as their rank
+		{"common password", "password", nil, PasswordTooGuessable},
+		{"common password in another case", "PASSWORD", nil, PasswordTooGuessable},
+		{"common password with a digit", "password1", nil, PasswordTooGuessable},
+		{"common password with substitutions", "p@ssw0rd", nil, PasswordTooGuessable},
+		{"repeated common passwords", "sunshinesunshine", nil, PasswordVeryGuessable},
+
+		// Keyboard and alphabet runs count as one pattern
+		{"keyboard row", "asdfghjkl", nil, PasswordTooGuessable},
+		{"reversed keyboard row", "poiuytrewq", nil, PasswordTooGuessable},
+		{"reversed alphabet", "zyxwvu", nil, PasswordTooGuessable},
+		{"keyboard row and digit run", "qwerty123456", nil, PasswordVeryGuessable},
+		{"repeated character", "aaaaaaaaaaaa", nil, PasswordTooGuessable},
+
+		// Words of the account count as one guess
+		{"random without user inputs", "alicesmith", nil, PasswordVeryUnguessable},
+		{"name of the account", "alicesmith", []string{"alice.smith@example.com", "Alice Smith"}, PasswordTooGuessable},
+		{"name of the account in another case", "SmithAlice", []string{"", "Alice Smith"}, PasswordTooGuessable},
+		{"short user input words are ignored", "xqjvkdmw", []string{"xq@jv.kd"}, PasswordVeryUnguessable},
+
+		// Thresholds at 10^3, 10^6, 10^8 and 10^10 guesses, with 10 guesses per digit
+		{"99 guesses", "92", nil, PasswordTooGuessable},
+		{"10^3 guesses", "927", nil, PasswordVeryGuessable},
+		{"10^5 guesses", "92746", nil, PasswordVeryGuessable},
+		{"10^6 guesses", "927461", nil, PasswordSomewhatGuessable},
+		{"10^7 guesses", "9274615", nil, PasswordSomewhatGuessable},
+		{"10^8 guesses", "92746158", nil, PasswordSafelyUnguessable},
+		{"10^9 guesses", "927461580", nil, PasswordSafelyUnguessable},
+		{"10^10 guesses", "9274615803", nil, PasswordVeryUnguessable},
+
+		// A larger character pool makes each character cost more
+		{"lowercase", "xqjvkd", nil, PasswordSafelyUnguessable},
+		{"mixed characters", "Tr0ub4dor&3", nil, PasswordVeryUnguessable},
+		{"passphrase", "correct horse battery staple", nil, PasswordVeryUnguessable},
+		{"empty", "", nil, PasswordTooGuessable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimatePasswordStrength(tt.password, tt.userInputs...); got != tt.want {
+				t.Errorf("EstimatePasswordStrength(%q) = %d, want %d", tt.password, got, tt.want)
+			}
+		})
+	}
+}
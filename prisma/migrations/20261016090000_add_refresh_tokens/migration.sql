@@ -0,0 +1,23 @@
+-- CreateTable
+CREATE TABLE "refresh_tokens" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "family_id" TEXT NOT NULL,
+    "token_hash" TEXT NOT NULL,
+    "expires_at" TIMESTAMPTZ(6) NOT NULL,
+    "used_at" TIMESTAMPTZ(6),
+    "revoked_at" TIMESTAMPTZ(6),
+
+    CONSTRAINT "refresh_tokens_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_refresh_tokens_token_hash" ON "refresh_tokens"("token_hash");
+
+-- CreateIndex
+CREATE INDEX "idx_refresh_tokens_user_id" ON "refresh_tokens"("user_id");
+
+-- CreateIndex
+CREATE INDEX "idx_refresh_tokens_family_id" ON "refresh_tokens"("family_id");
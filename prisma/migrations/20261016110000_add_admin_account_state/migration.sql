@@ -0,0 +1,23 @@
+-- AlterTable
+ALTER TABLE "users" ADD COLUMN "suspended_at" TIMESTAMPTZ(6),
+ADD COLUMN "suspension_reason" TEXT,
+ADD COLUMN "password_reset_required" BOOLEAN NOT NULL DEFAULT false;
+
+-- CreateTable
+CREATE TABLE "login_events" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "ip" TEXT,
+    "user_agent" TEXT,
+    "success" BOOLEAN NOT NULL,
+    "reason" TEXT,
+
+    CONSTRAINT "login_events_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_login_events_created_at" ON "login_events"("created_at");
+
+-- CreateIndex
+CREATE INDEX "idx_login_events_user_id" ON "login_events"("user_id");
@@ -0,0 +1,17 @@
+-- AlterTable
+ALTER TABLE "users" ADD COLUMN "totp_secret" TEXT,
+ADD COLUMN "totp_enabled_at" TIMESTAMPTZ(6);
+
+-- CreateTable
+CREATE TABLE "recovery_codes" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "code_hash" TEXT NOT NULL,
+    "used_at" TIMESTAMPTZ(6),
+
+    CONSTRAINT "recovery_codes_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_recovery_codes_user_id" ON "recovery_codes"("user_id");
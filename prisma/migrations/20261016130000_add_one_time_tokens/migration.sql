@@ -0,0 +1,18 @@
+-- CreateTable
+CREATE TABLE "one_time_tokens" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "purpose" TEXT NOT NULL,
+    "token_hash" TEXT NOT NULL,
+    "expires_at" TIMESTAMPTZ(6) NOT NULL,
+    "used_at" TIMESTAMPTZ(6),
+
+    CONSTRAINT "one_time_tokens_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_one_time_tokens_token_hash" ON "one_time_tokens"("token_hash");
+
+-- CreateIndex
+CREATE INDEX "idx_one_time_tokens_user_id" ON "one_time_tokens"("user_id");
@@ -0,0 +1,2 @@
+-- AlterTable
+ALTER TABLE "users" ADD COLUMN "email_verified_at" TIMESTAMPTZ(6);
@@ -0,0 +1,24 @@
+-- CreateTable
+CREATE TABLE "audit_logs" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "action" TEXT NOT NULL,
+    "actor_id" BIGINT,
+    "user_id" BIGINT,
+    "ip" TEXT,
+    "details" TEXT,
+
+    CONSTRAINT "audit_logs_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_audit_logs_created_at" ON "audit_logs"("created_at");
+
+-- CreateIndex
+CREATE INDEX "idx_audit_logs_action" ON "audit_logs"("action");
+
+-- CreateIndex
+CREATE INDEX "idx_audit_logs_actor_id" ON "audit_logs"("actor_id");
+
+-- CreateIndex
+CREATE INDEX "idx_audit_logs_user_id" ON "audit_logs"("user_id");
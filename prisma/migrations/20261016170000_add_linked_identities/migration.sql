@@ -0,0 +1,18 @@
+-- CreateTable
+CREATE TABLE "linked_identities" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "provider" TEXT NOT NULL,
+    "subject" TEXT NOT NULL,
+    "email" TEXT,
+
+    CONSTRAINT "linked_identities_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_linked_identities_provider_subject" ON "linked_identities"("provider", "subject");
+
+-- CreateIndex
+CREATE INDEX "idx_linked_identities_user_id" ON "linked_identities"("user_id");
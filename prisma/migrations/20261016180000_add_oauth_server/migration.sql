@@ -0,0 +1,42 @@
+-- AlterTable
+ALTER TABLE "refresh_tokens" ADD COLUMN "oauth_client_id" BIGINT,
+ADD COLUMN "scopes" TEXT;
+
+-- CreateTable
+CREATE TABLE "oauth_clients" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6),
+    "client_id" TEXT NOT NULL,
+    "secret_hash" TEXT,
+    "name" TEXT NOT NULL,
+    "redirect_uris" TEXT,
+    "grant_types" TEXT,
+    "scopes" TEXT,
+
+    CONSTRAINT "oauth_clients_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateTable
+CREATE TABLE "oauth_consents" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "oauth_client_id" BIGINT NOT NULL,
+    "scopes" TEXT,
+
+    CONSTRAINT "oauth_consents_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_refresh_tokens_oauth_client_id" ON "refresh_tokens"("oauth_client_id");
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_oauth_clients_client_id" ON "oauth_clients"("client_id");
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_oauth_consents_user_client" ON "oauth_consents"("user_id", "oauth_client_id");
+
+-- CreateIndex
+CREATE INDEX "idx_oauth_consents_oauth_client_id" ON "oauth_consents"("oauth_client_id");
@@ -0,0 +1,22 @@
+-- CreateTable
+CREATE TABLE "sessions" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "family_id" TEXT NOT NULL,
+    "ip" TEXT,
+    "user_agent" TEXT,
+    "location" TEXT,
+    "last_seen_at" TIMESTAMPTZ(6) NOT NULL,
+    "expires_at" TIMESTAMPTZ(6) NOT NULL,
+    "revoked_at" TIMESTAMPTZ(6),
+
+    CONSTRAINT "sessions_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_sessions_family_id" ON "sessions"("family_id");
+
+-- CreateIndex
+CREATE INDEX "idx_sessions_user_id" ON "sessions"("user_id");
@@ -0,0 +1,12 @@
+-- CreateTable
+CREATE TABLE "password_histories" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "user_id" BIGINT NOT NULL,
+    "password_hash" TEXT NOT NULL,
+
+    CONSTRAINT "password_histories_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE INDEX "idx_password_histories_user_id" ON "password_histories"("user_id");
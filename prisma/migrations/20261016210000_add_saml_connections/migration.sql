@@ -0,0 +1,22 @@
+-- CreateTable
+CREATE TABLE "saml_connections" (
+    "id" BIGSERIAL NOT NULL,
+    "created_at" TIMESTAMPTZ(6),
+    "updated_at" TIMESTAMPTZ(6),
+    "organization" TEXT NOT NULL,
+    "name" TEXT NOT NULL,
+    "idp_entity_id" TEXT NOT NULL,
+    "idp_metadata" TEXT NOT NULL,
+    "domains" TEXT NOT NULL,
+    "email_attribute" TEXT,
+    "name_attribute" TEXT,
+    "role_attribute" TEXT,
+    "role_mapping" TEXT,
+    "default_role" TEXT NOT NULL,
+    "jit_provisioning" BOOLEAN,
+
+    CONSTRAINT "saml_connections_pkey" PRIMARY KEY ("id")
+);
+
+-- CreateIndex
+CREATE UNIQUE INDEX "idx_saml_connections_organization" ON "saml_connections"("organization");
@@ -0,0 +1,2 @@
+-- CreateIndex
+CREATE INDEX "idx_users_created_at_id" ON "users"("created_at", "id");